@@ -0,0 +1,78 @@
+// Command polkasim sends Polka webhooks to a running Chirpy, signed the way
+// Polka signs them, for local and integration testing.
+//
+//	go run ./cmd/polkasim -user <uuid> [-event user.downgraded] [-ends-at 2026-01-02T15:04:05Z]
+//
+// The API key and signing secret default to POLKA_KEY and
+// POLKA_WEBHOOK_SECRET, read from the environment or a .env file.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	"github.com/kavancamp/chirpy/internal/payments"
+)
+
+func main() {
+	// a .env file is optional here; flags and the environment also work
+	_ = godotenv.Load()
+
+	url := flag.String("url", "http://localhost:8080/api/polka/webhooks", "webhook endpoint")
+	key := flag.String("key", os.Getenv("POLKA_KEY"), "Polka API key")
+	secret := flag.String("secret", os.Getenv("POLKA_WEBHOOK_SECRET"), "webhook signing secret; empty sends unsigned deliveries")
+	event := flag.String("event", payments.PolkaUserUpgraded, "event type, e.g. user.upgraded, user.downgraded or subscription.cancelled")
+	user := flag.String("user", "", "ID of the user the event is about")
+	id := flag.String("id", "", "event ID; a random one by default")
+	endsAt := flag.String("ends-at", "", "optional end of the paid period, RFC 3339")
+	skew := flag.Duration("skew", 0, "shift the signing timestamp, e.g. -10m to test stale deliveries")
+	flag.Parse()
+
+	var e payments.PolkaEvent
+	e.ID = *id
+	if e.ID == "" {
+		e.ID = "evt_" + uuid.NewString()
+	}
+	e.Event = *event
+	userID, err := uuid.Parse(*user)
+	if err != nil {
+		log.Fatalf("invalid -user: %s", err)
+	}
+	e.Data.UserID = userID
+	if *endsAt != "" {
+		t, err := time.Parse(time.RFC3339, *endsAt)
+		if err != nil {
+			log.Fatalf("invalid -ends-at: %s", err)
+		}
+		e.Data.EndsAt = &t
+	}
+
+	polka := &payments.Polka{APIKey: *key, WebhookSecret: *secret}
+	req, err := polka.NewWebhookRequest(context.Background(), *url, e, time.Now().Add(*skew))
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	fmt.Printf("%s %s -> %s\n", e.ID, e.Event, resp.Status)
+	if len(body) > 0 {
+		fmt.Println(string(body))
+	}
+	if resp.StatusCode >= 300 {
+		os.Exit(1)
+	}
+}
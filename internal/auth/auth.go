@@ -22,46 +22,97 @@ func CheckPasswordHash(password, hash string) error {
 }
 var (
 	errInvalidToken = errors.New("invalid token")
+	// ErrTokenRevoked is returned for access tokens issued before the
+	// user last revoked all their sessions.
+	ErrTokenRevoked = errors.New("token revoked")
 )
 
-// creates and signs a new JWT for a user
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String(),
-	}
+// AccessToken is what a validated access token says about its holder.
+type AccessToken struct {
+	UserID uuid.UUID
+	// SessionID is the refresh token family the token was issued for.
+	SessionID uuid.NullUUID
+	// Version is the user's token version when the token was issued.
+	Version int32
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(tokenSecret))
-	if err != nil {
-		return "", err
+// TokenVersionFunc returns a user's current token version. Access tokens
+// carrying an older version have been revoked.
+type TokenVersionFunc func(userID uuid.UUID) (int32, error)
+
+type accessClaims struct {
+	jwt.RegisteredClaims
+	Version   int32  `json:"ver"`
+	SessionID string `json:"sid,omitempty"`
+}
+
+// creates and signs a new JWT for a user with key
+func MakeJWT(userID uuid.UUID, key *Key, expiresIn time.Duration, version int32, sessionID uuid.UUID) (string, error) {
+	claims := accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Version: version,
+	}
+	if sessionID != uuid.Nil {
+		claims.SessionID = sessionID.String()
 	}
-	return signedToken, nil
+
+	return key.signToken(claims)
 }
-// validates a JWT and returns the user ID if successful
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	parsedToken, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errInvalidToken
-		}
-		return []byte(tokenSecret), nil
-	})
+
+// ParseJWT validates a JWT and returns what it says. When currentVersion
+// is not nil, tokens older than the user's current token version are
+// rejected with ErrTokenRevoked.
+func ParseJWT(tokenString string, keys Verifier, currentVersion TokenVersionFunc) (AccessToken, error) {
+	parsedToken, err := jwt.ParseWithClaims(tokenString, &accessClaims{}, keys.verificationKey)
 
 	if err != nil {
-		return uuid.Nil, errInvalidToken
+		return AccessToken{}, errInvalidToken
 	}
 
-	claims, ok := parsedToken.Claims.(*jwt.RegisteredClaims)
+	claims, ok := parsedToken.Claims.(*accessClaims)
 	if !ok || !parsedToken.Valid {
-		return uuid.Nil, errInvalidToken
+		return AccessToken{}, errInvalidToken
+	}
+	// access tokens have no audience; tokens with one are for other uses
+	if len(claims.Audience) > 0 {
+		return AccessToken{}, errInvalidToken
 	}
 
 	userID, err := uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.Nil, errInvalidToken
+		return AccessToken{}, errInvalidToken
+	}
+	out := AccessToken{UserID: userID, Version: claims.Version}
+	if claims.SessionID != "" {
+		sessionID, err := uuid.Parse(claims.SessionID)
+		if err != nil {
+			return AccessToken{}, errInvalidToken
+		}
+		out.SessionID = uuid.NullUUID{UUID: sessionID, Valid: true}
 	}
 
-	return userID, nil
+	if currentVersion != nil {
+		version, err := currentVersion(userID)
+		if err != nil {
+			return AccessToken{}, err
+		}
+		if claims.Version < version {
+			return AccessToken{}, ErrTokenRevoked
+		}
+	}
+	return out, nil
+}
+
+// validates a JWT and returns the user ID if successful
+func ValidateJWT(tokenString string, keys Verifier, currentVersion TokenVersionFunc) (uuid.UUID, error) {
+	token, err := ParseJWT(tokenString, keys, currentVersion)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return token.UserID, nil
 }
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -29,4 +30,11 @@ func MakeRefreshToken() (string, error) {
 		return "", fmt.Errorf("failed to generate token: %w", err)
 	}
 	return hex.EncodeToString(bytes), nil
-}
\ No newline at end of file
+}
+
+// HashToken returns the hex SHA-256 of an opaque token, for storing
+// tokens that only need to be recognised, never read back.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,12 +1,18 @@
 package auth
 
 import (
+	"errors"
 	"testing"
 	"time"
 	"net/http"
 	"github.com/google/uuid"
 )
 
+var (
+	testKey  = NewHMACKey("", testSecret)
+	wrongKey = NewHMACKey("", wrongSecret)
+)
+
 const (
 	testSecret     = "supersecretkey"
 	wrongSecret    = "wrongsecretkey"
@@ -17,12 +23,12 @@ const (
 func TestMakeAndValidateJWT_Success(t *testing.T) {
 	userID := uuid.New()
 
-	token, err := MakeJWT(userID, testSecret, validDuration)
+	token, err := MakeJWT(userID, testKey, validDuration, 0, uuid.Nil)
 	if err != nil {
 		t.Fatalf("expected no error making token, got: %v", err)
 	}
 
-	returnedID, err := ValidateJWT(token, testSecret)
+	returnedID, err := ValidateJWT(token, testKey, nil)
 	if err != nil {
 		t.Fatalf("expected no error validating token, got: %v", err)
 	}
@@ -35,12 +41,12 @@ func TestMakeAndValidateJWT_Success(t *testing.T) {
 func TestValidateJWT_WrongSecret(t *testing.T) {
 	userID := uuid.New()
 
-	token, err := MakeJWT(userID, testSecret, validDuration)
+	token, err := MakeJWT(userID, testKey, validDuration, 0, uuid.Nil)
 	if err != nil {
 		t.Fatalf("error creating token: %v", err)
 	}
 
-	_, err = ValidateJWT(token, wrongSecret)
+	_, err = ValidateJWT(token, wrongKey, nil)
 	if err == nil {
 		t.Fatal("expected error when using wrong secret, got nil")
 	}
@@ -49,17 +55,47 @@ func TestValidateJWT_WrongSecret(t *testing.T) {
 func TestValidateJWT_ExpiredToken(t *testing.T) {
 	userID := uuid.New()
 
-	token, err := MakeJWT(userID, testSecret, expiredDuration)
+	token, err := MakeJWT(userID, testKey, expiredDuration, 0, uuid.Nil)
 	if err != nil {
 		t.Fatalf("error creating expired token: %v", err)
 	}
 
-	_, err = ValidateJWT(token, testSecret)
+	_, err = ValidateJWT(token, testKey, nil)
 	if err == nil {
 		t.Fatal("expected error from expired token, got nil")
 	}
 }
 
+func TestParseJWT_Version(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+
+	token, err := MakeJWT(userID, testKey, validDuration, 2, sessionID)
+	if err != nil {
+		t.Fatalf("error creating token: %v", err)
+	}
+
+	current := func(version int32) TokenVersionFunc {
+		return func(id uuid.UUID) (int32, error) {
+			if id != userID {
+				t.Fatalf("looked up version of %v", id)
+			}
+			return version, nil
+		}
+	}
+	got, err := ParseJWT(token, testKey, current(2))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got.UserID != userID || got.SessionID.UUID != sessionID || got.Version != 2 {
+		t.Errorf("unexpected claims: %+v", got)
+	}
+
+	if _, err := ParseJWT(token, testKey, current(3)); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected ErrTokenRevoked after the version moved on, got: %v", err)
+	}
+}
+
 func TestGetBearerToken(t *testing.T) {
 	headers := http.Header{}
 	headers.Set("Authorization", "Bearer test-token")
@@ -68,4 +104,36 @@ func TestGetBearerToken(t *testing.T) {
 	if err != nil || token != "test-token" {
 		t.Fatalf("expected 'test-token', got '%s', err: %v", token, err)
 	}
-}
\ No newline at end of file
+}
+
+func TestVerificationToken(t *testing.T) {
+	userID := uuid.New()
+
+	token, err := MakeVerificationToken(userID, "alice@example.com", testKey, validDuration)
+	if err != nil {
+		t.Fatalf("error creating token: %v", err)
+	}
+	gotID, email, err := ParseVerificationToken(token, testKey)
+	if err != nil || gotID != userID || email != "alice@example.com" {
+		t.Fatalf("unexpected result %v, %q, %v", gotID, email, err)
+	}
+	if _, err := ValidateJWT(token, testKey, nil); err == nil {
+		t.Error("expected a verification token not to work as an access token")
+	}
+
+	access, err := MakeJWT(userID, testKey, validDuration, 0, uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ParseVerificationToken(access, testKey); err == nil {
+		t.Error("expected an access token not to work as a verification token")
+	}
+
+	expired, err := MakeVerificationToken(userID, "alice@example.com", testKey, expiredDuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ParseVerificationToken(expired, testKey); err == nil {
+		t.Error("expected an expired verification token to be rejected")
+	}
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Verifier checks the signature of access tokens: a single *Key, or a
+// *KeyRing that picks one by the token's kid.
+type Verifier interface {
+	verificationKey(token *jwt.Token) (interface{}, error)
+}
+
+var (
+	_ Verifier = (*Key)(nil)
+	_ Verifier = (*KeyRing)(nil)
+)
+
+// KeyRing holds the key new access tokens are signed with and every key
+// tokens are still accepted from, so the signing key can be replaced
+// without invalidating tokens already handed out. A KeyRing is not safe
+// for concurrent use while keys are being added, so fill it in before
+// sharing it; rotating keys means building a new one.
+type KeyRing struct {
+	signing *Key
+	keys    map[string]ringKey
+}
+
+type ringKey struct {
+	key       *Key
+	expiresAt time.Time
+}
+
+// NewKeyRing returns a key ring that signs with signing and accepts only
+// its tokens.
+func NewKeyRing(signing *Key) *KeyRing {
+	r := &KeyRing{signing: signing, keys: make(map[string]ringKey)}
+	r.keys[signing.ID] = ringKey{key: signing}
+	return r
+}
+
+// AddVerificationKey accepts tokens signed by k, identified by its kid,
+// until expiresAt, or indefinitely if expiresAt is zero. It replaces any
+// key with the same ID other than the signing key.
+func (r *KeyRing) AddVerificationKey(k *Key, expiresAt time.Time) {
+	if k.ID == r.signing.ID {
+		return
+	}
+	r.keys[k.ID] = ringKey{key: k, expiresAt: expiresAt}
+}
+
+// SigningKey is the key new tokens are signed with.
+func (r *KeyRing) SigningKey() *Key {
+	return r.signing
+}
+
+// JWKS returns the public halves of the keys accepted at now.
+func (r *KeyRing) JWKS(now time.Time) JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, rk := range r.keys {
+		if rk.expired(now) {
+			continue
+		}
+		if jwk, ok := rk.key.PublicJWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func (rk ringKey) expired(now time.Time) bool {
+	return !rk.expiresAt.IsZero() && !now.Before(rk.expiresAt)
+}
+
+func (r *KeyRing) verificationKey(token *jwt.Token) (interface{}, error) {
+	// tokens signed with an HMAC secret before key IDs were used carry no
+	// kid; they match a key without an ID
+	kid, _ := token.Header["kid"].(string)
+	rk, ok := r.keys[kid]
+	if !ok || rk.expired(time.Now()) {
+		return nil, errInvalidToken
+	}
+	return rk.key.verificationKey(token)
+}
+
+// GenerateKey creates a new RS256 or EdDSA key, identified by its
+// thumbprint.
+func GenerateKey(alg string) (*Key, error) {
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey("", private)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey("", private)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+// MarshalPEM encodes the private key as PEM encoded PKCS #8, which
+// ParseKeyPEM reads back. HMAC keys cannot be encoded.
+func (k *Key) MarshalPEM() ([]byte, error) {
+	if _, ok := k.sign.([]byte); ok {
+		return nil, errors.New("cannot encode an HMAC key")
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(k.sign)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestKeyRing(t *testing.T) {
+	old := NewHMACKey("", testSecret)
+	current, err := GenerateKey("EdDSA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired, err := GenerateKey("RS256")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ring := NewKeyRing(current)
+	ring.AddVerificationKey(old, time.Now().Add(time.Hour))
+	ring.AddVerificationKey(retired, time.Now().Add(-time.Second))
+
+	userID := uuid.New()
+	for name, tc := range map[string]struct {
+		key  *Key
+		want bool
+	}{
+		"signing key":         {current, true},
+		"replaced key":        {old, true},
+		"expired key":         {retired, false},
+		"key not in the ring": {wrongKey, false},
+	} {
+		token, err := MakeJWT(userID, tc.key, validDuration, 0, uuid.Nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ValidateJWT(token, ring, nil)
+		if tc.want && (err != nil || got != userID) {
+			t.Errorf("%s: expected the token to verify, got %v", name, err)
+		}
+		if !tc.want && err == nil {
+			t.Errorf("%s: expected the token to be rejected", name)
+		}
+	}
+
+	set := ring.JWKS(time.Now())
+	if len(set.Keys) != 1 || set.Keys[0].Kid != current.ID {
+		t.Errorf("expected only the signing key to be published, got %+v", set.Keys)
+	}
+	if ring.SigningKey() != current {
+		t.Error("unexpected signing key")
+	}
+}
+
+func TestGenerateKey_MarshalPEM(t *testing.T) {
+	for _, alg := range []string{"RS256", "EdDSA"} {
+		key, err := GenerateKey(alg)
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		data, err := key.MarshalPEM()
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		back, err := ParseKeyPEM("", data)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", alg, err)
+		}
+		if back.ID != key.ID || back.Algorithm() != alg {
+			t.Errorf("%s: got %s/%s back, want %s", alg, back.ID, back.Algorithm(), key.ID)
+		}
+	}
+	if _, err := GenerateKey("HS256"); err == nil {
+		t.Error("expected HS256 to be refused")
+	}
+	if _, err := NewHMACKey("", testSecret).MarshalPEM(); err == nil {
+		t.Error("expected HMAC keys not to be encoded")
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"crypto/subtle"
 	"errors"
 	"net/http"
 	"strings"
@@ -12,4 +13,17 @@ func GetAPIKey(headers http.Header) (string, error) {
 		return "", errors.New("missing or invalid Authorization header")
 	}
 	return strings.TrimPrefix(authHeader, "ApiKey "), nil
-}
\ No newline at end of file
+}
+// CheckAPIKey reports whether the ApiKey in headers matches want. The
+// comparison takes the same time wherever the keys differ, and an empty
+// want never matches.
+func CheckAPIKey(headers http.Header, want string) error {
+	key, err := GetAPIKey(headers)
+	if err != nil {
+		return err
+	}
+	if want == "" || subtle.ConstantTimeCompare([]byte(key), []byte(want)) != 1 {
+		return errors.New("invalid API key")
+	}
+	return nil
+}
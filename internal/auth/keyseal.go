@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// sealedKeyPrefix marks a private key sealed by SealKeyPEM, and names the
+// cipher so another can be introduced later.
+const sealedKeyPrefix = "aes256gcm:"
+
+var errSealedKey = errors.New("cannot open sealed key")
+
+// SealKeyPEM encrypts a PEM encoded private key for storing, with
+// AES-256-GCM under kek, a 32 byte key-encryption key. The key's id is
+// authenticated along with it, so a sealed key only opens for that id.
+func SealKeyPEM(kek []byte, id string, data []byte) (string, error) {
+	gcm, err := newKeyGCM(kek)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, data, []byte(id))
+	return sealedKeyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenKeyPEM decrypts a key sealed by SealKeyPEM.
+func OpenKeyPEM(kek []byte, id, sealed string) ([]byte, error) {
+	gcm, err := newKeyGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	if !IsSealedKey(sealed) {
+		return nil, errSealedKey
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedKeyPrefix))
+	if err != nil || len(data) < gcm.NonceSize() {
+		return nil, errSealedKey
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return nil, errSealedKey
+	}
+	return plain, nil
+}
+
+// IsSealedKey reports whether s was produced by SealKeyPEM, as opposed to
+// a plain PEM key.
+func IsSealedKey(s string) bool {
+	return strings.HasPrefix(s, sealedKeyPrefix)
+}
+
+func newKeyGCM(kek []byte) (cipher.AEAD, error) {
+	if len(kek) != 32 {
+		return nil, errors.New("key-encryption key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSealKeyPEM(t *testing.T) {
+	kek := bytes.Repeat([]byte{7}, 32)
+	key, err := GenerateKey("EdDSA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := key.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := SealKeyPEM(kek, key.ID, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealedKey(sealed) || strings.Contains(sealed, "PRIVATE KEY") {
+		t.Fatalf("expected an opaque sealed key, got %q", sealed)
+	}
+	opened, err := OpenKeyPEM(kek, key.ID, sealed)
+	if err != nil || !bytes.Equal(opened, private) {
+		t.Fatalf("OpenKeyPEM: %v", err)
+	}
+
+	for name, open := range map[string]func() ([]byte, error){
+		"wrong kek": func() ([]byte, error) { return OpenKeyPEM(bytes.Repeat([]byte{8}, 32), key.ID, sealed) },
+		"wrong id":  func() ([]byte, error) { return OpenKeyPEM(kek, "other", sealed) },
+		"plain PEM": func() ([]byte, error) { return OpenKeyPEM(kek, key.ID, string(private)) },
+		"short kek": func() ([]byte, error) { return OpenKeyPEM(kek[:16], key.ID, sealed) },
+		"truncated": func() ([]byte, error) { return OpenKeyPEM(kek, key.ID, sealed[:len(sealed)-4]) },
+	} {
+		if _, err := open(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
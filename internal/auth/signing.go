@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key signs and verifies access tokens. An HMAC key is a shared secret;
+// RSA (RS256) and Ed25519 (EdDSA) keys have a public half that other
+// services can verify tokens with, published as a JWK.
+type Key struct {
+	// ID is sent as the kid header of tokens the key signs.
+	ID     string
+	method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+}
+
+// NewHMACKey returns an HS256 key for secret.
+func NewHMACKey(id, secret string) *Key {
+	return &Key{ID: id, method: jwt.SigningMethodHS256, sign: []byte(secret), verify: []byte(secret)}
+}
+
+// NewKey wraps an *rsa.PrivateKey or ed25519.PrivateKey. When id is empty
+// the key's RFC 7638 thumbprint is used.
+func NewKey(id string, private crypto.Signer) (*Key, error) {
+	var k *Key
+	switch priv := private.(type) {
+	case *rsa.PrivateKey:
+		if priv.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA key is %d bits, need at least 2048", priv.N.BitLen())
+		}
+		k = &Key{method: jwt.SigningMethodRS256, sign: priv, verify: &priv.PublicKey}
+	case ed25519.PrivateKey:
+		k = &Key{method: jwt.SigningMethodEdDSA, sign: priv, verify: priv.Public()}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", private)
+	}
+	k.ID = id
+	if k.ID == "" {
+		jwk, _ := k.PublicJWK()
+		k.ID = jwk.Thumbprint()
+	}
+	return k, nil
+}
+
+// ParseKeyPEM reads a PEM encoded PKCS #8 private key, or a PKCS #1 RSA
+// private key.
+func ParseKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	var private interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", private)
+	}
+	return NewKey(id, signer)
+}
+
+// LoadKeyFile reads a private key from a PEM file; see ParseKeyPEM.
+func LoadKeyFile(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	k, err := ParseKeyPEM(id, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return k, nil
+}
+
+// Algorithm is the JWS alg the key signs with.
+func (k *Key) Algorithm() string {
+	return k.method.Alg()
+}
+
+// JWK is the public half of a key as a JSON Web Key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set, as served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWK returns the public half of the key. HMAC keys have none and
+// report false.
+func (k *Key) PublicJWK() (JWK, bool) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwk := JWK{Use: "sig", Alg: k.Algorithm(), Kid: k.ID}
+	switch pub := k.verify.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(pub.N.Bytes())
+		jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64(pub)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// Thumbprint is the RFC 7638 SHA-256 thumbprint of the key.
+func (j JWK) Thumbprint() string {
+	// the required members in lexicographic order, as the RFC specifies
+	var members interface{}
+	switch j.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.Kty, j.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Crv, j.Kty, j.X}
+	default:
+		return ""
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (k *Key) signToken(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	if k.ID != "" {
+		token.Header["kid"] = k.ID
+	}
+	return token.SignedString(k.sign)
+}
+
+// verificationKey is the jwt.Keyfunc for tokens signed by k. The alg must
+// be the key's own, so a public key can never be used as an HMAC secret.
+func (k *Key) verificationKey(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != k.Algorithm() {
+		return nil, errInvalidToken
+	}
+	if kid, ok := token.Header["kid"].(string); ok && k.ID != "" && kid != k.ID {
+		return nil, errInvalidToken
+	}
+	return k.verify, nil
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// writeKeyPEM PKCS #8 encodes private into a temporary file.
+func writeKeyPEM(t *testing.T, private interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		private interface{}
+		alg     string
+		kty     string
+	}{
+		{rsaKey, "RS256", "RSA"},
+		{edKey, "EdDSA", "OKP"},
+	} {
+		t.Run(tc.alg, func(t *testing.T) {
+			key, err := LoadKeyFile("", writeKeyPEM(t, tc.private))
+			if err != nil {
+				t.Fatalf("load key: %v", err)
+			}
+			jwk, ok := key.PublicJWK()
+			if !ok {
+				t.Fatal("expected a public JWK")
+			}
+			if jwk.Alg != tc.alg || jwk.Kty != tc.kty || jwk.Use != "sig" {
+				t.Errorf("unexpected JWK %+v", jwk)
+			}
+			if key.ID == "" || jwk.Kid != key.ID || key.ID != jwk.Thumbprint() {
+				t.Errorf("expected the thumbprint as kid, got %q (thumbprint %q)", key.ID, jwk.Thumbprint())
+			}
+
+			userID := uuid.New()
+			token, err := MakeJWT(userID, key, validDuration, 0, uuid.Nil)
+			if err != nil {
+				t.Fatalf("make token: %v", err)
+			}
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Header["alg"] != tc.alg || parsed.Header["kid"] != key.ID {
+				t.Errorf("unexpected header %v", parsed.Header)
+			}
+			got, err := ValidateJWT(token, key, nil)
+			if err != nil || got != userID {
+				t.Fatalf("validate: got %v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestParseKeyPEM_Errors(t *testing.T) {
+	if _, err := ParseKeyPEM("", []byte("not a key")); err == nil {
+		t.Error("expected an error for non-PEM data")
+	}
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der := x509.MarshalPKCS1PrivateKey(small)
+	_, err = ParseKeyPEM("", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}))
+	if err == nil || !strings.Contains(err.Error(), "2048") {
+		t.Errorf("expected a short RSA key to be refused, got %v", err)
+	}
+}
+
+func TestValidateJWT_KeyMismatch(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := NewKey("k1", edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.New()
+
+	// an HMAC token must not verify against an asymmetric key...
+	hsToken, err := MakeJWT(userID, NewHMACKey("k1", testSecret), validDuration, 0, uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateJWT(hsToken, key, nil); err == nil {
+		t.Error("expected an HS256 token to be rejected")
+	}
+
+	// ...nor a token naming another key
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	other, err := NewKey("k2", otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := MakeJWT(userID, other, validDuration, 0, uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateJWT(token, key, nil); err == nil {
+		t.Error("expected a token for another kid to be rejected")
+	}
+}
+
+func TestHMACKeyHasNoJWK(t *testing.T) {
+	if _, ok := NewHMACKey("", testSecret).PublicJWK(); ok {
+		t.Error("HMAC keys must not be published")
+	}
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// emailVerificationAudience marks email verification tokens, which are
+// signed like access tokens but must never be accepted as one.
+const emailVerificationAudience = "chirpy:verify-email"
+
+type verificationClaims struct {
+	jwt.RegisteredClaims
+	Email string `json:"email"`
+}
+
+// MakeVerificationToken signs a token proving that whoever holds it
+// received mail sent to email for userID.
+func MakeVerificationToken(userID uuid.UUID, email string, key *Key, expiresIn time.Duration) (string, error) {
+	now := time.Now().UTC()
+	return key.signToken(verificationClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			Audience:  jwt.ClaimStrings{emailVerificationAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Email: email,
+	})
+}
+
+// ParseVerificationToken checks a token made by MakeVerificationToken and
+// returns the user and address it verifies.
+func ParseVerificationToken(tokenString string, keys Verifier) (uuid.UUID, string, error) {
+	claims := &verificationClaims{}
+	parsed, err := jwt.ParseWithClaims(tokenString, claims, keys.verificationKey,
+		jwt.WithAudience(emailVerificationAudience), jwt.WithExpirationRequired())
+	if err != nil || !parsed.Valid || claims.Email == "" {
+		return uuid.Nil, "", errInvalidToken
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, "", errInvalidToken
+	}
+	return userID, claims.Email, nil
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"event":"user.upgraded"}`)
+	now := time.Now()
+	ts := now.Unix()
+	sig := SignWebhook(testSecret, ts, body)
+	stamp := strconv.FormatInt(ts, 10)
+
+	if err := VerifyWebhookSignature(testSecret, stamp, sig, body, now); err != nil {
+		t.Fatalf("valid signature: %v", err)
+	}
+	if err := VerifyWebhookSignature(wrongSecret, stamp, sig, body, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("wrong secret: got %v", err)
+	}
+	if err := VerifyWebhookSignature(testSecret, stamp, sig, []byte(`{"event":"user.downgraded"}`), now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered body: got %v", err)
+	}
+	if err := VerifyWebhookSignature(testSecret, strconv.FormatInt(ts+1, 10), sig, body, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("changed timestamp: got %v", err)
+	}
+	if err := VerifyWebhookSignature(testSecret, stamp, "not-hex", body, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("malformed signature: got %v", err)
+	}
+	if err := VerifyWebhookSignature(testSecret, stamp, sig, body, now.Add(WebhookTolerance+time.Second)); !errors.Is(err, ErrStaleWebhook) {
+		t.Errorf("replayed delivery: got %v", err)
+	}
+}
+
+func TestCheckAPIKey(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "ApiKey secret")
+	if err := CheckAPIKey(headers, "secret"); err != nil {
+		t.Errorf("matching key: %v", err)
+	}
+	if err := CheckAPIKey(headers, "secreT"); err == nil {
+		t.Error("expected mismatched key to fail")
+	}
+	if err := CheckAPIKey(headers, ""); err == nil {
+		t.Error("expected an unset key to never match")
+	}
+	if err := CheckAPIKey(http.Header{}, "secret"); err == nil {
+		t.Error("expected missing header to fail")
+	}
+}
@@ -7,23 +7,41 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const chirpHasReplies = `-- name: ChirpHasReplies :one
+SELECT EXISTS (SELECT 1 FROM chirps WHERE in_reply_to = $1)
+`
+
+// Deleted replies count too: one may yet be restored into the thread.
+func (q *Queries) ChirpHasReplies(ctx context.Context, inReplyTo uuid.NullUUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, chirpHasReplies, inReplyTo)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, created_at, updated_at, body, user_id
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, rechirp_of, quote_of)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at
 `
 
 type CreateChirpParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
-	UserID    uuid.UUID
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	InReplyTo      uuid.NullUUID
+	ConversationID uuid.UUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
@@ -33,6 +51,10 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		arg.UpdatedAt,
 		arg.Body,
 		arg.UserID,
+		arg.InReplyTo,
+		arg.ConversationID,
+		arg.RechirpOf,
+		arg.QuoteOf,
 	)
 	var i Chirp
 	err := row.Scan(
@@ -41,6 +63,14 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.ConversationID,
+		&i.TombstonedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -54,8 +84,48 @@ func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const editChirp = `-- name: EditChirp :one
+WITH revision AS (
+    INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+    SELECT gen_random_uuid(), id, body, COALESCE(edited_at, created_at), NOW()
+    FROM chirps WHERE id = $1
+)
+UPDATE chirps
+SET body = $2, edited_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at
+`
+
+type EditChirpParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+// the replaced body is saved in the same statement so no edit can lose it
+func (q *Queries) EditChirp(ctx context.Context, arg EditChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, editChirp, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.ConversationID,
+		&i.TombstonedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at FROM chirps
+WHERE deleted_at IS NULL
 ORDER BY created_at ASC
 `
 
@@ -74,6 +144,14 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -89,8 +167,8 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsByAuthorID = `-- name: GetChirpsByAuthorID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE user_id = $1
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at FROM chirps
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at ASC
 `
 
@@ -109,6 +187,14 @@ func (q *Queries) GetChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -124,8 +210,8 @@ func (q *Queries) GetChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]
 }
 
 const getChirpsByID = `-- name: GetChirpsByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at FROM chirps
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetChirpsByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -137,6 +223,696 @@ func (q *Queries) GetChirpsByID(ctx context.Context, id uuid.UUID) (Chirp, error
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.ConversationID,
+		&i.TombstonedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at FROM chirps
+WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversation = `-- name: GetConversation :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at FROM chirps
+WHERE conversation_id = $1
+ORDER BY created_at ASC, id ASC
+`
+
+func (q *Queries) GetConversation(ctx context.Context, conversationID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getConversation, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedChirp = `-- name: GetDeletedChirp :one
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at FROM chirps
+WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) GetDeletedChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.ConversationID,
+		&i.TombstonedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getRechirp = `-- name: GetRechirp :one
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at FROM chirps
+WHERE user_id = $1 AND rechirp_of = $2
+`
+
+type GetRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getRechirp, arg.UserID, arg.RechirpOf)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.ConversationID,
+		&i.TombstonedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getRechirpStats = `-- name: GetRechirpStats :many
+SELECT c.id AS chirp_id,
+       (SELECT COUNT(*) FROM chirps r WHERE r.rechirp_of = c.id AND r.deleted_at IS NULL) AS rechirp_count,
+       (SELECT COUNT(*) FROM chirps q WHERE q.quote_of = c.id AND q.deleted_at IS NULL) AS quote_count,
+       EXISTS (SELECT 1 FROM chirps r WHERE r.rechirp_of = c.id AND r.deleted_at IS NULL AND r.user_id = $1::uuid) AS rechirped_by_me
+FROM chirps c
+WHERE c.id = ANY($2::uuid[])
+`
+
+type GetRechirpStatsParams struct {
+	ViewerID uuid.NullUUID
+	ChirpIds []uuid.UUID
+}
+
+type GetRechirpStatsRow struct {
+	ChirpID       uuid.UUID
+	RechirpCount  int64
+	QuoteCount    int64
+	RechirpedByMe bool
+}
+
+func (q *Queries) GetRechirpStats(ctx context.Context, arg GetRechirpStatsParams) ([]GetRechirpStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRechirpStats, arg.ViewerID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRechirpStatsRow
+	for rows.Next() {
+		var i GetRechirpStatsRow
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.RechirpedByMe,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsAfter = `-- name: ListChirpsAfter :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at FROM chirps
+WHERE deleted_at IS NULL
+  AND ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::uuid IS NULL OR in_reply_to = $2::uuid)
+  AND ($3::timestamp IS NULL
+       OR (created_at, id) > ($3::timestamp, $4::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $5
+`
+
+type ListChirpsAfterParams struct {
+	AuthorID        uuid.NullUUID
+	InReplyTo       uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) ListChirpsAfter(ctx context.Context, arg ListChirpsAfterParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAfter,
+		arg.AuthorID,
+		arg.InReplyTo,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsBefore = `-- name: ListChirpsBefore :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at FROM chirps
+WHERE deleted_at IS NULL
+  AND ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::uuid IS NULL OR in_reply_to = $2::uuid)
+  AND ($3::timestamp IS NULL
+       OR (created_at, id) < ($3::timestamp, $4::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type ListChirpsBeforeParams struct {
+	AuthorID        uuid.NullUUID
+	InReplyTo       uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) ListChirpsBefore(ctx context.Context, arg ListChirpsBeforeParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsBefore,
+		arg.AuthorID,
+		arg.InReplyTo,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPurgeableChirps = `-- name: ListPurgeableChirps :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at FROM chirps
+WHERE deleted_at < $1 AND tombstoned_at IS NULL
+ORDER BY deleted_at ASC, id ASC
+LIMIT $2
+`
+
+type ListPurgeableChirpsParams struct {
+	Before    sql.NullTime
+	MaxChirps int32
+}
+
+func (q *Queries) ListPurgeableChirps(ctx context.Context, arg ListPurgeableChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listPurgeableChirps, arg.Before, arg.MaxChirps)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreChirp = `-- name: RestoreChirp :exec
+UPDATE chirps
+SET deleted_at = NULL
+WHERE (id = $1 OR rechirp_of = $1) AND deleted_at = $2
+`
+
+type RestoreChirpParams struct {
+	ID        uuid.UUID
+	DeletedAt sql.NullTime
+}
+
+// Only rechirps hidden by the same soft delete come back.
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) error {
+	_, err := q.db.ExecContext(ctx, restoreChirp, arg.ID, arg.DeletedAt)
+	return err
+}
+
+const searchChirpsAfter = `-- name: SearchChirpsAfter :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at FROM chirps
+WHERE search_vector @@ to_tsquery('english', $1)
+  AND deleted_at IS NULL
+  AND ($2::uuid IS NULL OR user_id = $2::uuid)
+  AND ($3::timestamp IS NULL
+       OR (created_at, id) > ($3::timestamp, $4::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $5
+`
+
+type SearchChirpsAfterParams struct {
+	Query           string
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) SearchChirpsAfter(ctx context.Context, arg SearchChirpsAfterParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirpsAfter,
+		arg.Query,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchChirpsBefore = `-- name: SearchChirpsBefore :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, conversation_id, tombstoned_at, rechirp_of, quote_of, edited_at, deleted_at FROM chirps
+WHERE search_vector @@ to_tsquery('english', $1)
+  AND deleted_at IS NULL
+  AND ($2::uuid IS NULL OR user_id = $2::uuid)
+  AND ($3::timestamp IS NULL
+       OR (created_at, id) < ($3::timestamp, $4::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type SearchChirpsBeforeParams struct {
+	Query           string
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) SearchChirpsBefore(ctx context.Context, arg SearchChirpsBeforeParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirpsBefore,
+		arg.Query,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchChirpsByRankAfter = `-- name: SearchChirpsByRankAfter :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.in_reply_to, chirps.conversation_id, chirps.tombstoned_at, chirps.rechirp_of, chirps.quote_of, chirps.edited_at, chirps.deleted_at, ts_rank(search_vector, to_tsquery('english', $1))::real AS rank
+FROM chirps
+WHERE search_vector @@ to_tsquery('english', $1)
+  AND deleted_at IS NULL
+  AND ($2::uuid IS NULL OR user_id = $2::uuid)
+  AND ($3::real IS NULL
+       OR (ts_rank(search_vector, to_tsquery('english', $1))::real, created_at, id)
+          > ($3::real, $4::timestamp, $5::uuid))
+ORDER BY rank ASC, created_at ASC, id ASC
+LIMIT $6
+`
+
+type SearchChirpsByRankAfterParams struct {
+	Query           string
+	AuthorID        uuid.NullUUID
+	CursorRank      sql.NullFloat64
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+type SearchChirpsByRankAfterRow struct {
+	Chirp Chirp
+	Rank  float32
+}
+
+func (q *Queries) SearchChirpsByRankAfter(ctx context.Context, arg SearchChirpsByRankAfterParams) ([]SearchChirpsByRankAfterRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirpsByRankAfter,
+		arg.Query,
+		arg.AuthorID,
+		arg.CursorRank,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsByRankAfterRow
+	for rows.Next() {
+		var i SearchChirpsByRankAfterRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.SearchVector,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.ConversationID,
+			&i.Chirp.TombstonedAt,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Chirp.EditedAt,
+			&i.Chirp.DeletedAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchChirpsByRankBefore = `-- name: SearchChirpsByRankBefore :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.in_reply_to, chirps.conversation_id, chirps.tombstoned_at, chirps.rechirp_of, chirps.quote_of, chirps.edited_at, chirps.deleted_at, ts_rank(search_vector, to_tsquery('english', $1))::real AS rank
+FROM chirps
+WHERE search_vector @@ to_tsquery('english', $1)
+  AND deleted_at IS NULL
+  AND ($2::uuid IS NULL OR user_id = $2::uuid)
+  AND ($3::real IS NULL
+       OR (ts_rank(search_vector, to_tsquery('english', $1))::real, created_at, id)
+          < ($3::real, $4::timestamp, $5::uuid))
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT $6
+`
+
+type SearchChirpsByRankBeforeParams struct {
+	Query           string
+	AuthorID        uuid.NullUUID
+	CursorRank      sql.NullFloat64
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+type SearchChirpsByRankBeforeRow struct {
+	Chirp Chirp
+	Rank  float32
+}
+
+func (q *Queries) SearchChirpsByRankBefore(ctx context.Context, arg SearchChirpsByRankBeforeParams) ([]SearchChirpsByRankBeforeRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirpsByRankBefore,
+		arg.Query,
+		arg.AuthorID,
+		arg.CursorRank,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsByRankBeforeRow
+	for rows.Next() {
+		var i SearchChirpsByRankBeforeRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.SearchVector,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.ConversationID,
+			&i.Chirp.TombstonedAt,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Chirp.EditedAt,
+			&i.Chirp.DeletedAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :exec
+UPDATE chirps
+SET deleted_at = $2
+WHERE (id = $1 OR rechirp_of = $1) AND deleted_at IS NULL
+`
+
+type SoftDeleteChirpParams struct {
+	ID        uuid.UUID
+	DeletedAt sql.NullTime
+}
+
+// Rechirps of the chirp are hidden along with it.
+func (q *Queries) SoftDeleteChirp(ctx context.Context, arg SoftDeleteChirpParams) error {
+	_, err := q.db.ExecContext(ctx, softDeleteChirp, arg.ID, arg.DeletedAt)
+	return err
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '', tombstoned_at = $2, updated_at = $2
+WHERE id = $1
+`
+
+type TombstoneChirpParams struct {
+	ID           uuid.UUID
+	TombstonedAt sql.NullTime
+}
+
+func (q *Queries) TombstoneChirp(ctx context.Context, arg TombstoneChirpParams) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, arg.ID, arg.TombstonedAt)
+	return err
+}
@@ -0,0 +1,14 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// IsUniqueViolation reports whether err is a Postgres unique constraint
+// violation, as returned by both *Queries and MemStore.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation
+}
@@ -0,0 +1,806 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// MemStore is an in-memory Store. It mirrors the constraints declared in
+// sql/schema (unique emails, ON DELETE CASCADE) and reports violations with
+// the same *pq.Error codes Postgres would, so handlers behave identically
+// against either backend.
+type MemStore struct {
+	mu            sync.RWMutex
+	users         map[uuid.UUID]User
+	chirps        map[uuid.UUID]Chirp
+	refreshTokens map[string]RefreshToken
+	likes         map[likeKey]Like
+	follows       map[followKey]Follow
+	tags          map[uuid.UUID]Tag
+	chirpTags     map[chirpTagKey]ChirpTag
+	notifications map[uuid.UUID]Notification
+	media         map[uuid.UUID]MediaAttachment
+	revisions     map[uuid.UUID]ChirpRevision
+	webhookEvents map[uuid.UUID]WebhookEvent
+	memberships   map[uuid.UUID]Membership
+	memberHistory map[uuid.UUID]MembershipHistory
+	rateLimits    map[string]RateLimitBucket
+	loginAttempts map[uuid.UUID]LoginAttempt
+	loginThrottle map[string]LoginThrottle
+	jwtKeys       map[string]JwtKey
+	resets        map[string]PasswordReset
+}
+
+type likeKey struct {
+	userID  uuid.UUID
+	chirpID uuid.UUID
+}
+
+var _ Store = (*MemStore)(nil)
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		users:         make(map[uuid.UUID]User),
+		chirps:        make(map[uuid.UUID]Chirp),
+		refreshTokens: make(map[string]RefreshToken),
+		likes:         make(map[likeKey]Like),
+		follows:       make(map[followKey]Follow),
+		tags:          make(map[uuid.UUID]Tag),
+		chirpTags:     make(map[chirpTagKey]ChirpTag),
+		notifications: make(map[uuid.UUID]Notification),
+		media:         make(map[uuid.UUID]MediaAttachment),
+		revisions:     make(map[uuid.UUID]ChirpRevision),
+		webhookEvents: make(map[uuid.UUID]WebhookEvent),
+		memberships:   make(map[uuid.UUID]Membership),
+		memberHistory: make(map[uuid.UUID]MembershipHistory),
+		rateLimits:    make(map[string]RateLimitBucket),
+		loginAttempts: make(map[uuid.UUID]LoginAttempt),
+		loginThrottle: make(map[string]LoginThrottle),
+		jwtKeys:       make(map[string]JwtKey),
+		resets:        make(map[string]PasswordReset),
+	}
+}
+
+const (
+	pqUniqueViolation     = pq.ErrorCode("23505")
+	pqForeignKeyViolation = pq.ErrorCode("23503")
+	pqCheckViolation      = pq.ErrorCode("23514")
+)
+
+func uniqueViolation(constraint string) error {
+	return &pq.Error{
+		Code:       pqUniqueViolation,
+		Message:    "duplicate key value violates unique constraint \"" + constraint + "\"",
+		Constraint: constraint,
+	}
+}
+
+func foreignKeyViolation(constraint string) error {
+	return &pq.Error{
+		Code:       pqForeignKeyViolation,
+		Message:    "insert or update violates foreign key constraint \"" + constraint + "\"",
+		Constraint: constraint,
+	}
+}
+
+func checkViolation(constraint string) error {
+	return &pq.Error{
+		Code:       pqCheckViolation,
+		Message:    "new row violates check constraint \"" + constraint + "\"",
+		Constraint: constraint,
+	}
+}
+
+// now matches the precision of a Postgres TIMESTAMP column.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// truncateNullTime stores t at the precision of a TIMESTAMP column.
+func truncateNullTime(t sql.NullTime) sql.NullTime {
+	if t.Valid {
+		t.Time = t.Time.UTC().Truncate(time.Microsecond)
+	}
+	return t
+}
+
+// chirpLess orders chirps by (created_at, id), the keyset used for paging.
+func chirpLess(a, b Chirp) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return bytes.Compare(a.ID[:], b.ID[:]) < 0
+}
+
+func sortChirpsAsc(chirps []Chirp) {
+	sort.Slice(chirps, func(i, j int) bool {
+		return chirpLess(chirps[i], chirps[j])
+	})
+}
+
+func sortChirpsDesc(chirps []Chirp) {
+	sort.Slice(chirps, func(i, j int) bool {
+		return chirpLess(chirps[j], chirps[i])
+	})
+}
+
+func limitChirps(chirps []Chirp, n int32) []Chirp {
+	if n >= 0 && int(n) < len(chirps) {
+		return chirps[:n]
+	}
+	return chirps
+}
+
+// users
+
+func (m *MemStore) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Email == arg.Email {
+			return User{}, uniqueViolation("users_email_key")
+		}
+		if arg.Username.Valid && u.Username == arg.Username {
+			return User{}, uniqueViolation("users_username_key")
+		}
+	}
+	t := now()
+	user := User{
+		ID:             uuid.New(),
+		CreatedAt:      t,
+		UpdatedAt:      t,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+		Username:       arg.Username,
+	}
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *MemStore) DeleteAllUsers(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// everything else references users ON DELETE CASCADE
+	clear(m.users)
+	clear(m.chirps)
+	clear(m.refreshTokens)
+	clear(m.likes)
+	clear(m.follows)
+	clear(m.chirpTags)
+	clear(m.notifications)
+	clear(m.media)
+	clear(m.revisions)
+	clear(m.memberships)
+	clear(m.memberHistory)
+	clear(m.resets)
+	// login_attempts.user_id is ON DELETE SET NULL
+	for id, a := range m.loginAttempts {
+		a.UserID = uuid.NullUUID{}
+		m.loginAttempts[id] = a
+	}
+	return nil
+}
+
+func (m *MemStore) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return User{}, sql.ErrNoRows
+}
+
+func (m *MemStore) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	return u, nil
+}
+
+func (m *MemStore) GetUsersByUsernames(ctx context.Context, usernames []string) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []User
+	for _, u := range m.users {
+		if u.Username.Valid && slices.Contains(usernames, u.Username.String) {
+			items = append(items, u)
+		}
+	}
+	return items, nil
+}
+
+func (m *MemStore) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[arg.ID]
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	for _, u := range m.users {
+		if u.ID != arg.ID && u.Email == arg.Email {
+			return User{}, uniqueViolation("users_email_key")
+		}
+		if u.ID != arg.ID && arg.Username.Valid && u.Username == arg.Username {
+			return User{}, uniqueViolation("users_username_key")
+		}
+	}
+	if user.Email != arg.Email {
+		user.VerifiedAt = sql.NullTime{}
+	}
+	user.Email = arg.Email
+	user.HashedPassword = arg.HashedPassword
+	if arg.Username.Valid {
+		user.Username = arg.Username
+	}
+	user.UpdatedAt = now()
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *MemStore) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[arg.ID]
+	if !ok || user.Email != arg.Email || user.VerifiedAt.Valid {
+		return 0, nil
+	}
+	user.VerifiedAt = truncateNullTime(arg.VerifiedAt)
+	user.UpdatedAt = user.VerifiedAt.Time
+	m.users[user.ID] = user
+	return 1, nil
+}
+
+// refresh tokens
+
+func (m *MemStore) InsertRefreshToken(ctx context.Context, arg InsertRefreshTokenParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return foreignKeyViolation("refresh_tokens_user_id_fkey")
+	}
+	if _, ok := m.refreshTokens[arg.Token]; ok {
+		return uniqueViolation("refresh_tokens_pkey")
+	}
+	t := now()
+	m.refreshTokens[arg.Token] = RefreshToken{
+		Token:      arg.Token,
+		CreatedAt:  t,
+		UpdatedAt:  t,
+		UserID:     arg.UserID,
+		ExpiresAt:  arg.ExpiresAt,
+		FamilyID:   arg.FamilyID,
+		UserAgent:  arg.UserAgent,
+		Ip:         arg.Ip,
+		LastUsedAt: t,
+	}
+	return nil
+}
+
+func (m *MemStore) GetUserFromRefreshToken(ctx context.Context, token string) (GetUserFromRefreshTokenRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rt, ok := m.refreshTokens[token]
+	if !ok {
+		return GetUserFromRefreshTokenRow{}, sql.ErrNoRows
+	}
+	return GetUserFromRefreshTokenRow{
+		Token:     rt.Token,
+		UserID:    rt.UserID,
+		FamilyID:  rt.FamilyID,
+		ExpiresAt: rt.ExpiresAt,
+		RevokedAt: rt.RevokedAt,
+		RotatedAt: rt.RotatedAt,
+	}, nil
+}
+
+func (m *MemStore) RevokeRefreshTokenFamily(ctx context.Context, arg RevokeRefreshTokenFamilyParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	at := truncateNullTime(arg.RevokedAt)
+	var n int64
+	for token, rt := range m.refreshTokens {
+		if rt.UserID == arg.UserID && rt.FamilyID == arg.FamilyID && !rt.RevokedAt.Valid {
+			rt.RevokedAt = at
+			rt.UpdatedAt = at.Time
+			m.refreshTokens[token] = rt
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemStore) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rt, ok := m.refreshTokens[arg.Token]
+	if !ok || rt.RotatedAt.Valid || rt.RevokedAt.Valid {
+		return 0, nil
+	}
+	if _, ok := m.refreshTokens[arg.NewToken]; ok {
+		return 0, uniqueViolation("refresh_tokens_pkey")
+	}
+	at := truncateNullTime(arg.RotatedAt)
+	rt.RotatedAt = at
+	rt.ReplacedBy = sql.NullString{String: arg.NewToken, Valid: true}
+	rt.UpdatedAt = at.Time
+	m.refreshTokens[arg.Token] = rt
+	m.refreshTokens[arg.NewToken] = RefreshToken{
+		Token:      arg.NewToken,
+		CreatedAt:  at.Time,
+		UpdatedAt:  at.Time,
+		UserID:     rt.UserID,
+		ExpiresAt:  arg.ExpiresAt,
+		FamilyID:   rt.FamilyID,
+		UserAgent:  arg.UserAgent,
+		Ip:         arg.Ip,
+		LastUsedAt: at.Time,
+	}
+	return 1, nil
+}
+
+// chirps
+
+func (m *MemStore) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return Chirp{}, foreignKeyViolation("chirps_user_id_fkey")
+	}
+	if _, ok := m.chirps[arg.ID]; ok {
+		return Chirp{}, uniqueViolation("chirps_pkey")
+	}
+	for constraint, ref := range map[string]uuid.NullUUID{
+		"chirps_in_reply_to_fkey": arg.InReplyTo,
+		"chirps_rechirp_of_fkey":  arg.RechirpOf,
+		"chirps_quote_of_fkey":    arg.QuoteOf,
+	} {
+		if _, ok := m.chirps[ref.UUID]; ref.Valid && !ok {
+			return Chirp{}, foreignKeyViolation(constraint)
+		}
+	}
+	if arg.RechirpOf.Valid && arg.QuoteOf.Valid {
+		return Chirp{}, checkViolation("chirps_rechirp_or_quote_check")
+	}
+	if arg.RechirpOf.Valid {
+		for _, c := range m.chirps {
+			if c.UserID == arg.UserID && c.RechirpOf == arg.RechirpOf {
+				return Chirp{}, uniqueViolation("chirps_user_id_rechirp_of_key")
+			}
+		}
+	}
+	chirp := Chirp{
+		ID:             arg.ID,
+		CreatedAt:      arg.CreatedAt.UTC().Truncate(time.Microsecond),
+		UpdatedAt:      arg.UpdatedAt.UTC().Truncate(time.Microsecond),
+		Body:           arg.Body,
+		UserID:         arg.UserID,
+		InReplyTo:      arg.InReplyTo,
+		ConversationID: arg.ConversationID,
+		RechirpOf:      arg.RechirpOf,
+		QuoteOf:        arg.QuoteOf,
+	}
+	m.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+func (m *MemStore) GetChirpsByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.chirps[id]
+	if !ok || c.DeletedAt.Valid {
+		return Chirp{}, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (m *MemStore) GetChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Chirp
+	for _, c := range m.chirps {
+		if c.UserID == userID && !c.DeletedAt.Valid {
+			items = append(items, c)
+		}
+	}
+	sortChirpsAsc(items)
+	return items, nil
+}
+
+func (m *MemStore) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteChirp(id)
+	return nil
+}
+
+// deleteChirp removes a chirp and applies the foreign keys that reference
+// it. Callers must hold m.mu.
+func (m *MemStore) deleteChirp(id uuid.UUID) {
+	delete(m.chirps, id)
+	for cid, c := range m.chirps {
+		switch {
+		case c.RechirpOf.Valid && c.RechirpOf.UUID == id:
+			// rechirp_of is ON DELETE CASCADE
+			m.deleteChirp(cid)
+		case c.InReplyTo.Valid && c.InReplyTo.UUID == id, c.QuoteOf.Valid && c.QuoteOf.UUID == id:
+			// in_reply_to and quote_of are ON DELETE SET NULL
+			if c.InReplyTo.UUID == id {
+				c.InReplyTo = uuid.NullUUID{}
+			}
+			if c.QuoteOf.UUID == id {
+				c.QuoteOf = uuid.NullUUID{}
+			}
+			m.chirps[cid] = c
+		}
+	}
+	for k := range m.likes {
+		if k.chirpID == id {
+			delete(m.likes, k)
+		}
+	}
+	for k := range m.chirpTags {
+		if k.chirpID == id {
+			delete(m.chirpTags, k)
+		}
+	}
+	for nid, n := range m.notifications {
+		if n.ChirpID.Valid && n.ChirpID.UUID == id {
+			delete(m.notifications, nid)
+		}
+	}
+	for mid, a := range m.media {
+		if a.ChirpID.Valid && a.ChirpID.UUID == id {
+			delete(m.media, mid)
+		}
+	}
+	for rid, rev := range m.revisions {
+		if rev.ChirpID == id {
+			delete(m.revisions, rid)
+		}
+	}
+}
+
+func (m *MemStore) EditChirp(ctx context.Context, arg EditChirpParams) (Chirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.chirps[arg.ID]
+	if !ok {
+		return Chirp{}, sql.ErrNoRows
+	}
+	t := now()
+	written := c.CreatedAt
+	if c.EditedAt.Valid {
+		written = c.EditedAt.Time
+	}
+	rev := ChirpRevision{
+		ID:         uuid.New(),
+		ChirpID:    c.ID,
+		Body:       c.Body,
+		CreatedAt:  written,
+		ReplacedAt: t,
+	}
+	m.revisions[rev.ID] = rev
+	c.Body = arg.Body
+	c.EditedAt = sql.NullTime{Time: t, Valid: true}
+	c.UpdatedAt = t
+	m.chirps[c.ID] = c
+	return c, nil
+}
+
+func (m *MemStore) ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]ChirpRevision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []ChirpRevision
+	for _, rev := range m.revisions {
+		if rev.ChirpID == chirpID {
+			items = append(items, rev)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].ReplacedAt.Equal(items[j].ReplacedAt) {
+			return items[i].ReplacedAt.Before(items[j].ReplacedAt)
+		}
+		return bytes.Compare(items[i].ID[:], items[j].ID[:]) < 0
+	})
+	return items, nil
+}
+
+func (m *MemStore) DeleteChirpRevisions(ctx context.Context, chirpID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, rev := range m.revisions {
+		if rev.ChirpID == chirpID {
+			delete(m.revisions, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemStore) ListChirpsAfter(ctx context.Context, arg ListChirpsAfterParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursor := Chirp{CreatedAt: arg.CursorCreatedAt.Time, ID: arg.CursorID.UUID}
+	var items []Chirp
+	for _, c := range m.chirps {
+		if c.DeletedAt.Valid {
+			continue
+		}
+		if arg.AuthorID.Valid && c.UserID != arg.AuthorID.UUID {
+			continue
+		}
+		if arg.InReplyTo.Valid && c.InReplyTo != arg.InReplyTo {
+			continue
+		}
+		if arg.CursorCreatedAt.Valid && !chirpLess(cursor, c) {
+			continue
+		}
+		items = append(items, c)
+	}
+	sortChirpsAsc(items)
+	return limitChirps(items, arg.PageSize), nil
+}
+
+func (m *MemStore) ListChirpsBefore(ctx context.Context, arg ListChirpsBeforeParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursor := Chirp{CreatedAt: arg.CursorCreatedAt.Time, ID: arg.CursorID.UUID}
+	var items []Chirp
+	for _, c := range m.chirps {
+		if c.DeletedAt.Valid {
+			continue
+		}
+		if arg.AuthorID.Valid && c.UserID != arg.AuthorID.UUID {
+			continue
+		}
+		if arg.InReplyTo.Valid && c.InReplyTo != arg.InReplyTo {
+			continue
+		}
+		if arg.CursorCreatedAt.Valid && !chirpLess(c, cursor) {
+			continue
+		}
+		items = append(items, c)
+	}
+	sortChirpsDesc(items)
+	return limitChirps(items, arg.PageSize), nil
+}
+
+func (m *MemStore) ChirpHasReplies(ctx context.Context, inReplyTo uuid.NullUUID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.chirps {
+		if inReplyTo.Valid && c.InReplyTo == inReplyTo {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemStore) TombstoneChirp(ctx context.Context, arg TombstoneChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.chirps[arg.ID]
+	if !ok {
+		return nil
+	}
+	c.Body = ""
+	c.TombstonedAt = arg.TombstonedAt
+	c.UpdatedAt = arg.TombstonedAt.Time
+	m.chirps[arg.ID] = c
+	return nil
+}
+
+func (m *MemStore) SoftDeleteChirp(ctx context.Context, arg SoftDeleteChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, c := range m.chirps {
+		if c.DeletedAt.Valid || (c.ID != arg.ID && c.RechirpOf != (uuid.NullUUID{UUID: arg.ID, Valid: true})) {
+			continue
+		}
+		c.DeletedAt = truncateNullTime(arg.DeletedAt)
+		m.chirps[id] = c
+	}
+	return nil
+}
+
+func (m *MemStore) GetDeletedChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.chirps[id]
+	if !ok || !c.DeletedAt.Valid {
+		return Chirp{}, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (m *MemStore) RestoreChirp(ctx context.Context, arg RestoreChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deletedAt := truncateNullTime(arg.DeletedAt)
+	for id, c := range m.chirps {
+		if c.ID != arg.ID && c.RechirpOf != (uuid.NullUUID{UUID: arg.ID, Valid: true}) {
+			continue
+		}
+		if !deletedAt.Valid || !c.DeletedAt.Valid || !c.DeletedAt.Time.Equal(deletedAt.Time) {
+			continue
+		}
+		c.DeletedAt = sql.NullTime{}
+		m.chirps[id] = c
+	}
+	return nil
+}
+
+func (m *MemStore) ListPurgeableChirps(ctx context.Context, arg ListPurgeableChirpsParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Chirp
+	for _, c := range m.chirps {
+		if c.TombstonedAt.Valid || !c.DeletedAt.Valid || !arg.Before.Valid || !c.DeletedAt.Time.Before(arg.Before.Time) {
+			continue
+		}
+		items = append(items, c)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].DeletedAt.Time.Equal(items[j].DeletedAt.Time) {
+			return items[i].DeletedAt.Time.Before(items[j].DeletedAt.Time)
+		}
+		return bytes.Compare(items[i].ID[:], items[j].ID[:]) < 0
+	})
+	return limitChirps(items, arg.MaxChirps), nil
+}
+
+func (m *MemStore) GetConversation(ctx context.Context, conversationID uuid.UUID) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Chirp
+	for _, c := range m.chirps {
+		if c.ConversationID == conversationID {
+			items = append(items, c)
+		}
+	}
+	sortChirpsAsc(items)
+	return items, nil
+}
+
+func (m *MemStore) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Chirp
+	for _, id := range ids {
+		if c, ok := m.chirps[id]; ok && !c.DeletedAt.Valid {
+			items = append(items, c)
+		}
+	}
+	return items, nil
+}
+
+func (m *MemStore) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.chirps {
+		if c.UserID == arg.UserID && arg.RechirpOf.Valid && c.RechirpOf == arg.RechirpOf {
+			return c, nil
+		}
+	}
+	return Chirp{}, sql.ErrNoRows
+}
+
+func (m *MemStore) GetRechirpStats(ctx context.Context, arg GetRechirpStatsParams) ([]GetRechirpStatsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []GetRechirpStatsRow
+	for _, id := range arg.ChirpIds {
+		if _, ok := m.chirps[id]; !ok {
+			continue
+		}
+		row := GetRechirpStatsRow{ChirpID: id}
+		for _, c := range m.chirps {
+			if c.DeletedAt.Valid {
+				continue
+			}
+			if c.RechirpOf.Valid && c.RechirpOf.UUID == id {
+				row.RechirpCount++
+				if arg.ViewerID.Valid && c.UserID == arg.ViewerID.UUID {
+					row.RechirpedByMe = true
+				}
+			}
+			if c.QuoteOf.Valid && c.QuoteOf.UUID == id {
+				row.QuoteCount++
+			}
+		}
+		items = append(items, row)
+	}
+	return items, nil
+}
+
+// likes
+
+func (m *MemStore) LikeChirp(ctx context.Context, arg LikeChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return foreignKeyViolation("likes_user_id_fkey")
+	}
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return foreignKeyViolation("likes_chirp_id_fkey")
+	}
+	k := likeKey{userID: arg.UserID, chirpID: arg.ChirpID}
+	if _, ok := m.likes[k]; !ok {
+		m.likes[k] = Like{UserID: arg.UserID, ChirpID: arg.ChirpID, CreatedAt: now()}
+	}
+	return nil
+}
+
+func (m *MemStore) UnlikeChirp(ctx context.Context, arg UnlikeChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.likes, likeKey{userID: arg.UserID, chirpID: arg.ChirpID})
+	return nil
+}
+
+func (m *MemStore) GetLikeStats(ctx context.Context, arg GetLikeStatsParams) ([]GetLikeStatsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []GetLikeStatsRow
+	for _, id := range arg.ChirpIds {
+		row := GetLikeStatsRow{ChirpID: id}
+		for k := range m.likes {
+			if k.chirpID != id {
+				continue
+			}
+			row.LikeCount++
+			if arg.ViewerID.Valid && k.userID == arg.ViewerID.UUID {
+				row.LikedByMe = true
+			}
+		}
+		if row.LikeCount > 0 {
+			items = append(items, row)
+		}
+	}
+	return items, nil
+}
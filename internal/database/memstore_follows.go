@@ -0,0 +1,162 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+type followKey struct {
+	followerID uuid.UUID
+	followeeID uuid.UUID
+}
+
+func (m *MemStore) FollowUser(ctx context.Context, arg FollowUserParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if arg.FollowerID == arg.FolloweeID {
+		return checkViolation("follows_check")
+	}
+	if _, ok := m.users[arg.FollowerID]; !ok {
+		return foreignKeyViolation("follows_follower_id_fkey")
+	}
+	if _, ok := m.users[arg.FolloweeID]; !ok {
+		return foreignKeyViolation("follows_followee_id_fkey")
+	}
+	k := followKey{followerID: arg.FollowerID, followeeID: arg.FolloweeID}
+	if _, ok := m.follows[k]; !ok {
+		m.follows[k] = Follow{FollowerID: arg.FollowerID, FolloweeID: arg.FolloweeID, CreatedAt: now()}
+	}
+	return nil
+}
+
+func (m *MemStore) UnfollowUser(ctx context.Context, arg UnfollowUserParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.follows, followKey{followerID: arg.FollowerID, followeeID: arg.FolloweeID})
+	return nil
+}
+
+// listFollows pages through follows matching match, keyed on (created_at,
+// other(f)) the same way the SQL queries are. Callers must hold m.mu.
+func (m *MemStore) listFollows(match func(Follow) bool, other func(Follow) uuid.UUID, cursorCreatedAt sql.NullTime, cursorID uuid.NullUUID, asc bool, n int32) []Follow {
+	less := func(a, b Follow) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		ai, bi := other(a), other(b)
+		return bytes.Compare(ai[:], bi[:]) < 0
+	}
+	var cursor Follow
+	if cursorCreatedAt.Valid {
+		cursor = Follow{CreatedAt: cursorCreatedAt.Time, FollowerID: cursorID.UUID, FolloweeID: cursorID.UUID}
+	}
+
+	var items []Follow
+	for _, f := range m.follows {
+		if !match(f) {
+			continue
+		}
+		if cursorCreatedAt.Valid && ((asc && !less(cursor, f)) || (!asc && !less(f, cursor))) {
+			continue
+		}
+		items = append(items, f)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if asc {
+			return less(items[i], items[j])
+		}
+		return less(items[j], items[i])
+	})
+	if int(n) < len(items) {
+		items = items[:n]
+	}
+	return items
+}
+
+func followerOf(f Follow) uuid.UUID { return f.FollowerID }
+func followeeOf(f Follow) uuid.UUID { return f.FolloweeID }
+
+func (m *MemStore) ListFollowersAfter(ctx context.Context, arg ListFollowersAfterParams) ([]Follow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	match := func(f Follow) bool { return f.FolloweeID == arg.UserID }
+	return m.listFollows(match, followerOf, arg.CursorCreatedAt, arg.CursorID, true, arg.PageSize), nil
+}
+
+func (m *MemStore) ListFollowersBefore(ctx context.Context, arg ListFollowersBeforeParams) ([]Follow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	match := func(f Follow) bool { return f.FolloweeID == arg.UserID }
+	return m.listFollows(match, followerOf, arg.CursorCreatedAt, arg.CursorID, false, arg.PageSize), nil
+}
+
+func (m *MemStore) ListFollowingAfter(ctx context.Context, arg ListFollowingAfterParams) ([]Follow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	match := func(f Follow) bool { return f.FollowerID == arg.UserID }
+	return m.listFollows(match, followeeOf, arg.CursorCreatedAt, arg.CursorID, true, arg.PageSize), nil
+}
+
+func (m *MemStore) ListFollowingBefore(ctx context.Context, arg ListFollowingBeforeParams) ([]Follow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	match := func(f Follow) bool { return f.FollowerID == arg.UserID }
+	return m.listFollows(match, followeeOf, arg.CursorCreatedAt, arg.CursorID, false, arg.PageSize), nil
+}
+
+// timeline returns the live chirps by users that userID follows. Callers
+// must hold m.mu.
+func (m *MemStore) timeline(userID uuid.UUID) []Chirp {
+	var items []Chirp
+	for _, c := range m.chirps {
+		if c.DeletedAt.Valid {
+			continue
+		}
+		if _, ok := m.follows[followKey{followerID: userID, followeeID: c.UserID}]; ok {
+			items = append(items, c)
+		}
+	}
+	return items
+}
+
+func (m *MemStore) ListTimelineAfter(ctx context.Context, arg ListTimelineAfterParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursor := Chirp{CreatedAt: arg.CursorCreatedAt.Time, ID: arg.CursorID.UUID}
+	var items []Chirp
+	for _, c := range m.timeline(arg.UserID) {
+		if arg.CursorCreatedAt.Valid && !chirpLess(cursor, c) {
+			continue
+		}
+		items = append(items, c)
+	}
+	sortChirpsAsc(items)
+	return limitChirps(items, arg.PageSize), nil
+}
+
+func (m *MemStore) ListTimelineBefore(ctx context.Context, arg ListTimelineBeforeParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursor := Chirp{CreatedAt: arg.CursorCreatedAt.Time, ID: arg.CursorID.UUID}
+	var items []Chirp
+	for _, c := range m.timeline(arg.UserID) {
+		if arg.CursorCreatedAt.Valid && !chirpLess(c, cursor) {
+			continue
+		}
+		items = append(items, c)
+	}
+	sortChirpsDesc(items)
+	return limitChirps(items, arg.PageSize), nil
+}
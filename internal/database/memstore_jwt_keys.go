@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+)
+
+func (m *MemStore) ActivateJWTKey(ctx context.Context, arg ActivateJWTKeyParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k, ok := m.jwtKeys[arg.ID]
+	at := truncateNullTime(arg.ActivatedAt)
+	if !ok || (k.ExpiresAt.Valid && !k.ExpiresAt.Time.After(at.Time)) {
+		return 0, nil
+	}
+	k.ActivatedAt = at
+	k.ExpiresAt = sql.NullTime{}
+	m.jwtKeys[arg.ID] = k
+	return 1, nil
+}
+
+func (m *MemStore) CreateJWTKey(ctx context.Context, arg CreateJWTKeyParams) (JwtKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.jwtKeys[arg.ID]; ok {
+		return JwtKey{}, uniqueViolation("jwt_keys_pkey")
+	}
+	k := JwtKey{ID: arg.ID, PrivateKey: arg.PrivateKey, CreatedAt: arg.CreatedAt.UTC().Truncate(time.Microsecond)}
+	m.jwtKeys[arg.ID] = k
+	return k, nil
+}
+
+func (m *MemStore) ExpireJWTKey(ctx context.Context, arg ExpireJWTKeyParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k, ok := m.jwtKeys[arg.ID]
+	at := truncateNullTime(arg.ExpiresAt)
+	if !ok || (k.ExpiresAt.Valid && !k.ExpiresAt.Time.After(at.Time)) {
+		return 0, nil
+	}
+	k.ExpiresAt = at
+	m.jwtKeys[arg.ID] = k
+	return 1, nil
+}
+
+func (m *MemStore) ListJWTKeys(ctx context.Context) ([]JwtKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []JwtKey
+	for _, k := range m.jwtKeys {
+		out = append(out, k)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (m *MemStore) UpdateJWTKeyPrivateKey(ctx context.Context, arg UpdateJWTKeyPrivateKeyParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if k, ok := m.jwtKeys[arg.ID]; ok {
+		k.PrivateKey = arg.PrivateKey
+		m.jwtKeys[arg.ID] = k
+	}
+	return nil
+}
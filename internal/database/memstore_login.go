@@ -0,0 +1,134 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemStore) CreateLoginAttempt(ctx context.Context, arg CreateLoginAttemptParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if arg.UserID.Valid {
+		if _, ok := m.users[arg.UserID.UUID]; !ok {
+			return foreignKeyViolation("login_attempts_user_id_fkey")
+		}
+	}
+	a := LoginAttempt{
+		ID:        uuid.New(),
+		Email:     arg.Email,
+		Ip:        arg.Ip,
+		UserID:    arg.UserID,
+		Succeeded: arg.Succeeded,
+		Reason:    arg.Reason,
+		CreatedAt: now(),
+	}
+	m.loginAttempts[a.ID] = a
+	return nil
+}
+
+func (m *MemStore) DeleteLoginThrottle(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.loginThrottle, key)
+	return nil
+}
+
+func (m *MemStore) DeleteOldLoginAttempts(ctx context.Context, createdAt time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for id, a := range m.loginAttempts {
+		if a.CreatedAt.Before(createdAt) {
+			delete(m.loginAttempts, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemStore) DeleteStaleLoginThrottles(ctx context.Context, lastFailureAt time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for key, t := range m.loginThrottle {
+		if t.LastFailureAt.Before(lastFailureAt) && (!t.LockedUntil.Valid || t.LockedUntil.Time.Before(lastFailureAt)) {
+			delete(m.loginThrottle, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemStore) GetLoginThrottle(ctx context.Context, key string) (LoginThrottle, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.loginThrottle[key]
+	if !ok {
+		return LoginThrottle{}, sql.ErrNoRows
+	}
+	return t, nil
+}
+
+func (m *MemStore) ListLoginAttempts(ctx context.Context, arg ListLoginAttemptsParams) ([]LoginAttempt, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []LoginAttempt
+	for _, a := range m.loginAttempts {
+		if a.Email == arg.Email {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return bytes.Compare(out[i].ID[:], out[j].ID[:]) > 0
+	})
+	if len(out) > int(arg.Limit) {
+		out = out[:arg.Limit]
+	}
+	return out, nil
+}
+
+func (m *MemStore) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	at := arg.Now.UTC().Truncate(time.Microsecond)
+	t, ok := m.loginThrottle[arg.Key]
+	switch {
+	case !ok:
+		t = LoginThrottle{Key: arg.Key, Failures: 1}
+	case t.LastFailureAt.Before(arg.ResetBefore):
+		t.Failures = 1
+	default:
+		t.Failures++
+	}
+	t.LastFailureAt = at
+	m.loginThrottle[arg.Key] = t
+	return t, nil
+}
+
+func (m *MemStore) SetLoginThrottleLock(ctx context.Context, arg SetLoginThrottleLockParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.loginThrottle[arg.Key]
+	if !ok {
+		return nil
+	}
+	t.LockedUntil = truncateNullTime(arg.LockedUntil)
+	m.loginThrottle[arg.Key] = t
+	return nil
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemStore) CreateMediaAttachment(ctx context.Context, arg CreateMediaAttachmentParams) (MediaAttachment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return MediaAttachment{}, foreignKeyViolation("media_attachments_user_id_fkey")
+	}
+	if _, ok := m.media[arg.ID]; ok {
+		return MediaAttachment{}, uniqueViolation("media_attachments_pkey")
+	}
+	a := MediaAttachment{
+		ID:                   arg.ID,
+		UserID:               arg.UserID,
+		ContentType:          arg.ContentType,
+		SizeBytes:            arg.SizeBytes,
+		Width:                arg.Width,
+		Height:               arg.Height,
+		BlobKey:              arg.BlobKey,
+		ThumbnailKey:         arg.ThumbnailKey,
+		ThumbnailContentType: arg.ThumbnailContentType,
+		CreatedAt:            arg.CreatedAt.UTC().Truncate(time.Microsecond),
+	}
+	m.media[a.ID] = a
+	return a, nil
+}
+
+func (m *MemStore) GetMediaAttachment(ctx context.Context, id uuid.UUID) (MediaAttachment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a, ok := m.media[id]
+	if !ok {
+		return MediaAttachment{}, sql.ErrNoRows
+	}
+	return a, nil
+}
+
+func (m *MemStore) GetMediaAttachmentsByIDs(ctx context.Context, ids []uuid.UUID) ([]MediaAttachment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []MediaAttachment
+	for _, a := range m.media {
+		if slices.Contains(ids, a.ID) {
+			items = append(items, a)
+		}
+	}
+	return items, nil
+}
+
+func (m *MemStore) AttachMedia(ctx context.Context, arg AttachMediaParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.media[arg.ID]
+	if !ok || a.UserID != arg.UserID || a.ChirpID.Valid {
+		return 0, nil
+	}
+	if _, ok := m.chirps[arg.ChirpID.UUID]; arg.ChirpID.Valid && !ok {
+		return 0, foreignKeyViolation("media_attachments_chirp_id_fkey")
+	}
+	a.ChirpID = arg.ChirpID
+	a.Position = arg.Position
+	m.media[a.ID] = a
+	return 1, nil
+}
+
+func (m *MemStore) ListChirpMedia(ctx context.Context, chirpIds []uuid.UUID) ([]MediaAttachment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []MediaAttachment
+	for _, a := range m.media {
+		if a.ChirpID.Valid && slices.Contains(chirpIds, a.ChirpID.UUID) {
+			items = append(items, a)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if c := bytes.Compare(items[i].ChirpID.UUID[:], items[j].ChirpID.UUID[:]); c != 0 {
+			return c < 0
+		}
+		return items[i].Position < items[j].Position
+	})
+	return items, nil
+}
+
+func (m *MemStore) DeleteChirpMedia(ctx context.Context, chirpID uuid.NullUUID) ([]MediaAttachment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var items []MediaAttachment
+	for id, a := range m.media {
+		if chirpID.Valid && a.ChirpID == chirpID {
+			items = append(items, a)
+			delete(m.media, id)
+		}
+	}
+	return items, nil
+}
+
+func (m *MemStore) DeleteUnattachedMedia(ctx context.Context, before time.Time) ([]MediaAttachment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var items []MediaAttachment
+	for id, a := range m.media {
+		if !a.ChirpID.Valid && a.CreatedAt.Before(before) {
+			items = append(items, a)
+			delete(m.media, id)
+		}
+	}
+	return items, nil
+}
+
+func (m *MemStore) DeleteAllMedia(ctx context.Context) ([]MediaAttachment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	items := slices.Collect(maps.Values(m.media))
+	clear(m.media)
+	return items, nil
+}
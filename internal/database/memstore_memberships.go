@@ -0,0 +1,155 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var membershipStatuses = []string{"active", "cancelled", "downgraded", "expired"}
+
+func (m *MemStore) CreateMembership(ctx context.Context, arg CreateMembershipParams) (Membership, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return Membership{}, foreignKeyViolation("memberships_user_id_fkey")
+	}
+	if _, ok := m.openMembership(arg.UserID); ok {
+		return Membership{}, uniqueViolation("memberships_user_id_open_key")
+	}
+	t := now()
+	ms := Membership{
+		ID:        uuid.New(),
+		UserID:    arg.UserID,
+		Tier:      arg.Tier,
+		Status:    "active",
+		StartedAt: t,
+		EndsAt:    truncateNullTime(arg.EndsAt),
+		CreatedAt: t,
+		UpdatedAt: t,
+	}
+	m.memberships[ms.ID] = ms
+	return ms, nil
+}
+
+// openMembership returns userID's membership with no ended_at. Callers must
+// hold m.mu.
+func (m *MemStore) openMembership(userID uuid.UUID) (Membership, bool) {
+	for _, ms := range m.memberships {
+		if ms.UserID == userID && !ms.EndedAt.Valid {
+			return ms, true
+		}
+	}
+	return Membership{}, false
+}
+
+func (m *MemStore) GetCurrentMembership(ctx context.Context, userID uuid.UUID) (Membership, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ms, ok := m.openMembership(userID)
+	if !ok {
+		return Membership{}, sql.ErrNoRows
+	}
+	return ms, nil
+}
+
+func (m *MemStore) GetActiveMembership(ctx context.Context, arg GetActiveMembershipParams) (Membership, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ms, ok := m.openMembership(arg.UserID)
+	if !ok || (ms.EndsAt.Valid && !ms.EndsAt.Time.After(arg.Now)) {
+		return Membership{}, sql.ErrNoRows
+	}
+	return ms, nil
+}
+
+func (m *MemStore) UpdateMembershipStatus(ctx context.Context, arg UpdateMembershipStatusParams) (Membership, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ms, ok := m.memberships[arg.ID]
+	if !ok {
+		return Membership{}, sql.ErrNoRows
+	}
+	if !slices.Contains(membershipStatuses, arg.Status) {
+		return Membership{}, checkViolation("memberships_status_check")
+	}
+	if !arg.EndedAt.Valid {
+		if open, ok := m.openMembership(ms.UserID); ok && open.ID != ms.ID {
+			return Membership{}, uniqueViolation("memberships_user_id_open_key")
+		}
+	}
+	ms.Status = arg.Status
+	ms.EndsAt = truncateNullTime(arg.EndsAt)
+	ms.EndedAt = truncateNullTime(arg.EndedAt)
+	ms.UpdatedAt = now()
+	m.memberships[ms.ID] = ms
+	return ms, nil
+}
+
+func (m *MemStore) ExpireLapsedMemberships(ctx context.Context, t time.Time) ([]Membership, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var items []Membership
+	for id, ms := range m.memberships {
+		if ms.EndedAt.Valid || !ms.EndsAt.Valid || ms.EndsAt.Time.After(t) {
+			continue
+		}
+		ms.Status = "expired"
+		ms.EndedAt = ms.EndsAt
+		ms.UpdatedAt = now()
+		m.memberships[id] = ms
+		items = append(items, ms)
+	}
+	return items, nil
+}
+
+func (m *MemStore) CreateMembershipHistory(ctx context.Context, arg CreateMembershipHistoryParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.memberships[arg.MembershipID]; !ok {
+		return foreignKeyViolation("membership_history_membership_id_fkey")
+	}
+	if _, ok := m.users[arg.UserID]; !ok {
+		return foreignKeyViolation("membership_history_user_id_fkey")
+	}
+	h := MembershipHistory{
+		ID:           uuid.New(),
+		MembershipID: arg.MembershipID,
+		UserID:       arg.UserID,
+		Status:       arg.Status,
+		Reason:       arg.Reason,
+		CreatedAt:    now(),
+	}
+	m.memberHistory[h.ID] = h
+	return nil
+}
+
+func (m *MemStore) ListMembershipHistory(ctx context.Context, userID uuid.UUID) ([]MembershipHistory, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []MembershipHistory
+	for _, h := range m.memberHistory {
+		if h.UserID == userID {
+			items = append(items, h)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		}
+		return bytes.Compare(items[i].ID[:], items[j].ID[:]) < 0
+	})
+	return items, nil
+}
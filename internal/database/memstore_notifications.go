@@ -0,0 +1,139 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+var notificationKinds = []string{"mention", "reply", "like", "follow"}
+
+func (m *MemStore) CreateNotification(ctx context.Context, arg CreateNotificationParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !slices.Contains(notificationKinds, arg.Kind) {
+		return checkViolation("notifications_kind_check")
+	}
+	if _, ok := m.users[arg.UserID]; !ok {
+		return foreignKeyViolation("notifications_user_id_fkey")
+	}
+	if _, ok := m.users[arg.ActorID]; !ok {
+		return foreignKeyViolation("notifications_actor_id_fkey")
+	}
+	if _, ok := m.chirps[arg.ChirpID.UUID]; arg.ChirpID.Valid && !ok {
+		return foreignKeyViolation("notifications_chirp_id_fkey")
+	}
+	// ON CONFLICT DO NOTHING against notifications_dedupe_key
+	for _, n := range m.notifications {
+		if n.UserID == arg.UserID && n.ActorID == arg.ActorID && n.Kind == arg.Kind && n.ChirpID.UUID == arg.ChirpID.UUID {
+			return nil
+		}
+	}
+	n := Notification{
+		ID:        uuid.New(),
+		UserID:    arg.UserID,
+		ActorID:   arg.ActorID,
+		Kind:      arg.Kind,
+		ChirpID:   arg.ChirpID,
+		CreatedAt: now(),
+	}
+	m.notifications[n.ID] = n
+	return nil
+}
+
+// notificationLess orders notifications by (created_at, id), the keyset used
+// for paging.
+func notificationLess(a, b Notification) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return bytes.Compare(a.ID[:], b.ID[:]) < 0
+}
+
+// listNotifications pages through userID's notifications the same way the
+// SQL queries do. Callers must hold m.mu.
+func (m *MemStore) listNotifications(userID uuid.UUID, unreadOnly bool, cursorCreatedAt sql.NullTime, cursorID uuid.NullUUID, asc bool, n int32) []Notification {
+	cursor := Notification{CreatedAt: cursorCreatedAt.Time, ID: cursorID.UUID}
+	var items []Notification
+	for _, n := range m.notifications {
+		if n.UserID != userID || (unreadOnly && n.ReadAt.Valid) {
+			continue
+		}
+		if cursorCreatedAt.Valid && ((asc && !notificationLess(cursor, n)) || (!asc && !notificationLess(n, cursor))) {
+			continue
+		}
+		items = append(items, n)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if asc {
+			return notificationLess(items[i], items[j])
+		}
+		return notificationLess(items[j], items[i])
+	})
+	if int(n) < len(items) {
+		items = items[:n]
+	}
+	return items
+}
+
+func (m *MemStore) ListNotificationsAfter(ctx context.Context, arg ListNotificationsAfterParams) ([]Notification, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.listNotifications(arg.UserID, arg.UnreadOnly, arg.CursorCreatedAt, arg.CursorID, true, arg.PageSize), nil
+}
+
+func (m *MemStore) ListNotificationsBefore(ctx context.Context, arg ListNotificationsBeforeParams) ([]Notification, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.listNotifications(arg.UserID, arg.UnreadOnly, arg.CursorCreatedAt, arg.CursorID, false, arg.PageSize), nil
+}
+
+func (m *MemStore) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var count int64
+	for _, n := range m.notifications {
+		if n.UserID == userID && !n.ReadAt.Valid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MemStore) MarkNotificationsRead(ctx context.Context, arg MarkNotificationsReadParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.markRead(func(n Notification) bool {
+		return n.UserID == arg.UserID && slices.Contains(arg.Ids, n.ID)
+	})
+	return nil
+}
+
+func (m *MemStore) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.markRead(func(n Notification) bool { return n.UserID == userID })
+	return nil
+}
+
+// markRead stamps read_at on the unread notifications matching match.
+// Callers must hold m.mu.
+func (m *MemStore) markRead(match func(Notification) bool) {
+	t := now()
+	for id, n := range m.notifications {
+		if match(n) && !n.ReadAt.Valid {
+			n.ReadAt.Time, n.ReadAt.Valid = t, true
+			m.notifications[id] = n
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemStore) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return foreignKeyViolation("password_resets_user_id_fkey")
+	}
+	if _, ok := m.resets[arg.TokenHash]; ok {
+		return uniqueViolation("password_resets_pkey")
+	}
+	m.resets[arg.TokenHash] = PasswordReset{
+		TokenHash: arg.TokenHash,
+		UserID:    arg.UserID,
+		CreatedAt: now(),
+		ExpiresAt: arg.ExpiresAt.UTC().Truncate(time.Microsecond),
+	}
+	return nil
+}
+
+func (m *MemStore) DeleteStalePasswordResets(ctx context.Context, expiresAt time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for hash, pr := range m.resets {
+		if pr.ExpiresAt.Before(expiresAt) {
+			delete(m.resets, hash)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemStore) GetPasswordResetUser(ctx context.Context, arg GetPasswordResetUserParams) (uuid.UUID, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	pr, ok := m.resets[arg.TokenHash]
+	if !ok || pr.UsedAt.Valid || !pr.ExpiresAt.After(arg.ExpiresAt) {
+		return uuid.Nil, sql.ErrNoRows
+	}
+	return pr.UserID, nil
+}
+
+func (m *MemStore) ResetPassword(ctx context.Context, arg ResetPasswordParams) (ResetPasswordRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	at := truncateNullTime(arg.UsedAt)
+	pr, ok := m.resets[arg.TokenHash]
+	if !ok || pr.UsedAt.Valid || !pr.ExpiresAt.After(at.Time) {
+		return ResetPasswordRow{}, sql.ErrNoRows
+	}
+	user, ok := m.users[pr.UserID]
+	if !ok {
+		return ResetPasswordRow{}, sql.ErrNoRows
+	}
+
+	for hash, other := range m.resets {
+		if other.UserID == pr.UserID && !other.UsedAt.Valid {
+			other.UsedAt = at
+			m.resets[hash] = other
+		}
+	}
+	for token, rt := range m.refreshTokens {
+		if rt.UserID == pr.UserID && !rt.RevokedAt.Valid {
+			rt.RevokedAt = at
+			rt.UpdatedAt = at.Time
+			m.refreshTokens[token] = rt
+		}
+	}
+	user.HashedPassword = arg.HashedPassword
+	user.TokenVersion++
+	user.UpdatedAt = now()
+	m.users[user.ID] = user
+	return ResetPasswordRow{ID: user.ID, Email: user.Email}, nil
+}
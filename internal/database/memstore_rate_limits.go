@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+)
+
+func (m *MemStore) DeleteStaleRateLimitBuckets(ctx context.Context, updatedAt time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for key, b := range m.rateLimits {
+		if b.UpdatedAt.Before(updatedAt) {
+			delete(m.rateLimits, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemStore) GetRateLimitBucket(ctx context.Context, key string) (RateLimitBucket, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, ok := m.rateLimits[key]
+	if !ok {
+		return RateLimitBucket{}, sql.ErrNoRows
+	}
+	return b, nil
+}
+
+func (m *MemStore) TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := arg.Now.UTC().Truncate(time.Microsecond)
+	b, ok := m.rateLimits[arg.Key]
+	if !ok {
+		b = RateLimitBucket{Key: arg.Key, Tokens: arg.Burst - 1, UpdatedAt: now}
+		m.rateLimits[arg.Key] = b
+		return b.Tokens, nil
+	}
+
+	// ON CONFLICT DO UPDATE ... WHERE the refilled level is at least 1
+	elapsed := math.Max(0, now.Sub(b.UpdatedAt).Seconds())
+	tokens := math.Min(arg.Burst, b.Tokens+elapsed*arg.Rate)
+	if tokens < 1 {
+		return 0, sql.ErrNoRows
+	}
+	b.Tokens = tokens - 1
+	if now.After(b.UpdatedAt) {
+		b.UpdatedAt = now
+	}
+	m.rateLimits[arg.Key] = b
+	return b.Tokens, nil
+}
@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// The in-memory search understands the subset of to_tsquery syntax the
+// handlers generate: terms joined by " & ", where a term is a word, a prefix
+// "word:*" or a phrase "(a <-> b)". There is no stemming or stop-word removal,
+// and the rank is a simple match density rather than ts_rank.
+
+type memTerm struct {
+	words  []string
+	prefix bool
+}
+
+func parseMemQuery(query string) []memTerm {
+	var terms []memTerm
+	for _, part := range strings.Split(query, " & ") {
+		part = strings.Trim(strings.TrimSpace(part), "()")
+		if part == "" {
+			continue
+		}
+		var t memTerm
+		if strings.HasSuffix(part, ":*") {
+			t.prefix = true
+			part = strings.TrimSuffix(part, ":*")
+		}
+		t.words = strings.Split(part, " <-> ")
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+func tokenize(body string) []string {
+	return strings.FieldsFunc(strings.ToLower(body), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// countMatches returns how often t occurs in tokens.
+func (t memTerm) countMatches(tokens []string) int {
+	n := 0
+	for i := 0; i+len(t.words) <= len(tokens); i++ {
+		ok := true
+		for j, w := range t.words {
+			last := j == len(t.words)-1
+			if tokens[i+j] != w && !(last && t.prefix && strings.HasPrefix(tokens[i+j], w)) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// memSearchRank returns the rank of body for query and whether it matches.
+func memSearchRank(body, query string) (float32, bool) {
+	terms := parseMemQuery(query)
+	if len(terms) == 0 {
+		return 0, false
+	}
+	tokens := tokenize(body)
+	hits := 0
+	for _, t := range terms {
+		n := t.countMatches(tokens)
+		if n == 0 {
+			return 0, false
+		}
+		hits += n
+	}
+	return float32(hits) / float32(len(tokens)), true
+}
+
+type memSearchHit struct {
+	chirp Chirp
+	rank  float32
+}
+
+func rankedLess(a, b memSearchHit) bool {
+	if a.rank != b.rank {
+		return a.rank < b.rank
+	}
+	return chirpLess(a.chirp, b.chirp)
+}
+
+// searchChirps returns the matching chirps by the given author, if any.
+// Callers must hold m.mu.
+func (m *MemStore) searchChirps(query string, authorID uuid.NullUUID) []memSearchHit {
+	var hits []memSearchHit
+	for _, c := range m.chirps {
+		if c.DeletedAt.Valid || (authorID.Valid && c.UserID != authorID.UUID) {
+			continue
+		}
+		if rank, ok := memSearchRank(c.Body, query); ok {
+			hits = append(hits, memSearchHit{chirp: c, rank: rank})
+		}
+	}
+	return hits
+}
+
+func (m *MemStore) SearchChirpsAfter(ctx context.Context, arg SearchChirpsAfterParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursor := Chirp{CreatedAt: arg.CursorCreatedAt.Time, ID: arg.CursorID.UUID}
+	var items []Chirp
+	for _, h := range m.searchChirps(arg.Query, arg.AuthorID) {
+		if arg.CursorCreatedAt.Valid && !chirpLess(cursor, h.chirp) {
+			continue
+		}
+		items = append(items, h.chirp)
+	}
+	sortChirpsAsc(items)
+	return limitChirps(items, arg.PageSize), nil
+}
+
+func (m *MemStore) SearchChirpsBefore(ctx context.Context, arg SearchChirpsBeforeParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursor := Chirp{CreatedAt: arg.CursorCreatedAt.Time, ID: arg.CursorID.UUID}
+	var items []Chirp
+	for _, h := range m.searchChirps(arg.Query, arg.AuthorID) {
+		if arg.CursorCreatedAt.Valid && !chirpLess(h.chirp, cursor) {
+			continue
+		}
+		items = append(items, h.chirp)
+	}
+	sortChirpsDesc(items)
+	return limitChirps(items, arg.PageSize), nil
+}
+
+func (m *MemStore) SearchChirpsByRankAfter(ctx context.Context, arg SearchChirpsByRankAfterParams) ([]SearchChirpsByRankAfterRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursor := memSearchHit{
+		chirp: Chirp{CreatedAt: arg.CursorCreatedAt.Time, ID: arg.CursorID.UUID},
+		rank:  float32(arg.CursorRank.Float64),
+	}
+	var hits []memSearchHit
+	for _, h := range m.searchChirps(arg.Query, arg.AuthorID) {
+		if arg.CursorRank.Valid && !rankedLess(cursor, h) {
+			continue
+		}
+		hits = append(hits, h)
+	}
+	sort.Slice(hits, func(i, j int) bool { return rankedLess(hits[i], hits[j]) })
+
+	items := make([]SearchChirpsByRankAfterRow, 0, len(hits))
+	for _, h := range hits {
+		if len(items) == int(arg.PageSize) {
+			break
+		}
+		items = append(items, SearchChirpsByRankAfterRow{Chirp: h.chirp, Rank: h.rank})
+	}
+	return items, nil
+}
+
+func (m *MemStore) SearchChirpsByRankBefore(ctx context.Context, arg SearchChirpsByRankBeforeParams) ([]SearchChirpsByRankBeforeRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursor := memSearchHit{
+		chirp: Chirp{CreatedAt: arg.CursorCreatedAt.Time, ID: arg.CursorID.UUID},
+		rank:  float32(arg.CursorRank.Float64),
+	}
+	var hits []memSearchHit
+	for _, h := range m.searchChirps(arg.Query, arg.AuthorID) {
+		if arg.CursorRank.Valid && !rankedLess(h, cursor) {
+			continue
+		}
+		hits = append(hits, h)
+	}
+	sort.Slice(hits, func(i, j int) bool { return rankedLess(hits[j], hits[i]) })
+
+	items := make([]SearchChirpsByRankBeforeRow, 0, len(hits))
+	for _, h := range hits {
+		if len(items) == int(arg.PageSize) {
+			break
+		}
+		items = append(items, SearchChirpsByRankBeforeRow{Chirp: h.chirp, Rank: h.rank})
+	}
+	return items, nil
+}
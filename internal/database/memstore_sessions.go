@@ -0,0 +1,84 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemStore) GetUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return u.TokenVersion, nil
+}
+
+func (m *MemStore) IncrementUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	u.TokenVersion++
+	u.UpdatedAt = now()
+	m.users[id] = u
+	return u.TokenVersion, nil
+}
+
+func (m *MemStore) ListUserSessions(ctx context.Context, arg ListUserSessionsParams) ([]ListUserSessionsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	started := make(map[uuid.UUID]RefreshToken)
+	for _, rt := range m.refreshTokens {
+		if first, ok := started[rt.FamilyID]; !ok || rt.CreatedAt.Before(first.CreatedAt) {
+			started[rt.FamilyID] = rt
+		}
+	}
+
+	var out []ListUserSessionsRow
+	for _, rt := range m.refreshTokens {
+		if rt.UserID != arg.UserID || rt.RotatedAt.Valid || rt.RevokedAt.Valid || !rt.ExpiresAt.After(arg.Now) {
+			continue
+		}
+		out = append(out, ListUserSessionsRow{
+			FamilyID:   rt.FamilyID,
+			UserAgent:  rt.UserAgent,
+			Ip:         rt.Ip,
+			StartedAt:  started[rt.FamilyID].CreatedAt,
+			LastUsedAt: rt.LastUsedAt,
+			ExpiresAt:  rt.ExpiresAt,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].LastUsedAt.Equal(out[j].LastUsedAt) {
+			return out[i].LastUsedAt.After(out[j].LastUsedAt)
+		}
+		return bytes.Compare(out[i].FamilyID[:], out[j].FamilyID[:]) < 0
+	})
+	return out, nil
+}
+
+func (m *MemStore) RevokeUserRefreshTokens(ctx context.Context, arg RevokeUserRefreshTokensParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	at := truncateNullTime(arg.RevokedAt)
+	for token, rt := range m.refreshTokens {
+		if rt.UserID == arg.UserID && !rt.RevokedAt.Valid {
+			rt.RevokedAt = at
+			rt.UpdatedAt = at.Time
+			m.refreshTokens[token] = rt
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+type chirpTagKey struct {
+	chirpID uuid.UUID
+	tagID   uuid.UUID
+}
+
+func (m *MemStore) UpsertTag(ctx context.Context, name string) (Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.tags {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	t := Tag{ID: uuid.New(), Name: name, CreatedAt: now()}
+	m.tags[t.ID] = t
+	return t, nil
+}
+
+func (m *MemStore) AddChirpTag(ctx context.Context, arg AddChirpTagParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return foreignKeyViolation("chirp_tags_chirp_id_fkey")
+	}
+	if _, ok := m.tags[arg.TagID]; !ok {
+		return foreignKeyViolation("chirp_tags_tag_id_fkey")
+	}
+	k := chirpTagKey{chirpID: arg.ChirpID, tagID: arg.TagID}
+	if _, ok := m.chirpTags[k]; !ok {
+		m.chirpTags[k] = ChirpTag{ChirpID: arg.ChirpID, TagID: arg.TagID, CreatedAt: arg.CreatedAt}
+	}
+	return nil
+}
+
+func (m *MemStore) DeleteChirpTags(ctx context.Context, chirpID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k := range m.chirpTags {
+		if k.chirpID == chirpID {
+			delete(m.chirpTags, k)
+		}
+	}
+	return nil
+}
+
+// taggedChirps returns the live chirps tagged with name. Callers must hold
+// m.mu.
+func (m *MemStore) taggedChirps(name string) []Chirp {
+	var items []Chirp
+	for k := range m.chirpTags {
+		if m.tags[k.tagID].Name != name {
+			continue
+		}
+		if c := m.chirps[k.chirpID]; !c.DeletedAt.Valid {
+			items = append(items, c)
+		}
+	}
+	return items
+}
+
+func (m *MemStore) ListTagChirpsAfter(ctx context.Context, arg ListTagChirpsAfterParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursor := Chirp{CreatedAt: arg.CursorCreatedAt.Time, ID: arg.CursorID.UUID}
+	var items []Chirp
+	for _, c := range m.taggedChirps(arg.Tag) {
+		if arg.CursorCreatedAt.Valid && !chirpLess(cursor, c) {
+			continue
+		}
+		items = append(items, c)
+	}
+	sortChirpsAsc(items)
+	return limitChirps(items, arg.PageSize), nil
+}
+
+func (m *MemStore) ListTagChirpsBefore(ctx context.Context, arg ListTagChirpsBeforeParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursor := Chirp{CreatedAt: arg.CursorCreatedAt.Time, ID: arg.CursorID.UUID}
+	var items []Chirp
+	for _, c := range m.taggedChirps(arg.Tag) {
+		if arg.CursorCreatedAt.Valid && !chirpLess(c, cursor) {
+			continue
+		}
+		items = append(items, c)
+	}
+	sortChirpsDesc(items)
+	return limitChirps(items, arg.PageSize), nil
+}
+
+func (m *MemStore) GetTrendingTags(ctx context.Context, arg GetTrendingTagsParams) ([]GetTrendingTagsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int64)
+	for _, ct := range m.chirpTags {
+		if !ct.CreatedAt.Before(arg.Since) && !m.chirps[ct.ChirpID].DeletedAt.Valid {
+			counts[m.tags[ct.TagID].Name]++
+		}
+	}
+	items := make([]GetTrendingTagsRow, 0, len(counts))
+	for name, n := range counts {
+		items = append(items, GetTrendingTagsRow{Name: name, ChirpCount: n})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].ChirpCount != items[j].ChirpCount {
+			return items[i].ChirpCount > items[j].ChirpCount
+		}
+		return items[i].Name < items[j].Name
+	})
+	if int(arg.MaxTags) < len(items) {
+		items = items[:arg.MaxTags]
+	}
+	return items, nil
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestMemStore_UniqueEmail(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemStore()
+
+	if _, err := m.CreateUser(ctx, CreateUserParams{Email: "a@example.com", HashedPassword: "x"}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	_, err := m.CreateUser(ctx, CreateUserParams{Email: "a@example.com", HashedPassword: "y"})
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		t.Fatalf("expected unique violation, got %v", err)
+	}
+}
+
+func TestMemStore_DeleteAllUsersCascades(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemStore()
+
+	user, err := m.CreateUser(ctx, CreateUserParams{Email: "a@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	now := time.Now().UTC()
+	if _, err := m.CreateChirp(ctx, CreateChirpParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Body: "hi", UserID: user.ID}); err != nil {
+		t.Fatalf("create chirp: %v", err)
+	}
+	if err := m.InsertRefreshToken(ctx, InsertRefreshTokenParams{Token: "t", UserID: user.ID, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("insert refresh token: %v", err)
+	}
+
+	if err := m.DeleteAllUsers(ctx); err != nil {
+		t.Fatalf("delete all users: %v", err)
+	}
+	chirps, _ := m.ListChirpsAfter(ctx, ListChirpsAfterParams{PageSize: 10})
+	if len(chirps) != 0 {
+		t.Errorf("expected chirps to cascade, got %d", len(chirps))
+	}
+	if _, err := m.GetUserFromRefreshToken(ctx, "t"); err == nil {
+		t.Error("expected refresh token to cascade")
+	}
+}
+
+func TestMemStore_ChirpRequiresUser(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemStore()
+
+	now := time.Now().UTC()
+	_, err := m.CreateChirp(ctx, CreateChirpParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Body: "hi", UserID: uuid.New()})
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23503" {
+		t.Fatalf("expected foreign key violation, got %v", err)
+	}
+}
+
+func TestMemStore_RotateRefreshTokenIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemStore()
+
+	user, err := m.CreateUser(ctx, CreateUserParams{Email: "a@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	now := time.Now().UTC()
+	for _, token := range []string{"old", "taken"} {
+		if err := m.InsertRefreshToken(ctx, InsertRefreshTokenParams{Token: token, UserID: user.ID, ExpiresAt: now.Add(time.Hour)}); err != nil {
+			t.Fatalf("insert refresh token: %v", err)
+		}
+	}
+	rotate := func(newToken string) (int64, error) {
+		return m.RotateRefreshToken(ctx, RotateRefreshTokenParams{
+			RotatedAt: sql.NullTime{Time: now, Valid: true},
+			NewToken:  newToken,
+			Token:     "old",
+			ExpiresAt: now.Add(time.Hour),
+		})
+	}
+
+	// a replacement that cannot be stored leaves the old token usable
+	if _, err := rotate("taken"); err == nil {
+		t.Fatal("expected a unique violation")
+	}
+	if rt, err := m.GetUserFromRefreshToken(ctx, "old"); err != nil || rt.RotatedAt.Valid {
+		t.Fatalf("old token after failed rotation: %+v, %v", rt, err)
+	}
+
+	if n, err := rotate("new"); err != nil || n != 1 {
+		t.Fatalf("rotate: %d, %v", n, err)
+	}
+	if rt, err := m.GetUserFromRefreshToken(ctx, "new"); err != nil || rt.UserID != user.ID {
+		t.Fatalf("new token: %+v, %v", rt, err)
+	}
+	if n, _ := rotate("newer"); n != 0 {
+		t.Fatal("expected a rotated token not to rotate again")
+	}
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemStore) CreateWebhookEvent(ctx context.Context, arg CreateWebhookEventParams) (WebhookEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// ON CONFLICT (event_id) DO NOTHING
+	for _, e := range m.webhookEvents {
+		if e.EventID == arg.EventID {
+			return WebhookEvent{}, sql.ErrNoRows
+		}
+	}
+	e := WebhookEvent{
+		ID:         uuid.New(),
+		EventID:    arg.EventID,
+		Event:      arg.Event,
+		Payload:    append(json.RawMessage(nil), arg.Payload...),
+		ReceivedAt: now(),
+	}
+	e.ProcessingStartedAt = sql.NullTime{Time: e.ReceivedAt, Valid: true}
+	m.webhookEvents[e.ID] = e
+	return e, nil
+}
+
+func (m *MemStore) ClaimWebhookEvent(ctx context.Context, arg ClaimWebhookEventParams) (WebhookEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, e := range m.webhookEvents {
+		if e.EventID != arg.EventID {
+			continue
+		}
+		if e.ProcessedAt.Valid || (e.ProcessingStartedAt.Valid && !e.ProcessingStartedAt.Time.Before(arg.StaleBefore.Time)) {
+			return WebhookEvent{}, sql.ErrNoRows
+		}
+		e.ProcessingStartedAt = truncateNullTime(arg.ProcessingStartedAt)
+		m.webhookEvents[id] = e
+		return e, nil
+	}
+	return WebhookEvent{}, sql.ErrNoRows
+}
+
+func (m *MemStore) ClaimWebhookEventForReplay(ctx context.Context, arg ClaimWebhookEventForReplayParams) (WebhookEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.webhookEvents[arg.ID]
+	if !ok || (e.ProcessingStartedAt.Valid && !e.ProcessingStartedAt.Time.Before(arg.StaleBefore.Time)) {
+		return WebhookEvent{}, sql.ErrNoRows
+	}
+	e.ProcessingStartedAt = truncateNullTime(arg.ProcessingStartedAt)
+	m.webhookEvents[arg.ID] = e
+	return e, nil
+}
+
+func (m *MemStore) GetWebhookEvent(ctx context.Context, id uuid.UUID) (WebhookEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.webhookEvents[id]
+	if !ok {
+		return WebhookEvent{}, sql.ErrNoRows
+	}
+	return e, nil
+}
+
+func (m *MemStore) GetWebhookEventByEventID(ctx context.Context, eventID string) (WebhookEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, e := range m.webhookEvents {
+		if e.EventID == eventID {
+			return e, nil
+		}
+	}
+	return WebhookEvent{}, sql.ErrNoRows
+}
+
+// webhookEventLess orders events by (received_at, id), the keyset used for
+// paging.
+func webhookEventLess(a, b WebhookEvent) bool {
+	if !a.ReceivedAt.Equal(b.ReceivedAt) {
+		return a.ReceivedAt.Before(b.ReceivedAt)
+	}
+	return bytes.Compare(a.ID[:], b.ID[:]) < 0
+}
+
+// listWebhookEvents pages through stored events the same way the SQL
+// queries do. Callers must hold m.mu.
+func (m *MemStore) listWebhookEvents(pendingOnly bool, cursorReceivedAt sql.NullTime, cursorID uuid.NullUUID, asc bool, n int32) []WebhookEvent {
+	cursor := WebhookEvent{ReceivedAt: cursorReceivedAt.Time, ID: cursorID.UUID}
+	var items []WebhookEvent
+	for _, e := range m.webhookEvents {
+		if pendingOnly && e.ProcessedAt.Valid {
+			continue
+		}
+		if cursorReceivedAt.Valid && ((asc && !webhookEventLess(cursor, e)) || (!asc && !webhookEventLess(e, cursor))) {
+			continue
+		}
+		items = append(items, e)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if asc {
+			return webhookEventLess(items[i], items[j])
+		}
+		return webhookEventLess(items[j], items[i])
+	})
+	if int(n) < len(items) {
+		items = items[:n]
+	}
+	return items
+}
+
+func (m *MemStore) ListWebhookEventsAfter(ctx context.Context, arg ListWebhookEventsAfterParams) ([]WebhookEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.listWebhookEvents(arg.PendingOnly, arg.CursorReceivedAt, arg.CursorID, true, arg.PageSize), nil
+}
+
+func (m *MemStore) ListWebhookEventsBefore(ctx context.Context, arg ListWebhookEventsBeforeParams) ([]WebhookEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.listWebhookEvents(arg.PendingOnly, arg.CursorReceivedAt, arg.CursorID, false, arg.PageSize), nil
+}
+
+func (m *MemStore) MarkWebhookEventProcessed(ctx context.Context, arg MarkWebhookEventProcessedParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.webhookEvents[arg.ID]
+	if !ok {
+		return nil
+	}
+	e.ProcessedAt = truncateNullTime(arg.ProcessedAt)
+	e.LastError = sql.NullString{}
+	e.ProcessingStartedAt = sql.NullTime{}
+	m.webhookEvents[arg.ID] = e
+	return nil
+}
+
+func (m *MemStore) MarkWebhookEventFailed(ctx context.Context, arg MarkWebhookEventFailedParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.webhookEvents[arg.ID]
+	if !ok {
+		return nil
+	}
+	e.LastError = arg.LastError
+	e.ProcessingStartedAt = sql.NullTime{}
+	m.webhookEvents[arg.ID] = e
+	return nil
+}
@@ -6,26 +6,158 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Chirp struct {
-	ID        uuid.UUID
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	SearchVector   interface{}
+	InReplyTo      uuid.NullUUID
+	ConversationID uuid.UUID
+	TombstonedAt   sql.NullTime
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	EditedAt       sql.NullTime
+	DeletedAt      sql.NullTime
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+type ChirpTag struct {
+	ChirpID   uuid.UUID
+	TagID     uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type JwtKey struct {
+	ID          string
+	PrivateKey  string
+	CreatedAt   time.Time
+	ActivatedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+type Like struct {
 	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
 }
 
-type RefreshToken struct {
-	Token     string
+type LoginAttempt struct {
+	ID        uuid.UUID
+	Email     string
+	Ip        string
+	UserID    uuid.NullUUID
+	Succeeded bool
+	Reason    sql.NullString
+	CreatedAt time.Time
+}
+
+type LoginThrottle struct {
+	Key           string
+	Failures      int32
+	LastFailureAt time.Time
+	LockedUntil   sql.NullTime
+}
+
+type MediaAttachment struct {
+	ID                   uuid.UUID
+	UserID               uuid.UUID
+	ChirpID              uuid.NullUUID
+	Position             int32
+	ContentType          string
+	SizeBytes            int64
+	Width                int32
+	Height               int32
+	BlobKey              string
+	ThumbnailKey         string
+	ThumbnailContentType string
+	CreatedAt            time.Time
+}
+
+type Membership struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Tier      string
+	Status    string
+	StartedAt time.Time
+	EndsAt    sql.NullTime
+	EndedAt   sql.NullTime
 	CreatedAt time.Time
 	UpdatedAt time.Time
+}
+
+type MembershipHistory struct {
+	ID           uuid.UUID
+	MembershipID uuid.UUID
+	UserID       uuid.UUID
+	Status       string
+	Reason       string
+	CreatedAt    time.Time
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	Kind      string
+	ChirpID   uuid.NullUUID
+	CreatedAt time.Time
+	ReadAt    sql.NullTime
+}
+
+type PasswordReset struct {
+	TokenHash string
 	UserID    uuid.UUID
+	CreatedAt time.Time
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+type RefreshToken struct {
+	Token      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	FamilyID   uuid.UUID
+	RotatedAt  sql.NullTime
+	ReplacedBy sql.NullString
+	UserAgent  string
+	Ip         string
+	LastUsedAt time.Time
+}
+
+type Tag struct {
+	ID        uuid.UUID
+	Name      string
+	CreatedAt time.Time
 }
 
 type User struct {
@@ -34,5 +166,18 @@ type User struct {
 	UpdatedAt      time.Time
 	Email          string
 	HashedPassword string
-	IsChirpyRed    bool
+	Username       sql.NullString
+	TokenVersion   int32
+	VerifiedAt     sql.NullTime
+}
+
+type WebhookEvent struct {
+	ID                  uuid.UUID
+	EventID             string
+	Event               string
+	Payload             json.RawMessage
+	ReceivedAt          time.Time
+	ProcessedAt         sql.NullTime
+	LastError           sql.NullString
+	ProcessingStartedAt sql.NullTime
 }
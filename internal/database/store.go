@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store is the set of queries the HTTP handlers depend on. *Queries satisfies
+// it against Postgres and MemStore satisfies it in memory for tests.
+type Store interface {
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DeleteAllUsers(ctx context.Context) error
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
+	GetUsersByUsernames(ctx context.Context, usernames []string) ([]User, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (int64, error)
+
+	InsertRefreshToken(ctx context.Context, arg InsertRefreshTokenParams) error
+	GetUserFromRefreshToken(ctx context.Context, token string) (GetUserFromRefreshTokenRow, error)
+	RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (int64, error)
+	RevokeRefreshTokenFamily(ctx context.Context, arg RevokeRefreshTokenFamilyParams) (int64, error)
+	RevokeUserRefreshTokens(ctx context.Context, arg RevokeUserRefreshTokensParams) error
+	ListUserSessions(ctx context.Context, arg ListUserSessionsParams) ([]ListUserSessionsRow, error)
+	GetUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error)
+	IncrementUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error)
+
+	CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error)
+	GetChirpsByID(ctx context.Context, id uuid.UUID) (Chirp, error)
+	GetChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]Chirp, error)
+	DeleteChirpByID(ctx context.Context, id uuid.UUID) error
+	EditChirp(ctx context.Context, arg EditChirpParams) (Chirp, error)
+	ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]ChirpRevision, error)
+	DeleteChirpRevisions(ctx context.Context, chirpID uuid.UUID) error
+	ListChirpsAfter(ctx context.Context, arg ListChirpsAfterParams) ([]Chirp, error)
+	ListChirpsBefore(ctx context.Context, arg ListChirpsBeforeParams) ([]Chirp, error)
+	SearchChirpsAfter(ctx context.Context, arg SearchChirpsAfterParams) ([]Chirp, error)
+	SearchChirpsBefore(ctx context.Context, arg SearchChirpsBeforeParams) ([]Chirp, error)
+	SearchChirpsByRankAfter(ctx context.Context, arg SearchChirpsByRankAfterParams) ([]SearchChirpsByRankAfterRow, error)
+	SearchChirpsByRankBefore(ctx context.Context, arg SearchChirpsByRankBeforeParams) ([]SearchChirpsByRankBeforeRow, error)
+	ChirpHasReplies(ctx context.Context, inReplyTo uuid.NullUUID) (bool, error)
+	TombstoneChirp(ctx context.Context, arg TombstoneChirpParams) error
+	SoftDeleteChirp(ctx context.Context, arg SoftDeleteChirpParams) error
+	GetDeletedChirp(ctx context.Context, id uuid.UUID) (Chirp, error)
+	RestoreChirp(ctx context.Context, arg RestoreChirpParams) error
+	ListPurgeableChirps(ctx context.Context, arg ListPurgeableChirpsParams) ([]Chirp, error)
+	GetConversation(ctx context.Context, conversationID uuid.UUID) ([]Chirp, error)
+	GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error)
+	GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error)
+	GetRechirpStats(ctx context.Context, arg GetRechirpStatsParams) ([]GetRechirpStatsRow, error)
+
+	LikeChirp(ctx context.Context, arg LikeChirpParams) error
+	UnlikeChirp(ctx context.Context, arg UnlikeChirpParams) error
+	GetLikeStats(ctx context.Context, arg GetLikeStatsParams) ([]GetLikeStatsRow, error)
+
+	FollowUser(ctx context.Context, arg FollowUserParams) error
+	UnfollowUser(ctx context.Context, arg UnfollowUserParams) error
+	ListFollowersAfter(ctx context.Context, arg ListFollowersAfterParams) ([]Follow, error)
+	ListFollowersBefore(ctx context.Context, arg ListFollowersBeforeParams) ([]Follow, error)
+	ListFollowingAfter(ctx context.Context, arg ListFollowingAfterParams) ([]Follow, error)
+	ListFollowingBefore(ctx context.Context, arg ListFollowingBeforeParams) ([]Follow, error)
+	ListTimelineAfter(ctx context.Context, arg ListTimelineAfterParams) ([]Chirp, error)
+	ListTimelineBefore(ctx context.Context, arg ListTimelineBeforeParams) ([]Chirp, error)
+
+	UpsertTag(ctx context.Context, name string) (Tag, error)
+	AddChirpTag(ctx context.Context, arg AddChirpTagParams) error
+	DeleteChirpTags(ctx context.Context, chirpID uuid.UUID) error
+	ListTagChirpsAfter(ctx context.Context, arg ListTagChirpsAfterParams) ([]Chirp, error)
+	ListTagChirpsBefore(ctx context.Context, arg ListTagChirpsBeforeParams) ([]Chirp, error)
+	GetTrendingTags(ctx context.Context, arg GetTrendingTagsParams) ([]GetTrendingTagsRow, error)
+
+	CreateMediaAttachment(ctx context.Context, arg CreateMediaAttachmentParams) (MediaAttachment, error)
+	GetMediaAttachment(ctx context.Context, id uuid.UUID) (MediaAttachment, error)
+	GetMediaAttachmentsByIDs(ctx context.Context, ids []uuid.UUID) ([]MediaAttachment, error)
+	AttachMedia(ctx context.Context, arg AttachMediaParams) (int64, error)
+	ListChirpMedia(ctx context.Context, chirpIds []uuid.UUID) ([]MediaAttachment, error)
+	DeleteChirpMedia(ctx context.Context, chirpID uuid.NullUUID) ([]MediaAttachment, error)
+	DeleteUnattachedMedia(ctx context.Context, before time.Time) ([]MediaAttachment, error)
+	DeleteAllMedia(ctx context.Context) ([]MediaAttachment, error)
+
+	CreateNotification(ctx context.Context, arg CreateNotificationParams) error
+	ListNotificationsAfter(ctx context.Context, arg ListNotificationsAfterParams) ([]Notification, error)
+	ListNotificationsBefore(ctx context.Context, arg ListNotificationsBeforeParams) ([]Notification, error)
+	CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error)
+	MarkNotificationsRead(ctx context.Context, arg MarkNotificationsReadParams) error
+	MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) error
+
+	CreateWebhookEvent(ctx context.Context, arg CreateWebhookEventParams) (WebhookEvent, error)
+	ClaimWebhookEvent(ctx context.Context, arg ClaimWebhookEventParams) (WebhookEvent, error)
+	ClaimWebhookEventForReplay(ctx context.Context, arg ClaimWebhookEventForReplayParams) (WebhookEvent, error)
+	GetWebhookEvent(ctx context.Context, id uuid.UUID) (WebhookEvent, error)
+	GetWebhookEventByEventID(ctx context.Context, eventID string) (WebhookEvent, error)
+	ListWebhookEventsAfter(ctx context.Context, arg ListWebhookEventsAfterParams) ([]WebhookEvent, error)
+	ListWebhookEventsBefore(ctx context.Context, arg ListWebhookEventsBeforeParams) ([]WebhookEvent, error)
+	MarkWebhookEventProcessed(ctx context.Context, arg MarkWebhookEventProcessedParams) error
+	MarkWebhookEventFailed(ctx context.Context, arg MarkWebhookEventFailedParams) error
+
+	CreateMembership(ctx context.Context, arg CreateMembershipParams) (Membership, error)
+	GetCurrentMembership(ctx context.Context, userID uuid.UUID) (Membership, error)
+	GetActiveMembership(ctx context.Context, arg GetActiveMembershipParams) (Membership, error)
+	UpdateMembershipStatus(ctx context.Context, arg UpdateMembershipStatusParams) (Membership, error)
+	ExpireLapsedMemberships(ctx context.Context, now time.Time) ([]Membership, error)
+	CreateMembershipHistory(ctx context.Context, arg CreateMembershipHistoryParams) error
+	ListMembershipHistory(ctx context.Context, userID uuid.UUID) ([]MembershipHistory, error)
+
+	TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (float64, error)
+	GetRateLimitBucket(ctx context.Context, key string) (RateLimitBucket, error)
+	DeleteStaleRateLimitBuckets(ctx context.Context, updatedAt time.Time) (int64, error)
+
+	CreateLoginAttempt(ctx context.Context, arg CreateLoginAttemptParams) error
+	ListLoginAttempts(ctx context.Context, arg ListLoginAttemptsParams) ([]LoginAttempt, error)
+	GetLoginThrottle(ctx context.Context, key string) (LoginThrottle, error)
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error)
+	SetLoginThrottleLock(ctx context.Context, arg SetLoginThrottleLockParams) error
+	DeleteLoginThrottle(ctx context.Context, key string) error
+	DeleteOldLoginAttempts(ctx context.Context, createdAt time.Time) (int64, error)
+	DeleteStaleLoginThrottles(ctx context.Context, lastFailureAt time.Time) (int64, error)
+
+	CreateJWTKey(ctx context.Context, arg CreateJWTKeyParams) (JwtKey, error)
+	ListJWTKeys(ctx context.Context) ([]JwtKey, error)
+	ActivateJWTKey(ctx context.Context, arg ActivateJWTKeyParams) (int64, error)
+	ExpireJWTKey(ctx context.Context, arg ExpireJWTKeyParams) (int64, error)
+	UpdateJWTKeyPrivateKey(ctx context.Context, arg UpdateJWTKeyPrivateKeyParams) error
+
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) error
+	GetPasswordResetUser(ctx context.Context, arg GetPasswordResetUserParams) (uuid.UUID, error)
+	ResetPassword(ctx context.Context, arg ResetPasswordParams) (ResetPasswordRow, error)
+	DeleteStalePasswordResets(ctx context.Context, expiresAt time.Time) (int64, error)
+}
+
+var _ Store = (*Queries)(nil)
@@ -11,21 +11,23 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, email, hashed_password, created_at, updated_at)
-VALUES (gen_random_uuid(), $1, $2, NOW(), NOW())
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+INSERT INTO users (id, email, hashed_password, username, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, $2, $3, NOW(), NOW())
+RETURNING id, created_at, updated_at, email, hashed_password, username, token_version, verified_at
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Username       sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Username)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -33,7 +35,9 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.Username,
+		&i.TokenVersion,
+		&i.VerifiedAt,
 	)
 	return i, err
 }
@@ -48,7 +52,7 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users WHERE email = $1
+SELECT id, created_at, updated_at, email, hashed_password, username, token_version, verified_at FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -60,7 +64,29 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.Username,
+		&i.TokenVersion,
+		&i.VerifiedAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, username, token_version, verified_at FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Username,
+		&i.TokenVersion,
+		&i.VerifiedAt,
 	)
 	return i, err
 }
@@ -69,8 +95,10 @@ const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
 SELECT
   token,
   user_id,
+  family_id,
   expires_at,
-  revoked_at
+  revoked_at,
+  rotated_at
 FROM
   refresh_tokens
 WHERE
@@ -80,8 +108,10 @@ WHERE
 type GetUserFromRefreshTokenRow struct {
 	Token     string
 	UserID    uuid.UUID
+	FamilyID  uuid.UUID
 	ExpiresAt time.Time
 	RevokedAt sql.NullTime
+	RotatedAt sql.NullTime
 }
 
 func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (GetUserFromRefreshTokenRow, error) {
@@ -90,69 +120,210 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Ge
 	err := row.Scan(
 		&i.Token,
 		&i.UserID,
+		&i.FamilyID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.RotatedAt,
 	)
 	return i, err
 }
 
+const getUserTokenVersion = `-- name: GetUserTokenVersion :one
+SELECT token_version FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getUserTokenVersion, id)
+	var token_version int32
+	err := row.Scan(&token_version)
+	return token_version, err
+}
+
+const getUsersByUsernames = `-- name: GetUsersByUsernames :many
+SELECT id, created_at, updated_at, email, hashed_password, username, token_version, verified_at FROM users WHERE username = ANY($1::text[])
+`
+
+func (q *Queries) GetUsersByUsernames(ctx context.Context, usernames []string) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByUsernames, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.Username,
+			&i.TokenVersion,
+			&i.VerifiedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const incrementUserTokenVersion = `-- name: IncrementUserTokenVersion :one
+UPDATE users
+SET token_version = token_version + 1, updated_at = NOW()
+WHERE id = $1
+RETURNING token_version
+`
+
+func (q *Queries) IncrementUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx, incrementUserTokenVersion, id)
+	var token_version int32
+	err := row.Scan(&token_version)
+	return token_version, err
+}
+
 const insertRefreshToken = `-- name: InsertRefreshToken :exec
 INSERT INTO refresh_tokens (
     token,
     user_id,
+    family_id,
     expires_at,
+    user_agent,
+    ip,
     created_at,
-    updated_at
+    updated_at,
+    last_used_at
 ) VALUES (
-    $1, $2, $3, NOW(), NOW()
+    $1, $2, $3, $4, $5, $6, NOW(), NOW(), NOW()
 )
 `
 
 type InsertRefreshTokenParams struct {
 	Token     string
 	UserID    uuid.UUID
+	FamilyID  uuid.UUID
 	ExpiresAt time.Time
+	UserAgent string
+	Ip        string
 }
 
 func (q *Queries) InsertRefreshToken(ctx context.Context, arg InsertRefreshTokenParams) error {
-	_, err := q.db.ExecContext(ctx, insertRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	_, err := q.db.ExecContext(ctx, insertRefreshToken,
+		arg.Token,
+		arg.UserID,
+		arg.FamilyID,
+		arg.ExpiresAt,
+		arg.UserAgent,
+		arg.Ip,
+	)
 	return err
 }
 
-const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :execrows
 UPDATE refresh_tokens
-SET revoked_at = $1, updated_at = $2
-WHERE token = $3
+SET revoked_at = $3, updated_at = $3
+WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL
 `
 
-type RevokeRefreshTokenParams struct {
+type RevokeRefreshTokenFamilyParams struct {
+	UserID    uuid.UUID
+	FamilyID  uuid.UUID
 	RevokedAt sql.NullTime
-	UpdatedAt time.Time
+}
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, arg RevokeRefreshTokenFamilyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, arg.UserID, arg.FamilyID, arg.RevokedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :execrows
+WITH rotated AS (
+    UPDATE refresh_tokens
+    SET rotated_at = $1, replaced_by = $2, updated_at = $1
+    WHERE token = $3 AND rotated_at IS NULL AND revoked_at IS NULL
+    RETURNING user_id, family_id
+)
+INSERT INTO refresh_tokens (
+    token,
+    user_id,
+    family_id,
+    expires_at,
+    user_agent,
+    ip,
+    created_at,
+    updated_at,
+    last_used_at
+)
+SELECT
+    $2, user_id, family_id, $4, $5, $6,
+    $1, $1, $1
+FROM rotated
+`
+
+type RotateRefreshTokenParams struct {
+	RotatedAt sql.NullTime
+	NewToken  string
 	Token     string
+	ExpiresAt time.Time
+	UserAgent string
+	Ip        string
 }
 
-func (q *Queries) RevokeRefreshToken(ctx context.Context, arg RevokeRefreshTokenParams) error {
-	_, err := q.db.ExecContext(ctx, revokeRefreshToken, arg.RevokedAt, arg.UpdatedAt, arg.Token)
-	return err
+// Replaces a live token with new_token in the same family. Both happen in
+// one statement, so a token is never used up without its replacement
+// existing. Affects no rows if the token was already rotated or revoked,
+// including by a concurrent refresh.
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rotateRefreshToken,
+		arg.RotatedAt,
+		arg.NewToken,
+		arg.Token,
+		arg.ExpiresAt,
+		arg.UserAgent,
+		arg.Ip,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET email = $2,
     hashed_password = $3,
+    username = COALESCE($4, username),
+    -- a new address has to be verified again
+    verified_at = CASE WHEN email = $2 THEN verified_at END,
     updated_at =  NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, username, token_version, verified_at
 `
 
 type UpdateUserParams struct {
 	ID             uuid.UUID
 	Email          string
 	HashedPassword string
+	Username       sql.NullString
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.ID, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, updateUser,
+		arg.ID,
+		arg.Email,
+		arg.HashedPassword,
+		arg.Username,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -160,16 +331,31 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.Username,
+		&i.TokenVersion,
+		&i.VerifiedAt,
 	)
 	return i, err
 }
 
-const upgradeUserToChirpyRed = `-- name: UpgradeUserToChirpyRed :exec
-UPDATE users SET is_chirpy_red = TRUE, updated_at = NOW() WHERE id = $1
+const verifyUserEmail = `-- name: VerifyUserEmail :execrows
+UPDATE users
+SET verified_at = $3, updated_at = $3
+WHERE id = $1 AND email = $2 AND verified_at IS NULL
 `
 
-func (q *Queries) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, upgradeUserToChirpyRed, id)
-	return err
+type VerifyUserEmailParams struct {
+	ID         uuid.UUID
+	Email      string
+	VerifiedAt sql.NullTime
+}
+
+// Marks the address verified if it is still the user's and was not
+// verified already, so each verification link works once.
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, verifyUserEmail, arg.ID, arg.Email, arg.VerifiedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
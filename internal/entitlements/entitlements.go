@@ -0,0 +1,128 @@
+// Package entitlements describes what each membership tier may do and
+// loads the per-tier limits from configuration.
+package entitlements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Tier names. Free applies to users without a membership.
+const (
+	Free      = "free"
+	ChirpyRed = "chirpy_red"
+)
+
+// Entitlements are the limits that apply to one tier.
+type Entitlements struct {
+	MaxChirpLength   int  `json:"max_chirp_length"`
+	MaxMediaPerChirp int  `json:"max_media_per_chirp"`
+	CanEdit          bool `json:"can_edit"`
+	// EditWindow is how long after posting a chirp may be edited. Zero
+	// means there is no limit.
+	EditWindow Duration `json:"edit_window"`
+	// RateLimitScale multiplies the per-user rate limits. Zero is
+	// treated as 1.
+	RateLimitScale float64 `json:"rate_limit_scale"`
+}
+
+// CanEditAt reports whether a chirp posted at createdAt may be edited now.
+func (e Entitlements) CanEditAt(createdAt, now time.Time) bool {
+	if !e.CanEdit {
+		return false
+	}
+	return e.EditWindow == 0 || now.Sub(createdAt) <= time.Duration(e.EditWindow)
+}
+
+// Config maps tier names to their entitlements.
+type Config map[string]Entitlements
+
+// Default is the configuration used when none is loaded.
+func Default() Config {
+	return Config{
+		Free: {
+			MaxChirpLength:   140,
+			MaxMediaPerChirp: 4,
+			CanEdit:          true,
+			EditWindow:       Duration(time.Hour),
+			RateLimitScale:   1,
+		},
+		ChirpyRed: {
+			MaxChirpLength:   280,
+			MaxMediaPerChirp: 8,
+			CanEdit:          true,
+			RateLimitScale:   2,
+		},
+	}
+}
+
+// For returns the entitlements of tier. Tiers missing from the
+// configuration get the free tier's.
+func (c Config) For(tier string) Entitlements {
+	if e, ok := c[tier]; ok {
+		return e
+	}
+	return c[Free]
+}
+
+// Validate checks that the free tier is configured and every tier's limits
+// make sense.
+func (c Config) Validate() error {
+	if _, ok := c[Free]; !ok {
+		return errors.New("entitlements: no " + Free + " tier")
+	}
+	for tier, e := range c {
+		if e.MaxChirpLength < 1 {
+			return fmt.Errorf("entitlements: %s: max_chirp_length must be positive", tier)
+		}
+		if e.MaxMediaPerChirp < 0 {
+			return fmt.Errorf("entitlements: %s: max_media_per_chirp must not be negative", tier)
+		}
+		if e.EditWindow < 0 {
+			return fmt.Errorf("entitlements: %s: edit_window must not be negative", tier)
+		}
+		if e.RateLimitScale < 0 {
+			return fmt.Errorf("entitlements: %s: rate_limit_scale must not be negative", tier)
+		}
+	}
+	return nil
+}
+
+// Load reads a JSON configuration from path.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("entitlements: %s: %w", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Duration is a time.Duration written in JSON as a string such as "1h30m".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,60 @@
+package entitlements
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entitlements.json")
+	config := `{
+		"free": {"max_chirp_length": 100, "max_media_per_chirp": 1, "can_edit": false},
+		"chirpy_red": {"max_chirp_length": 500, "max_media_per_chirp": 4, "can_edit": true, "edit_window": "24h"}
+	}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	red := c.For(ChirpyRed)
+	if red.MaxChirpLength != 500 || time.Duration(red.EditWindow) != 24*time.Hour {
+		t.Errorf("chirpy_red: %+v", red)
+	}
+	if got := c.For("platinum"); got != c[Free] {
+		t.Errorf("unknown tier should fall back to free, got %+v", got)
+	}
+
+	created := time.Now().Add(-25 * time.Hour)
+	if c.For(Free).CanEditAt(time.Now(), time.Now()) {
+		t.Error("free tier cannot edit")
+	}
+	if red.CanEditAt(created, time.Now()) {
+		t.Error("edit allowed after the window")
+	}
+	if !Default().For(ChirpyRed).CanEditAt(created, time.Now()) {
+		t.Error("default chirpy_red should edit at any time")
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for name, config := range map[string]string{
+		"no free tier":    `{"chirpy_red": {"max_chirp_length": 280}}`,
+		"zero length":     `{"free": {"max_chirp_length": 0}}`,
+		"bad duration":    `{"free": {"max_chirp_length": 140, "edit_window": "soon"}}`,
+		"negative window": `{"free": {"max_chirp_length": 140, "edit_window": "-1h"}}`,
+		"negative scale":  `{"free": {"max_chirp_length": 140, "rate_limit_scale": -1}}`,
+	} {
+		path := filepath.Join(t.TempDir(), "entitlements.json")
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
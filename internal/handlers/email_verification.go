@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/auth"
+	"github.com/kavancamp/chirpy/internal/database"
+	"github.com/kavancamp/chirpy/internal/mail"
+)
+
+// emailVerificationTTL is how long a verification link works.
+const emailVerificationTTL = 24 * time.Hour
+
+// validEmail reports whether s is a bare email address, without a display
+// name or angle brackets.
+func validEmail(s string) bool {
+	addr, err := netmail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// publicURL is where links in emails point, without a trailing slash.
+func (cfg *ApiConfig) publicURL() string {
+	if cfg.PublicURL == "" {
+		return "http://localhost:8080"
+	}
+	return strings.TrimRight(cfg.PublicURL, "/")
+}
+
+// sendVerificationEmail mails userID a link proving they own email. It
+// does nothing when no Mailer is configured.
+func (cfg *ApiConfig) sendVerificationEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	if cfg.Mailer == nil {
+		return nil
+	}
+	token, err := auth.MakeVerificationToken(userID, email, cfg.keys().SigningKey(), emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	link := cfg.publicURL() + "/api/users/verify?token=" + url.QueryEscape(token)
+	return cfg.Mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Verify your email address for Chirpy",
+		Body: "Welcome to Chirpy! Open this link within 24 hours to verify your email address:\n\n" +
+			link + "\n\nIf you did not sign up for Chirpy, you can ignore this email.\n",
+	})
+}
+
+// requireVerified writes a 403 and returns false when verified email
+// addresses are required and userID has not verified theirs.
+func (cfg *ApiConfig) requireVerified(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	if !cfg.RequireVerifiedEmail {
+		return true
+	}
+	user, err := cfg.DB.GetUserByID(r.Context(), userID)
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "User not found")
+		return false
+	}
+	if !user.VerifiedAt.Valid {
+		RespondWithError(w, http.StatusForbidden, "Verify your email address first")
+		return false
+	}
+	return true
+}
+
+// HandleVerifyEmail serves GET /api/users/verify?token=, the link sent on
+// signup. Each link works once, and only while the address is still the
+// user's.
+func (cfg *ApiConfig) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	userID, email, err := auth.ParseVerificationToken(r.URL.Query().Get("token"), cfg.keys())
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Verification link is invalid or has expired")
+		return
+	}
+
+	n, err := cfg.DB.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+		ID:         userID,
+		Email:      email,
+		VerifiedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error verifying email for %s: %s", userID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not verify email address")
+		return
+	}
+	if n == 0 {
+		RespondWithError(w, http.StatusBadRequest, "Verification link has already been used")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"id":             userID,
+		"email":          email,
+		"email_verified": true,
+	})
+}
+
+// HandleResendVerification serves POST /api/users/verify/resend, mailing
+// the caller a fresh verification link.
+func (cfg *ApiConfig) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := cfg.DB.GetUserByID(r.Context(), userID)
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "User not found")
+		return
+	}
+	if user.VerifiedAt.Valid {
+		RespondWithError(w, http.StatusConflict, "Email address is already verified")
+		return
+	}
+	if err := cfg.sendVerificationEmail(r.Context(), user.ID, user.Email); err != nil {
+		log.Printf("error sending verification email to %s: %s", user.ID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not send verification email")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
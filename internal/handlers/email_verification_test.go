@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/kavancamp/chirpy/internal/mail"
+)
+
+// outbox is a mail.Mailer that keeps what it is sent.
+type outbox struct {
+	mu   sync.Mutex
+	msgs []mail.Message
+}
+
+func (o *outbox) Send(ctx context.Context, msg mail.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.msgs = append(o.msgs, msg)
+	return nil
+}
+
+var verifyLinkRe = regexp.MustCompile(`(https?://\S+/api/users/verify\?token=\S+)`)
+
+// lastVerifyLink returns the path and query of the link in the latest
+// message to to.
+func (o *outbox) lastVerifyLink(t *testing.T, to string) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.msgs) - 1; i >= 0; i-- {
+		if o.msgs[i].To != to {
+			continue
+		}
+		m := verifyLinkRe.FindStringSubmatch(o.msgs[i].Body)
+		if m == nil {
+			t.Fatalf("no verification link in %q", o.msgs[i].Body)
+		}
+		u, err := url.Parse(m[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.RequestURI()
+	}
+	t.Fatalf("no mail to %s", to)
+	return ""
+}
+
+func (o *outbox) count() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.msgs)
+}
+
+func TestEmailVerification(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	mails := &outbox{}
+	cfg.Mailer = mails
+	cfg.PublicURL = "https://chirpy.example/"
+	cfg.RequireVerifiedEmail = true
+
+	alice := signup(t, srv, "alice@example.com")
+	if alice.EmailVerified {
+		t.Fatal("expected a new account to be unverified")
+	}
+	link := mails.lastVerifyLink(t, "alice@example.com")
+
+	chirp := map[string]string{"body": "hello"}
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+alice.Token, chirp, nil); code != http.StatusForbidden {
+		t.Fatalf("chirp before verifying: expected 403, got %d", code)
+	}
+
+	if code := doJSON(t, "GET", srv.URL+"/api/users/verify?token=garbage", "", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("bad token: expected 400, got %d", code)
+	}
+	if code := doJSON(t, "GET", srv.URL+link, "", nil, nil); code != http.StatusOK {
+		t.Fatalf("verify: status %d", code)
+	}
+	if code := doJSON(t, "GET", srv.URL+link, "", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("reused link: expected 400, got %d", code)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+alice.Token, chirp, nil); code != http.StatusCreated {
+		t.Fatalf("chirp after verifying: status %d", code)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/users/verify/resend", "Bearer "+alice.Token, nil, nil); code != http.StatusConflict {
+		t.Fatalf("resend when verified: expected 409, got %d", code)
+	}
+
+	// changing the address has to be verified again
+	var updated User
+	update := map[string]string{"email": "alice@example.org", "password": "hunter2"}
+	if code := doJSON(t, "PUT", srv.URL+"/api/users", "Bearer "+alice.Token, update, &updated); code != http.StatusOK {
+		t.Fatalf("update: status %d", code)
+	}
+	if updated.EmailVerified {
+		t.Fatal("expected a changed address to be unverified")
+	}
+	newLink := mails.lastVerifyLink(t, "alice@example.org")
+	sent := mails.count()
+	if code := doJSON(t, "POST", srv.URL+"/api/users/verify/resend", "Bearer "+alice.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("resend: status %d", code)
+	}
+	if mails.count() != sent+1 {
+		t.Fatal("expected another verification email")
+	}
+	if code := doJSON(t, "GET", srv.URL+newLink, "", nil, nil); code != http.StatusOK {
+		t.Fatalf("verify new address: status %d", code)
+	}
+}
+
+func TestEmailVerification_StaleAddress(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	mails := &outbox{}
+	cfg.Mailer = mails
+
+	alice := signup(t, srv, "alice@example.com")
+	link := mails.lastVerifyLink(t, "alice@example.com")
+	update := map[string]string{"email": "alice@example.org", "password": "hunter2"}
+	if code := doJSON(t, "PUT", srv.URL+"/api/users", "Bearer "+alice.Token, update, nil); code != http.StatusOK {
+		t.Fatalf("update: status %d", code)
+	}
+	// a link for the old address does not verify the new one
+	if code := doJSON(t, "GET", srv.URL+link, "", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("stale link: expected 400, got %d", code)
+	}
+
+	// unverified users can post unless verification is required
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+alice.Token, map[string]string{"body": "hello"}, nil); code != http.StatusCreated {
+		t.Fatalf("chirp: status %d", code)
+	}
+}
+
+func TestCreateUser_InvalidEmail(t *testing.T) {
+	_, srv := newTestAPI(t)
+	for _, email := range []string{"not-an-email", "Alice <alice@example.com>", "alice@"} {
+		creds := map[string]string{"email": email, "password": "hunter2"}
+		if code := doJSON(t, "POST", srv.URL+"/api/users", "", creds, nil); code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", email, code)
+		}
+	}
+}
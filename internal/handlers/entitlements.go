@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+	"github.com/kavancamp/chirpy/internal/entitlements"
+)
+
+// entitlements returns the limits that apply to userID under their current
+// membership tier.
+func (cfg *ApiConfig) entitlements(ctx context.Context, userID uuid.UUID) (entitlements.Entitlements, error) {
+	config := cfg.Entitlements
+	if config == nil {
+		config = entitlements.Default()
+	}
+	ms, err := cfg.DB.GetActiveMembership(ctx, database.GetActiveMembershipParams{
+		UserID: userID,
+		Now:    time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return config.For(entitlements.Free), nil
+	}
+	if err != nil {
+		return entitlements.Entitlements{}, err
+	}
+	return config.For(ms.Tier), nil
+}
+
+// HandleGetEntitlements serves GET /api/users/me/entitlements: the limits
+// that apply to the caller.
+func (cfg *ApiConfig) HandleGetEntitlements(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	ent, err := cfg.entitlements(r.Context(), userID)
+	if err != nil {
+		log.Printf("error loading entitlements for %s: %s", userID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve entitlements")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, ent)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kavancamp/chirpy/internal/entitlements"
+)
+
+func TestEntitlements(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+
+	get := func() entitlements.Entitlements {
+		t.Helper()
+		var out entitlements.Entitlements
+		if code := doJSON(t, "GET", srv.URL+"/api/users/me/entitlements", "Bearer "+alice.Token, nil, &out); code != http.StatusOK {
+			t.Fatalf("entitlements: status %d", code)
+		}
+		return out
+	}
+	create := func(body string) int {
+		t.Helper()
+		return doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+alice.Token, map[string]string{"body": body}, nil)
+	}
+
+	if got, want := get(), entitlements.Default().For(entitlements.Free); got != want {
+		t.Fatalf("free user: got %+v, want %+v", got, want)
+	}
+	long := strings.Repeat("a", 200)
+	if code := create(long); code != http.StatusBadRequest {
+		t.Fatalf("200 characters on the free tier: expected 400, got %d", code)
+	}
+
+	if err := cfg.startMembership(context.Background(), alice.ID, sql.NullTime{}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if got := get(); got.MaxChirpLength != 280 || got.EditWindow != 0 {
+		t.Fatalf("Chirpy Red user: %+v", got)
+	}
+	if code := create(long); code != http.StatusCreated {
+		t.Fatalf("200 characters on Chirpy Red: expected 201, got %d", code)
+	}
+	if code := create(strings.Repeat("a", 281)); code != http.StatusBadRequest {
+		t.Fatalf("281 characters on Chirpy Red: expected 400, got %d", code)
+	}
+
+	// a loaded configuration replaces the defaults
+	cfg.Entitlements = entitlements.Config{
+		entitlements.Free: {MaxChirpLength: 10},
+	}
+	if got := get(); got.MaxChirpLength != 10 || got.CanEdit {
+		t.Fatalf("configured tiers: %+v", got)
+	}
+	chirp := postChirp(t, srv, alice.Token, "short")
+	edit := map[string]string{"body": "shorter"}
+	if code := doJSON(t, "PUT", srv.URL+"/api/chirps/"+chirp.ID, "Bearer "+alice.Token, edit, nil); code != http.StatusForbidden {
+		t.Fatalf("edit without the entitlement: expected 403, got %d", code)
+	}
+}
@@ -1,19 +1,181 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"time"
 	"log"
 	"strings"
-	"sort"
 	"github.com/kavancamp/chirpy/internal/auth"
 	"github.com/kavancamp/chirpy/internal/database"
 	"github.com/google/uuid"
 )
+
+type Chirp struct {
+	ID             uuid.UUID     `json:"id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	Body           string        `json:"body"`
+	UserID         uuid.UUID     `json:"user_id"`
+	InReplyTo      uuid.NullUUID `json:"in_reply_to"`
+	ConversationID uuid.UUID     `json:"conversation_id"`
+	RechirpOf      uuid.NullUUID `json:"rechirp_of"`
+	QuoteOf        uuid.NullUUID `json:"quote_of"`
+	Original       *Chirp        `json:"original,omitempty"`
+	Deleted        bool          `json:"deleted,omitempty"`
+	LikeCount      int64         `json:"like_count"`
+	LikedByMe      *bool         `json:"liked_by_me,omitempty"`
+	RechirpCount   int64         `json:"rechirp_count"`
+	QuoteCount     int64         `json:"quote_count"`
+	RechirpedByMe  *bool         `json:"rechirped_by_me,omitempty"`
+	EditedAt       *time.Time    `json:"edited_at,omitempty"`
+	Media          []Media       `json:"media,omitempty"`
+}
+
+func chirpFromDB(c database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:             c.ID,
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+		Body:           c.Body,
+		UserID:         c.UserID,
+		InReplyTo:      c.InReplyTo,
+		ConversationID: c.ConversationID,
+		RechirpOf:      c.RechirpOf,
+		QuoteOf:        c.QuoteOf,
+		Deleted:        c.TombstonedAt.Valid || c.DeletedAt.Valid,
+	}
+	if c.EditedAt.Valid {
+		chirp.EditedAt = &c.EditedAt.Time
+	}
+	if chirp.Deleted {
+		chirp.Body = ""
+	}
+	return chirp
+}
+
+// renderChirps builds response payloads for chirps: like and rechirp counts,
+// the viewer's own likes and rechirps when viewer is set, and the original
+// chirp embedded in rechirps and quotes.
+func (cfg *ApiConfig) renderChirps(ctx context.Context, chirps []database.Chirp, viewer uuid.NullUUID) ([]Chirp, error) {
+	out, err := cfg.renderChirpStats(ctx, chirps, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	var originalIDs []uuid.UUID
+	for _, c := range chirps {
+		if c.RechirpOf.Valid {
+			originalIDs = append(originalIDs, c.RechirpOf.UUID)
+		}
+		if c.QuoteOf.Valid {
+			originalIDs = append(originalIDs, c.QuoteOf.UUID)
+		}
+	}
+	if len(originalIDs) == 0 {
+		return out, nil
+	}
+	originals, err := cfg.DB.GetChirpsByIDs(ctx, originalIDs)
+	if err != nil {
+		return nil, err
+	}
+	// originals are embedded one level deep only
+	rendered, err := cfg.renderChirpStats(ctx, originals, viewer)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*Chirp, len(rendered))
+	for i := range rendered {
+		byID[rendered[i].ID] = &rendered[i]
+	}
+	for i := range out {
+		if out[i].RechirpOf.Valid {
+			out[i].Original = byID[out[i].RechirpOf.UUID]
+		}
+		if out[i].QuoteOf.Valid {
+			out[i].Original = byID[out[i].QuoteOf.UUID]
+			// a deleted original is not linked to until it is restored
+			if out[i].Original == nil {
+				out[i].QuoteOf = uuid.NullUUID{}
+			}
+		}
+	}
+	return out, nil
+}
+
+// renderChirpStats converts chirps and fills in their like and rechirp
+// counts and attachments.
+func (cfg *ApiConfig) renderChirpStats(ctx context.Context, chirps []database.Chirp, viewer uuid.NullUUID) ([]Chirp, error) {
+	ids := make([]uuid.UUID, len(chirps))
+	for i, c := range chirps {
+		ids[i] = c.ID
+	}
+	likeStats, err := cfg.DB.GetLikeStats(ctx, database.GetLikeStatsParams{
+		ViewerID: viewer,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rechirpStats, err := cfg.DB.GetRechirpStats(ctx, database.GetRechirpStatsParams{
+		ViewerID: viewer,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := cfg.DB.ListChirpMedia(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	chirpMedia := make(map[uuid.UUID][]Media)
+	for _, a := range attachments {
+		chirpMedia[a.ChirpID.UUID] = append(chirpMedia[a.ChirpID.UUID], mediaFromDB(a))
+	}
+	likes := make(map[uuid.UUID]database.GetLikeStatsRow, len(likeStats))
+	for _, s := range likeStats {
+		likes[s.ChirpID] = s
+	}
+	rechirps := make(map[uuid.UUID]database.GetRechirpStatsRow, len(rechirpStats))
+	for _, s := range rechirpStats {
+		rechirps[s.ChirpID] = s
+	}
+
+	out := make([]Chirp, 0, len(chirps))
+	for _, c := range chirps {
+		chirp := chirpFromDB(c)
+		l, rc := likes[c.ID], rechirps[c.ID]
+		chirp.LikeCount = l.LikeCount
+		chirp.RechirpCount = rc.RechirpCount
+		chirp.QuoteCount = rc.QuoteCount
+		if !chirp.Deleted {
+			chirp.Media = chirpMedia[c.ID]
+		}
+		if viewer.Valid {
+			chirp.LikedByMe = &l.LikedByMe
+			chirp.RechirpedByMe = &rc.RechirpedByMe
+		}
+		out = append(out, chirp)
+	}
+	return out, nil
+}
+
+func (cfg *ApiConfig) renderChirp(ctx context.Context, c database.Chirp, viewer uuid.NullUUID) (Chirp, error) {
+	out, err := cfg.renderChirps(ctx, []database.Chirp{c}, viewer)
+	if err != nil {
+		return Chirp{}, err
+	}
+	return out[0], nil
+}
+
 func (cfg *ApiConfig) HandleCreateChirp(w http.ResponseWriter, r *http.Request) {
 	type ChirpInput struct {
-		Body string `json:"body"`
+		Body      string      `json:"body"`
+		InReplyTo *uuid.UUID  `json:"in_reply_to"`
+		QuoteOf   *uuid.UUID  `json:"quote_of"`
+		MediaIDs  []uuid.UUID `json:"media_ids"`
 	}
 
 	var input ChirpInput
@@ -21,108 +183,187 @@ func (cfg *ApiConfig) HandleCreateChirp(w http.ResponseWriter, r *http.Request)
 		RespondWithError(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
-	if len(input.Body) > 140 {
-		RespondWithError(w, http.StatusBadRequest, "Chirp is too long")
-		return
-	}
-
 	tokenStr, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		RespondWithError(w, http.StatusUnauthorized, "Missing or invalid token")
 		return
 	}
 
-	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	userID, err := cfg.validateJWT(r.Context(), tokenStr)
 	if err != nil {
 		RespondWithError(w, http.StatusUnauthorized, "Invalid or expired token")
 		return
 	}
+	if !cfg.requireVerified(w, r, userID) {
+		return
+	}
+
+	ent, err := cfg.entitlements(r.Context(), userID)
+	if err != nil {
+		log.Printf("error loading entitlements for %s: %s", userID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not create chirp")
+		return
+	}
+	if len(input.Body) > ent.MaxChirpLength {
+		RespondWithError(w, http.StatusBadRequest, "Chirp is too long")
+		return
+	}
 
 	body := CleanProfanity(input.Body)
 	now := time.Now().UTC()
 	id := uuid.New()
 
+	// a reply joins its parent's conversation; anything else starts one
+	var inReplyTo, parentAuthor uuid.NullUUID
+	conversationID := id
+	if input.InReplyTo != nil {
+		parent, err := cfg.DB.GetChirpsByID(r.Context(), *input.InReplyTo)
+		if err != nil || parent.TombstonedAt.Valid {
+			RespondWithError(w, http.StatusNotFound, "Parent chirp not found")
+			return
+		}
+		inReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		parentAuthor = uuid.NullUUID{UUID: parent.UserID, Valid: true}
+		conversationID = parent.ConversationID
+	}
+
+	// quoting a plain rechirp quotes the chirp it reshared
+	var quoteOf uuid.NullUUID
+	if input.QuoteOf != nil {
+		if strings.TrimSpace(input.Body) == "" {
+			RespondWithError(w, http.StatusBadRequest, "Quote chirps need a body")
+			return
+		}
+		original, err := cfg.DB.GetChirpsByID(r.Context(), *input.QuoteOf)
+		if err != nil || original.TombstonedAt.Valid {
+			RespondWithError(w, http.StatusNotFound, "Quoted chirp not found")
+			return
+		}
+		if original.RechirpOf.Valid {
+			quoteOf = original.RechirpOf
+		} else {
+			quoteOf = uuid.NullUUID{UUID: original.ID, Valid: true}
+		}
+	}
+
+	if len(input.MediaIDs) > 0 {
+		msg, err := cfg.checkChirpMedia(r.Context(), userID, input.MediaIDs, ent.MaxMediaPerChirp)
+		if err != nil {
+			log.Printf("error checking chirp media: %s", err)
+			RespondWithError(w, http.StatusInternalServerError, "Could not create chirp")
+			return
+		}
+		if msg != "" {
+			RespondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
+
 	dbChirp, err := cfg.DB.CreateChirp(r.Context(), database.CreateChirpParams{
-		ID:        id,
-		CreatedAt: now,
-		UpdatedAt: now,
-		Body:      body,
-		UserID:    userID,
+		ID:             id,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Body:           body,
+		UserID:         userID,
+		InReplyTo:      inReplyTo,
+		ConversationID: conversationID,
+		QuoteOf:        quoteOf,
 	})
 	if err != nil {
 		log.Printf("error creating chirp: %s", err)
 		RespondWithError(w, http.StatusInternalServerError, "Could not create chirp")
 		return
 	}
-	type ChirpResponse struct {
-		ID        uuid.UUID `json:"id"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-		Body      string    `json:"body"`
-		UserID    uuid.UUID `json:"user_id"`
+	// tags and notifications are derived from the chirp, so a failure here
+	// is logged rather than failing a chirp that has already been stored
+	if err := cfg.tagChirp(r.Context(), dbChirp); err != nil {
+		log.Printf("error tagging chirp %s: %s", dbChirp.ID, err)
 	}
-	RespondWithJSON(w, http.StatusCreated, ChirpResponse{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
-	})
+	cfg.notifyChirp(r.Context(), dbChirp, parentAuthor)
+	if err := cfg.attachChirpMedia(r.Context(), dbChirp, input.MediaIDs); err != nil {
+		log.Printf("error attaching media to chirp %s: %s", dbChirp.ID, err)
+	}
+
+	chirp, err := cfg.renderChirp(r.Context(), dbChirp, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		log.Printf("error rendering chirp: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not create chirp")
+		return
 	}
+	RespondWithJSON(w, http.StatusCreated, chirp)
+}
 
 func (cfg *ApiConfig) HandleGetChirps(w http.ResponseWriter, r *http.Request) {
-	chirps, err := cfg.DB.GetChirps(r.Context())
+	page, err := parsePageRequest(r)
 	if err != nil {
-		log.Printf("error getting chirps: %s", err)
-		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve chirps")
+		RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
 		return
 	}
 
-	authorIDStr := r.URL.Query().Get("author_id")
-	if authorIDStr != "" {
-		authorID, err := uuid.Parse(authorIDStr)
-		if err == nil {
-			filtered := make([]database.Chirp, 0)
-			for _, c := range chirps {
-				if c.UserID == authorID {
-					filtered = append(filtered, c)
-				}
-			}
-			chirps = filtered
-		}
+	authorID, err := parseAuthorID(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid author_id")
+		return
 	}
 
-	sortOrder := r.URL.Query().Get("sort")
-	if sortOrder != "desc" {
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
-		})
-	} else {
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[j].CreatedAt.Before(chirps[i].CreatedAt)
+	desc := r.URL.Query().Get("sort") == "desc"
+	chirps, links, err := paginate(page, desc, chirpCursor, func(asc bool, c *pageCursor, n int32) ([]database.Chirp, error) {
+		createdAt, id := cursorArgs(c)
+		if asc {
+			return cfg.DB.ListChirpsAfter(r.Context(), database.ListChirpsAfterParams{
+				AuthorID:        authorID,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				PageSize:        n,
+			})
+		}
+		return cfg.DB.ListChirpsBefore(r.Context(), database.ListChirpsBeforeParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageSize:        n,
 		})
+	})
+	if err != nil {
+		log.Printf("error getting chirps: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve chirps")
+		return
 	}
 
-	type Chirp struct {
-		ID        uuid.UUID `json:"id"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-		Body      string    `json:"body"`
-		UserID    uuid.UUID `json:"user_id"`
+	chirpList, err := cfg.renderChirps(r.Context(), chirps, cfg.viewerID(r))
+	if err != nil {
+		log.Printf("error rendering chirps: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve chirps")
+		return
 	}
 
-	var chirpList []Chirp
-	for _, c := range chirps {
-		chirpList = append(chirpList, Chirp{
-			ID:        c.ID,
-			CreatedAt: c.CreatedAt,
-			UpdatedAt: c.UpdatedAt,
-			Body:      c.Body,
-			UserID:    c.UserID,
-		})
+	setLinkHeader(w, r, links)
+	RespondWithJSON(w, http.StatusOK, chirpList)
+}
+
+// parseAuthorID reads the optional author_id filter.
+func parseAuthorID(r *http.Request) (uuid.NullUUID, error) {
+	s := r.URL.Query().Get("author_id")
+	if s == "" {
+		return uuid.NullUUID{}, nil
 	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: id, Valid: true}, nil
+}
 
-	RespondWithJSON(w, http.StatusOK, chirpList)
+func chirpCursor(c database.Chirp) pageCursor {
+	return pageCursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+// cursorArgs converts an optional cursor into nullable query parameters.
+func cursorArgs(c *pageCursor) (sql.NullTime, uuid.NullUUID) {
+	if c == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: c.CreatedAt, Valid: true}, uuid.NullUUID{UUID: c.ID, Valid: true}
 }
 
 
@@ -136,28 +377,33 @@ func (cfg *ApiConfig) HandleGetChirpByID(w http.ResponseWriter, r *http.Request)
 	}
 	//get chirp from database
 	dbChirp, err := cfg.DB.GetChirpsByID(r.Context(), chirpID)
-	if err != nil {
+	if err != nil || dbChirp.TombstonedAt.Valid {
 		RespondWithError(w, http.StatusNotFound, "chirp not found")
 		return
 	}
-	// Map to output struct with correct JSON field names
-	type Chirp struct {
-		ID        uuid.UUID `json:"id"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-		Body      string    `json:"body"`
-		UserID    uuid.UUID `json:"user_id"`
-	}
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
+	chirp, err := cfg.renderChirp(r.Context(), dbChirp, cfg.viewerID(r))
+	if err != nil {
+		log.Printf("error rendering chirp: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve chirp")
+		return
 	}
-
 	RespondWithJSON(w, http.StatusOK, chirp)
 }
+// authorChirp loads a live chirp for a change only its author may make,
+// writing a 404 if it does not exist and a 403 if userID did not write it.
+func (cfg *ApiConfig) authorChirp(w http.ResponseWriter, r *http.Request, chirpID, userID uuid.UUID, action string) (database.Chirp, bool) {
+	chirp, err := cfg.DB.GetChirpsByID(r.Context(), chirpID)
+	if err != nil || chirp.TombstonedAt.Valid {
+		RespondWithError(w, http.StatusNotFound, "Chirp not found")
+		return database.Chirp{}, false
+	}
+	if chirp.UserID != userID {
+		RespondWithError(w, http.StatusForbidden, "Not authorized to "+action+" this chirp")
+		return database.Chirp{}, false
+	}
+	return chirp, true
+}
+
 func (cfg *ApiConfig) HandleDeleteChirp(w http.ResponseWriter, r *http.Request) {
 	// 1. Extract and validate JWT
 	tokenStr, err := auth.GetBearerToken(r.Header)
@@ -166,7 +412,7 @@ func (cfg *ApiConfig) HandleDeleteChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	userID, err := cfg.validateJWT(r.Context(), tokenStr)
 	if err != nil {
 		RespondWithError(w, http.StatusUnauthorized, "Invalid or expired token")
 		return
@@ -180,21 +426,22 @@ func (cfg *ApiConfig) HandleDeleteChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// 3. Get chirp from DB
-	chirp, err := cfg.DB.GetChirpsByID(r.Context(), chirpID)
-	if err != nil {
-		RespondWithError(w, http.StatusNotFound, "Chirp not found")
+	// 3. Get chirp from DB and ensure the authenticated user is the author
+	chirp, ok := cfg.authorChirp(w, r, chirpID, userID, "delete")
+	if !ok {
 		return
 	}
 
-	// 4. Ensure the authenticated user is the author
-	if chirp.UserID != userID {
-		RespondWithError(w, http.StatusForbidden, "Not authorized to delete this chirp")
-		return
+	// 4. Rechirps are removed outright so they can be made again; anything
+	// else is soft deleted and can be restored until the purge removes it
+	if chirp.RechirpOf.Valid {
+		err = cfg.DB.DeleteChirpByID(r.Context(), chirpID)
+	} else {
+		err = cfg.DB.SoftDeleteChirp(r.Context(), database.SoftDeleteChirpParams{
+			ID:        chirpID,
+			DeletedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		})
 	}
-
-	// 5. Delete the chirp
-	err = cfg.DB.DeleteChirpByID(r.Context(), chirpID)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Failed to delete chirp")
 		return
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+type chirpJSON struct {
+	ID     string `json:"id"`
+	Body   string `json:"body"`
+	UserID string `json:"user_id"`
+}
+
+func postChirp(t *testing.T, srv *httptest.Server, token, body string) chirpJSON {
+	t.Helper()
+	var c chirpJSON
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+token, map[string]string{"body": body}, &c); code != http.StatusCreated {
+		t.Fatalf("create chirp %q: status %d", body, code)
+	}
+	return c
+}
+
+var linkRe = regexp.MustCompile(`<([^>]+)>; rel="(\w+)"`)
+
+// getPage fetches a chirp listing and returns its body and Link relations.
+func getPage(t *testing.T, url string) ([]chirpJSON, map[string]string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d", url, resp.StatusCode)
+	}
+	var chirps []chirpJSON
+	if err := json.NewDecoder(resp.Body).Decode(&chirps); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	links := map[string]string{}
+	for _, m := range linkRe.FindAllStringSubmatch(resp.Header.Get("Link"), -1) {
+		links[m[2]] = m[1]
+	}
+	return chirps, links
+}
+
+func bodies(chirps []chirpJSON) []string {
+	out := make([]string, len(chirps))
+	for i, c := range chirps {
+		out[i] = c.Body
+	}
+	return out
+}
+
+func TestGetChirps_CursorPagination(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	for _, b := range []string{"one", "two", "three", "four", "five"} {
+		postChirp(t, srv, alice.Token, b)
+	}
+
+	tests := []struct {
+		sort  string
+		pages [][]string
+	}{
+		{"asc", [][]string{{"one", "two"}, {"three", "four"}, {"five"}}},
+		{"desc", [][]string{{"five", "four"}, {"three", "two"}, {"one"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			url := srv.URL + "/api/chirps?limit=2&sort=" + tt.sort
+			var prevURL string
+			for i, want := range tt.pages {
+				chirps, links := getPage(t, url)
+				if got := bodies(chirps); !slices.Equal(got, want) {
+					t.Fatalf("page %d: got %v, want %v", i, got, want)
+				}
+				if i == 0 && links["prev"] != "" {
+					t.Errorf("first page should not have a prev link")
+				}
+				if i == len(tt.pages)-1 {
+					if links["next"] != "" {
+						t.Errorf("last page should not have a next link")
+					}
+					prevURL = links["prev"]
+					break
+				}
+				url = srv.URL + links["next"]
+			}
+
+			// walking back from the last page returns the middle page
+			chirps, _ := getPage(t, srv.URL+prevURL)
+			if got := bodies(chirps); !slices.Equal(got, tt.pages[1]) {
+				t.Fatalf("prev page: got %v, want %v", got, tt.pages[1])
+			}
+		})
+	}
+}
+
+func TestGetChirps_InvalidCursor(t *testing.T) {
+	_, srv := newTestAPI(t)
+	if code := doJSON(t, "GET", srv.URL+"/api/chirps?cursor=not-a-cursor", "", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", code)
+	}
+}
+
+func TestGetChirps_AuthorFilter(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+	postChirp(t, srv, alice.Token, "from alice")
+	postChirp(t, srv, bob.Token, "from bob")
+	postChirp(t, srv, alice.Token, "alice again")
+
+	chirps, _ := getPage(t, srv.URL+"/api/chirps?sort=desc&author_id="+alice.ID.String())
+	if got, want := bodies(chirps), []string{"alice again", "from alice"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if code := doJSON(t, "GET", srv.URL+"/api/chirps?author_id=nope", "", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("malformed author_id: expected 400, got %d", code)
+	}
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+type FollowEntry struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// HandleFollowUser serves POST /api/users/{id}/follow. Following someone
+// twice is a no-op.
+func (cfg *ApiConfig) HandleFollowUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if followeeID == userID {
+		RespondWithError(w, http.StatusBadRequest, "You cannot follow yourself")
+		return
+	}
+	if _, err := cfg.DB.GetUserByID(r.Context(), followeeID); err != nil {
+		RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	err = cfg.DB.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		log.Printf("error following user: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not follow user")
+		return
+	}
+	cfg.notify(r.Context(), followeeID, userID, notifyFollow, uuid.NullUUID{})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleUnfollowUser serves DELETE /api/users/{id}/follow.
+func (cfg *ApiConfig) HandleUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	err = cfg.DB.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		log.Printf("error unfollowing user: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not unfollow user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetFollowers serves GET /api/users/{id}/followers, oldest follow
+// first unless sort=desc.
+func (cfg *ApiConfig) HandleGetFollowers(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, func(f database.Follow) uuid.UUID { return f.FollowerID },
+		func(userID uuid.UUID, asc bool, createdAt sql.NullTime, id uuid.NullUUID, n int32) ([]database.Follow, error) {
+			if asc {
+				return cfg.DB.ListFollowersAfter(r.Context(), database.ListFollowersAfterParams{
+					UserID:          userID,
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+					PageSize:        n,
+				})
+			}
+			return cfg.DB.ListFollowersBefore(r.Context(), database.ListFollowersBeforeParams{
+				UserID:          userID,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				PageSize:        n,
+			})
+		})
+}
+
+// HandleGetFollowing serves GET /api/users/{id}/following, oldest follow
+// first unless sort=desc.
+func (cfg *ApiConfig) HandleGetFollowing(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, func(f database.Follow) uuid.UUID { return f.FolloweeID },
+		func(userID uuid.UUID, asc bool, createdAt sql.NullTime, id uuid.NullUUID, n int32) ([]database.Follow, error) {
+			if asc {
+				return cfg.DB.ListFollowingAfter(r.Context(), database.ListFollowingAfterParams{
+					UserID:          userID,
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+					PageSize:        n,
+				})
+			}
+			return cfg.DB.ListFollowingBefore(r.Context(), database.ListFollowingBeforeParams{
+				UserID:          userID,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				PageSize:        n,
+			})
+		})
+}
+
+// listFollows serves a paginated follower or following list for the user in
+// the path. other picks the user on the far side of each follow.
+func (cfg *ApiConfig) listFollows(w http.ResponseWriter, r *http.Request, other func(database.Follow) uuid.UUID, fetch func(userID uuid.UUID, asc bool, createdAt sql.NullTime, id uuid.NullUUID, n int32) ([]database.Follow, error)) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if _, err := cfg.DB.GetUserByID(r.Context(), userID); err != nil {
+		RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		return
+	}
+
+	key := func(f database.Follow) pageCursor {
+		return pageCursor{CreatedAt: f.CreatedAt, ID: other(f)}
+	}
+	desc := r.URL.Query().Get("sort") == "desc"
+	follows, links, err := paginate(page, desc, key, func(asc bool, c *pageCursor, n int32) ([]database.Follow, error) {
+		createdAt, id := cursorArgs(c)
+		return fetch(userID, asc, createdAt, id, n)
+	})
+	if err != nil {
+		log.Printf("error listing follows: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve follows")
+		return
+	}
+
+	entries := make([]FollowEntry, 0, len(follows))
+	for _, f := range follows {
+		entries = append(entries, FollowEntry{UserID: other(f), FollowedAt: f.CreatedAt})
+	}
+
+	setLinkHeader(w, r, links)
+	RespondWithJSON(w, http.StatusOK, entries)
+}
+
+// HandleGetTimeline serves GET /api/timeline: chirps by the users the caller
+// follows, newest first unless sort=asc.
+func (cfg *ApiConfig) HandleGetTimeline(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		return
+	}
+
+	desc := r.URL.Query().Get("sort") != "asc"
+	chirps, links, err := paginate(page, desc, chirpCursor, func(asc bool, c *pageCursor, n int32) ([]database.Chirp, error) {
+		createdAt, id := cursorArgs(c)
+		if asc {
+			return cfg.DB.ListTimelineAfter(r.Context(), database.ListTimelineAfterParams{
+				UserID:          userID,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				PageSize:        n,
+			})
+		}
+		return cfg.DB.ListTimelineBefore(r.Context(), database.ListTimelineBeforeParams{
+			UserID:          userID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageSize:        n,
+		})
+	})
+	if err != nil {
+		log.Printf("error getting timeline: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve timeline")
+		return
+	}
+
+	chirpList, err := cfg.renderChirps(r.Context(), chirps, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		log.Printf("error rendering chirps: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve timeline")
+		return
+	}
+
+	setLinkHeader(w, r, links)
+	RespondWithJSON(w, http.StatusOK, chirpList)
+}
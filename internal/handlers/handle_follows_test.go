@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestFollowsAndTimeline(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+	carol := signup(t, srv, "carol@example.com")
+
+	postChirp(t, srv, bob.Token, "bob 1")
+	postChirp(t, srv, carol.Token, "carol 1")
+	postChirp(t, srv, bob.Token, "bob 2")
+	postChirp(t, srv, alice.Token, "alice 1")
+
+	follow := func(who loginResponse, target string) int {
+		return doJSON(t, "POST", srv.URL+"/api/users/"+target+"/follow", "Bearer "+who.Token, nil, nil)
+	}
+	if code := follow(alice, bob.ID.String()); code != http.StatusNoContent {
+		t.Fatalf("follow: status %d", code)
+	}
+	if code := follow(carol, bob.ID.String()); code != http.StatusNoContent {
+		t.Fatalf("follow: status %d", code)
+	}
+	if code := follow(alice, alice.ID.String()); code != http.StatusBadRequest {
+		t.Fatalf("self follow: expected 400, got %d", code)
+	}
+	if code := follow(alice, "00000000-0000-0000-0000-000000000000"); code != http.StatusNotFound {
+		t.Fatalf("follow missing user: expected 404, got %d", code)
+	}
+
+	var followers []FollowEntry
+	doJSON(t, "GET", srv.URL+"/api/users/"+bob.ID.String()+"/followers", "", nil, &followers)
+	if len(followers) != 2 || followers[0].UserID != alice.ID || followers[1].UserID != carol.ID {
+		t.Fatalf("followers: got %+v", followers)
+	}
+	var following []FollowEntry
+	doJSON(t, "GET", srv.URL+"/api/users/"+alice.ID.String()+"/following?limit=1", "", nil, &following)
+	if len(following) != 1 || following[0].UserID != bob.ID {
+		t.Fatalf("following: got %+v", following)
+	}
+
+	var timeline []chirpJSON
+	if code := doJSON(t, "GET", srv.URL+"/api/timeline", "Bearer "+alice.Token, nil, &timeline); code != http.StatusOK {
+		t.Fatalf("timeline: status %d", code)
+	}
+	if got, want := bodies(timeline), []string{"bob 2", "bob 1"}; !slices.Equal(got, want) {
+		t.Fatalf("timeline: got %v, want %v", got, want)
+	}
+
+	if code := doJSON(t, "DELETE", srv.URL+"/api/users/"+bob.ID.String()+"/follow", "Bearer "+alice.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("unfollow: status %d", code)
+	}
+	doJSON(t, "GET", srv.URL+"/api/timeline", "Bearer "+alice.Token, nil, &timeline)
+	if len(timeline) != 0 {
+		t.Fatalf("timeline after unfollow: got %v", bodies(timeline))
+	}
+
+	if code := doJSON(t, "GET", srv.URL+"/api/timeline", "", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("timeline without token: expected 401, got %d", code)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+// HandleLikeChirp serves POST /api/chirps/{id}/likes. Liking a chirp twice
+// is a no-op.
+func (cfg *ApiConfig) HandleLikeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+	chirp, err := cfg.DB.GetChirpsByID(r.Context(), chirpID)
+	if err != nil || chirp.TombstonedAt.Valid {
+		RespondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	err = cfg.DB.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		log.Printf("error liking chirp: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not like chirp")
+		return
+	}
+	cfg.notify(r.Context(), chirp.UserID, userID, notifyLike, uuid.NullUUID{UUID: chirpID, Valid: true})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleUnlikeChirp serves DELETE /api/chirps/{id}/likes.
+func (cfg *ApiConfig) HandleUnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	err = cfg.DB.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		log.Printf("error unliking chirp: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not unlike chirp")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+type likedChirpJSON struct {
+	LikeCount int64 `json:"like_count"`
+	LikedByMe *bool `json:"liked_by_me"`
+}
+
+func TestLikes(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+	chirp := postChirp(t, srv, alice.Token, "like me")
+	likesURL := srv.URL + "/api/chirps/" + chirp.ID + "/likes"
+
+	// liking twice counts once
+	for range 2 {
+		if code := doJSON(t, "POST", likesURL, "Bearer "+bob.Token, nil, nil); code != http.StatusNoContent {
+			t.Fatalf("like: status %d", code)
+		}
+	}
+	if code := doJSON(t, "POST", likesURL, "Bearer "+alice.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("like: status %d", code)
+	}
+
+	var got likedChirpJSON
+	doJSON(t, "GET", srv.URL+"/api/chirps/"+chirp.ID, "", nil, &got)
+	if got.LikeCount != 2 || got.LikedByMe != nil {
+		t.Fatalf("anonymous view: got count %d, liked_by_me %v", got.LikeCount, got.LikedByMe)
+	}
+
+	if code := doJSON(t, "DELETE", likesURL, "Bearer "+bob.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("unlike: status %d", code)
+	}
+	var list []likedChirpJSON
+	doJSON(t, "GET", srv.URL+"/api/chirps", "Bearer "+bob.Token, nil, &list)
+	if len(list) != 1 || list[0].LikeCount != 1 || list[0].LikedByMe == nil || *list[0].LikedByMe {
+		t.Fatalf("bob's view after unlike: %+v", list)
+	}
+
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps/"+bob.ID.String()+"/likes", "Bearer "+bob.Token, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("like missing chirp: expected 404, got %d", code)
+	}
+	if code := doJSON(t, "POST", likesURL, "", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("like without token: expected 401, got %d", code)
+	}
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+	"github.com/kavancamp/chirpy/internal/media"
+)
+
+// unattachedMediaRetention is how long an upload may wait to be attached
+// to a chirp before the media sweep deletes it.
+const unattachedMediaRetention = 24 * time.Hour
+
+type Media struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+}
+
+func mediaFromDB(a database.MediaAttachment) Media {
+	url := "/api/media/" + a.ID.String()
+	return Media{
+		ID:           a.ID,
+		URL:          url,
+		ThumbnailURL: url + "/thumbnail",
+		ContentType:  a.ContentType,
+		Width:        a.Width,
+		Height:       a.Height,
+	}
+}
+
+// HandleUploadMedia serves POST /api/media. It takes a multipart form with
+// the image in a "file" field and returns the stored attachment, whose ID
+// can then be passed in media_ids when creating a chirp.
+func (cfg *ApiConfig) HandleUploadMedia(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	// leave room for the multipart framing around the file itself
+	r.Body = http.MaxBytesReader(w, r.Body, media.MaxUploadBytes+64<<10)
+	file, _, err := r.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		RespondWithError(w, http.StatusRequestEntityTooLarge, "File is too large")
+		return
+	}
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Expected a multipart form with a file field")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, media.MaxUploadBytes+1))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Could not read upload")
+		return
+	}
+
+	img, err := media.Process(data)
+	switch {
+	case errors.Is(err, media.ErrTooLarge):
+		RespondWithError(w, http.StatusRequestEntityTooLarge, "Image is too large")
+		return
+	case errors.Is(err, media.ErrUnsupportedType):
+		RespondWithError(w, http.StatusUnsupportedMediaType, "Only JPEG, PNG and GIF images are supported")
+		return
+	case err != nil:
+		RespondWithError(w, http.StatusBadRequest, "Could not decode image")
+		return
+	}
+
+	id := uuid.New()
+	blobKey := "media/" + id.String() + "/original"
+	thumbKey := "media/" + id.String() + "/thumbnail"
+	if err := cfg.Blobs.Put(r.Context(), blobKey, img.ContentType, img.Data); err != nil {
+		log.Printf("error storing media %s: %s", id, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not store media")
+		return
+	}
+	if err := cfg.Blobs.Put(r.Context(), thumbKey, img.ThumbnailType, img.Thumbnail); err != nil {
+		log.Printf("error storing thumbnail %s: %s", id, err)
+		cfg.deleteBlobs(r.Context(), blobKey)
+		RespondWithError(w, http.StatusInternalServerError, "Could not store media")
+		return
+	}
+
+	attachment, err := cfg.DB.CreateMediaAttachment(r.Context(), database.CreateMediaAttachmentParams{
+		ID:                   id,
+		UserID:               userID,
+		ContentType:          img.ContentType,
+		SizeBytes:            int64(len(img.Data)),
+		Width:                int32(img.Width),
+		Height:               int32(img.Height),
+		BlobKey:              blobKey,
+		ThumbnailKey:         thumbKey,
+		ThumbnailContentType: img.ThumbnailType,
+		CreatedAt:            time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("error saving media %s: %s", id, err)
+		cfg.deleteBlobs(r.Context(), blobKey, thumbKey)
+		RespondWithError(w, http.StatusInternalServerError, "Could not store media")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, mediaFromDB(attachment))
+}
+
+// HandleGetMedia serves GET /api/media/{id}, the processed image.
+func (cfg *ApiConfig) HandleGetMedia(w http.ResponseWriter, r *http.Request) {
+	cfg.serveMedia(w, r, func(a database.MediaAttachment) (string, string) {
+		return a.BlobKey, a.ContentType
+	})
+}
+
+// HandleGetMediaThumbnail serves GET /api/media/{id}/thumbnail.
+func (cfg *ApiConfig) HandleGetMediaThumbnail(w http.ResponseWriter, r *http.Request) {
+	cfg.serveMedia(w, r, func(a database.MediaAttachment) (string, string) {
+		return a.ThumbnailKey, a.ThumbnailContentType
+	})
+}
+
+// serveMedia streams one blob of the attachment in the path. Blobs never
+// change once written, so they can be cached indefinitely.
+func (cfg *ApiConfig) serveMedia(w http.ResponseWriter, r *http.Request, pick func(database.MediaAttachment) (key, contentType string)) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid media ID")
+		return
+	}
+	attachment, err := cfg.DB.GetMediaAttachment(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "Media not found")
+		return
+	}
+	// attachments of deleted chirps stay stored until the purge but are
+	// not served
+	if attachment.ChirpID.Valid {
+		if _, err := cfg.DB.GetChirpsByID(r.Context(), attachment.ChirpID.UUID); err != nil {
+			RespondWithError(w, http.StatusNotFound, "Media not found")
+			return
+		}
+	}
+
+	key, contentType := pick(attachment)
+	blob, err := cfg.Blobs.Get(r.Context(), key)
+	if errors.Is(err, media.ErrNotFound) {
+		RespondWithError(w, http.StatusNotFound, "Media not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error reading media %s: %s", key, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve media")
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if key == attachment.BlobKey {
+		w.Header().Set("Content-Length", strconv.FormatInt(attachment.SizeBytes, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, blob); err != nil {
+		log.Printf("error streaming media %s: %s", key, err)
+	}
+}
+
+// checkChirpMedia verifies that ids name at most max distinct, unattached
+// uploads owned by userID, returning a client-facing message when they do not.
+func (cfg *ApiConfig) checkChirpMedia(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, max int) (string, error) {
+	if len(ids) > max {
+		return fmt.Sprintf("A chirp can have at most %d attachments", max), nil
+	}
+	attachments, err := cfg.DB.GetMediaAttachmentsByIDs(ctx, ids)
+	if err != nil {
+		return "", err
+	}
+	if len(attachments) != len(ids) {
+		return "Invalid media ID", nil
+	}
+	for _, a := range attachments {
+		if a.UserID != userID || a.ChirpID.Valid {
+			return "Invalid media ID", nil
+		}
+	}
+	return "", nil
+}
+
+// attachChirpMedia attaches uploads to a new chirp in the order given.
+func (cfg *ApiConfig) attachChirpMedia(ctx context.Context, chirp database.Chirp, ids []uuid.UUID) error {
+	for i, id := range ids {
+		n, err := cfg.DB.AttachMedia(ctx, database.AttachMediaParams{
+			ChirpID:  uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			Position: int32(i),
+			ID:       id,
+			UserID:   chirp.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New("media " + id.String() + " was attached elsewhere")
+		}
+	}
+	return nil
+}
+
+// deleteChirpMedia removes a chirp's attachments and their blobs. Blob
+// failures are only logged since the rows are already gone.
+func (cfg *ApiConfig) deleteChirpMedia(ctx context.Context, chirpID uuid.UUID) error {
+	attachments, err := cfg.DB.DeleteChirpMedia(ctx, uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		cfg.deleteBlobs(ctx, a.BlobKey, a.ThumbnailKey)
+	}
+	return nil
+}
+
+// PurgeUnattachedMedia deletes uploads created before the given time that
+// were never attached to a chirp, with their blobs, returning how many it
+// deleted.
+func (cfg *ApiConfig) PurgeUnattachedMedia(ctx context.Context, before time.Time) (int, error) {
+	attachments, err := cfg.DB.DeleteUnattachedMedia(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+	for _, a := range attachments {
+		cfg.deleteBlobs(ctx, a.BlobKey, a.ThumbnailKey)
+	}
+	return len(attachments), nil
+}
+
+// RunMediaSweep purges uploads left unattached for longer than
+// unattachedMediaRetention every interval until ctx is done.
+func (cfg *ApiConfig) RunMediaSweep(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func(ctx context.Context) {
+		n, err := cfg.PurgeUnattachedMedia(ctx, time.Now().UTC().Add(-unattachedMediaRetention))
+		if err != nil {
+			log.Printf("error purging unattached media: %s", err)
+		} else if n > 0 {
+			log.Printf("purged %d unattached uploads", n)
+		}
+	})
+}
+
+func (cfg *ApiConfig) deleteBlobs(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := cfg.Blobs.Delete(ctx, key); err != nil {
+			log.Printf("error deleting blob %s: %s", key, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kavancamp/chirpy/internal/media"
+)
+
+// upload posts data as the "file" field of a multipart form.
+func upload(t *testing.T, srv *httptest.Server, token string, data []byte, out interface{}) int {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+
+	req, err := http.NewRequest("POST", srv.URL+"/api/media", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+func TestMediaAttachments(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 640, 480))); err != nil {
+		t.Fatal(err)
+	}
+	var m Media
+	if code := upload(t, srv, alice.Token, img.Bytes(), &m); code != http.StatusCreated {
+		t.Fatalf("upload: status %d", code)
+	}
+	if m.ContentType != "image/png" || m.Width != 640 || m.Height != 480 {
+		t.Fatalf("upload: got %+v", m)
+	}
+	if code := upload(t, srv, alice.Token, []byte("just text"), nil); code != http.StatusUnsupportedMediaType {
+		t.Fatalf("text upload: expected 415, got %d", code)
+	}
+
+	resp, err := http.Get(srv.URL + m.ThumbnailURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, err := png.DecodeConfig(resp.Body)
+	resp.Body.Close()
+	if err != nil || thumb.Width != 320 || thumb.Height != 240 {
+		t.Fatalf("thumbnail: %v %dx%d", err, thumb.Width, thumb.Height)
+	}
+
+	// only the uploader can attach it, and only once
+	input := map[string]interface{}{"body": "look", "media_ids": []string{m.ID.String()}}
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+bob.Token, input, nil); code != http.StatusBadRequest {
+		t.Fatalf("attach someone else's media: expected 400, got %d", code)
+	}
+	var c struct {
+		ID    string  `json:"id"`
+		Media []Media `json:"media"`
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+alice.Token, input, &c); code != http.StatusCreated {
+		t.Fatalf("create chirp with media: status %d", code)
+	}
+	if len(c.Media) != 1 || c.Media[0].ID != m.ID {
+		t.Fatalf("chirp media: got %+v", c.Media)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+alice.Token, input, nil); code != http.StatusBadRequest {
+		t.Fatalf("reuse media: expected 400, got %d", code)
+	}
+
+	if code := doJSON(t, "DELETE", srv.URL+"/api/chirps/"+c.ID, "Bearer "+alice.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("delete chirp: status %d", code)
+	}
+	resp, err = http.Get(srv.URL + m.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("media after chirp deleted: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestPurgeUnattachedMedia(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	var attached, stray Media
+	for _, m := range []*Media{&attached, &stray} {
+		if code := upload(t, srv, alice.Token, img.Bytes(), m); code != http.StatusCreated {
+			t.Fatalf("upload: status %d", code)
+		}
+	}
+	input := map[string]interface{}{"body": "look", "media_ids": []string{attached.ID.String()}}
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+alice.Token, input, nil); code != http.StatusCreated {
+		t.Fatalf("create chirp with media: status %d", code)
+	}
+
+	ctx := context.Background()
+	if n, err := cfg.PurgeUnattachedMedia(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("purge before any upload: %d, %v", n, err)
+	}
+	n, err := cfg.PurgeUnattachedMedia(ctx, time.Now().Add(time.Minute))
+	if err != nil || n != 1 {
+		t.Fatalf("purged %d uploads, want 1: %v", n, err)
+	}
+	blobGone := func(m Media) bool {
+		blob, err := cfg.Blobs.Get(ctx, "media/"+m.ID.String()+"/original")
+		if err == nil {
+			blob.Close()
+		}
+		return errors.Is(err, media.ErrNotFound)
+	}
+	if !blobGone(stray) {
+		t.Error("the unattached upload's blob was kept")
+	}
+	if blobGone(attached) {
+		t.Error("the attached upload's blob was deleted")
+	}
+
+	// a reset takes every user's media with it, blobs included
+	if code := doJSON(t, "POST", srv.URL+"/admin/reset", "", nil, nil); code != http.StatusOK {
+		t.Fatalf("reset: status %d", code)
+	}
+	if !blobGone(attached) {
+		t.Error("reset kept the attached upload's blob")
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+const (
+	notifyMention = "mention"
+	notifyReply   = "reply"
+	notifyLike    = "like"
+	notifyFollow  = "follow"
+)
+
+type Notification struct {
+	ID        uuid.UUID     `json:"id"`
+	Kind      string        `json:"kind"`
+	ActorID   uuid.UUID     `json:"actor_id"`
+	ChirpID   uuid.NullUUID `json:"chirp_id"`
+	CreatedAt time.Time     `json:"created_at"`
+	ReadAt    *time.Time    `json:"read_at"`
+}
+
+type NotificationList struct {
+	UnreadCount   int64          `json:"unread_count"`
+	Notifications []Notification `json:"notifications"`
+}
+
+func notificationFromDB(n database.Notification) Notification {
+	out := Notification{
+		ID:        n.ID,
+		Kind:      n.Kind,
+		ActorID:   n.ActorID,
+		ChirpID:   n.ChirpID,
+		CreatedAt: n.CreatedAt,
+	}
+	if n.ReadAt.Valid {
+		out.ReadAt = &n.ReadAt.Time
+	}
+	return out
+}
+
+// notify records a notification for recipient about something actor did.
+// Nobody is notified about their own actions. Notifications are a side
+// effect of the request, so failures are logged rather than returned.
+func (cfg *ApiConfig) notify(ctx context.Context, recipient, actor uuid.UUID, kind string, chirpID uuid.NullUUID) {
+	if recipient == actor {
+		return
+	}
+	err := cfg.DB.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:  recipient,
+		ActorID: actor,
+		Kind:    kind,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		log.Printf("error creating %s notification for %s: %s", kind, recipient, err)
+	}
+}
+
+// notifyChirp notifies the author of the chirp being replied to, if any,
+// and every user @mentioned in the body. A parent author who is also
+// mentioned only gets the reply notification.
+func (cfg *ApiConfig) notifyChirp(ctx context.Context, chirp database.Chirp, parentAuthor uuid.NullUUID) {
+	chirpID := uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	if parentAuthor.Valid {
+		cfg.notify(ctx, parentAuthor.UUID, chirp.UserID, notifyReply, chirpID)
+	}
+
+	names := ExtractMentions(chirp.Body)
+	if len(names) == 0 {
+		return
+	}
+	users, err := cfg.DB.GetUsersByUsernames(ctx, names)
+	if err != nil {
+		log.Printf("error resolving mentions in chirp %s: %s", chirp.ID, err)
+		return
+	}
+	for _, u := range users {
+		if parentAuthor.Valid && u.ID == parentAuthor.UUID {
+			continue
+		}
+		cfg.notify(ctx, u.ID, chirp.UserID, notifyMention, chirpID)
+	}
+}
+
+// HandleGetNotifications serves GET /api/notifications: the caller's
+// notifications, newest first unless sort=asc, and only unread ones when
+// unread=true. The unread count always covers every unread notification.
+func (cfg *ApiConfig) HandleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		return
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	key := func(n database.Notification) pageCursor {
+		return pageCursor{CreatedAt: n.CreatedAt, ID: n.ID}
+	}
+	desc := r.URL.Query().Get("sort") != "asc"
+	notifications, links, err := paginate(page, desc, key, func(asc bool, c *pageCursor, n int32) ([]database.Notification, error) {
+		createdAt, id := cursorArgs(c)
+		if asc {
+			return cfg.DB.ListNotificationsAfter(r.Context(), database.ListNotificationsAfterParams{
+				UserID:          userID,
+				UnreadOnly:      unreadOnly,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				PageSize:        n,
+			})
+		}
+		return cfg.DB.ListNotificationsBefore(r.Context(), database.ListNotificationsBeforeParams{
+			UserID:          userID,
+			UnreadOnly:      unreadOnly,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageSize:        n,
+		})
+	})
+	if err != nil {
+		log.Printf("error listing notifications: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve notifications")
+		return
+	}
+
+	unread, err := cfg.DB.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		log.Printf("error counting notifications: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve notifications")
+		return
+	}
+
+	list := NotificationList{
+		UnreadCount:   unread,
+		Notifications: make([]Notification, 0, len(notifications)),
+	}
+	for _, n := range notifications {
+		list.Notifications = append(list.Notifications, notificationFromDB(n))
+	}
+
+	setLinkHeader(w, r, links)
+	RespondWithJSON(w, http.StatusOK, list)
+}
+
+// HandleMarkNotificationsRead serves POST /api/notifications/read. The body
+// names the notifications to mark with {"ids": [...]} or marks them all with
+// {"all": true}. Other users' notification IDs are ignored.
+func (cfg *ApiConfig) HandleMarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		IDs []uuid.UUID `json:"ids"`
+		All bool        `json:"all"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	var err error
+	switch {
+	case input.All:
+		err = cfg.DB.MarkAllNotificationsRead(r.Context(), userID)
+	case len(input.IDs) > 0:
+		err = cfg.DB.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+			UserID: userID,
+			Ids:    input.IDs,
+		})
+	default:
+		RespondWithError(w, http.StatusBadRequest, "Provide ids or all")
+		return
+	}
+	if err != nil {
+		log.Printf("error marking notifications read: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not update notifications")
+		return
+	}
+
+	unread, err := cfg.DB.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		log.Printf("error counting notifications: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not update notifications")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]int64{"unread_count": unread})
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// setUsername gives a signed-up user a username through PUT /api/users.
+func setUsername(t *testing.T, srv *httptest.Server, user loginResponse, username string) {
+	t.Helper()
+	input := map[string]string{"email": user.Email, "password": "hunter2", "username": username}
+	if code := doJSON(t, "PUT", srv.URL+"/api/users", "Bearer "+user.Token, input, nil); code != http.StatusOK {
+		t.Fatalf("set username %q: status %d", username, code)
+	}
+}
+
+func TestExtractMentions(t *testing.T) {
+	got := ExtractMentions("hi @Bob and @bob, cc @carol_2. mail bob@example.com @")
+	if want := []string{"bob", "carol_2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ExtractMentions = %q, want %q", got, want)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+	setUsername(t, srv, alice, "Alice")
+	setUsername(t, srv, bob, "@bob")
+
+	creds := map[string]string{"email": "carol@example.com", "password": "hunter2", "username": "bob"}
+	if code := doJSON(t, "POST", srv.URL+"/api/users", "", creds, nil); code != http.StatusConflict {
+		t.Fatalf("duplicate username: expected 409, got %d", code)
+	}
+
+	chirp := postChirp(t, srv, alice.Token, "hello @bob and @nobody and @alice")
+	postReply(t, srv, bob.Token, chirp.ID, "hi @alice")
+	doJSON(t, "POST", srv.URL+"/api/chirps/"+chirp.ID+"/likes", "Bearer "+bob.Token, nil, nil)
+	doJSON(t, "DELETE", srv.URL+"/api/chirps/"+chirp.ID+"/likes", "Bearer "+bob.Token, nil, nil)
+	doJSON(t, "POST", srv.URL+"/api/chirps/"+chirp.ID+"/likes", "Bearer "+bob.Token, nil, nil)
+	doJSON(t, "POST", srv.URL+"/api/users/"+alice.ID.String()+"/follow", "Bearer "+bob.Token, nil, nil)
+
+	kinds := func(list NotificationList) []string {
+		var out []string
+		for _, n := range list.Notifications {
+			out = append(out, n.Kind)
+		}
+		return out
+	}
+
+	var aliceList NotificationList
+	if code := doJSON(t, "GET", srv.URL+"/api/notifications", "Bearer "+alice.Token, nil, &aliceList); code != http.StatusOK {
+		t.Fatalf("list: status %d", code)
+	}
+	if want := []string{"follow", "like", "reply"}; !reflect.DeepEqual(kinds(aliceList), want) || aliceList.UnreadCount != 3 {
+		t.Fatalf("alice: got %q unread %d, want %q unread 3", kinds(aliceList), aliceList.UnreadCount, want)
+	}
+	if n := aliceList.Notifications[0]; n.ActorID != bob.ID || n.ChirpID.Valid {
+		t.Fatalf("follow notification: %+v", n)
+	}
+
+	var bobList NotificationList
+	doJSON(t, "GET", srv.URL+"/api/notifications", "Bearer "+bob.Token, nil, &bobList)
+	if want := []string{"mention"}; !reflect.DeepEqual(kinds(bobList), want) || bobList.Notifications[0].ChirpID.UUID.String() != chirp.ID {
+		t.Fatalf("bob: got %+v", bobList)
+	}
+
+	// bob cannot mark alice's notifications
+	ids := map[string]interface{}{"ids": []string{aliceList.Notifications[0].ID.String()}}
+	doJSON(t, "POST", srv.URL+"/api/notifications/read", "Bearer "+bob.Token, ids, nil)
+
+	var marked map[string]int64
+	if code := doJSON(t, "POST", srv.URL+"/api/notifications/read", "Bearer "+alice.Token, ids, &marked); code != http.StatusOK || marked["unread_count"] != 2 {
+		t.Fatalf("mark read: status %d, %v", code, marked)
+	}
+	var unread NotificationList
+	doJSON(t, "GET", srv.URL+"/api/notifications?unread=true", "Bearer "+alice.Token, nil, &unread)
+	if want := []string{"like", "reply"}; !reflect.DeepEqual(kinds(unread), want) || unread.UnreadCount != 2 {
+		t.Fatalf("unread only: got %q unread %d", kinds(unread), unread.UnreadCount)
+	}
+
+	all := map[string]bool{"all": true}
+	if code := doJSON(t, "POST", srv.URL+"/api/notifications/read", "Bearer "+alice.Token, all, &marked); code != http.StatusOK || marked["unread_count"] != 0 {
+		t.Fatalf("mark all read: status %d, %v", code, marked)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/notifications/read", "Bearer "+alice.Token, map[string]string{}, nil); code != http.StatusBadRequest {
+		t.Fatalf("empty mark request: expected 400, got %d", code)
+	}
+	if code := doJSON(t, "GET", srv.URL+"/api/notifications", "", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("no token: expected 401, got %d", code)
+	}
+}
@@ -1,41 +1,156 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/kavancamp/chirpy/internal/auth"
+	"github.com/kavancamp/chirpy/internal/database"
+	"github.com/kavancamp/chirpy/internal/payments"
 )
-func (cfg *ApiConfig) HandlePolkaWebhook(w http.ResponseWriter, r *http.Request) {
-	apiKey, err := auth.GetAPIKey(r.Header) 
-	if err != nil || apiKey != cfg.PolkaKey {
-		RespondWithError(w, http.StatusUnauthorized, "Invalid API Key")
-	} 
 
-	type webhookRequest struct {
-		Event string `json:"event"`
-		Data struct {
-			UserID uuid.UUID `json:"user_id"`
-		} `json:"data"`
-	}
+// maxWebhookBytes bounds the size of a webhook body we will read.
+const maxWebhookBytes = 64 << 10
 
-	var req webhookRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+// webhookClaimTimeout is how long a delivery may spend applying an event
+// before a redelivery takes it to have died and applies the event itself.
+const webhookClaimTimeout = 5 * time.Minute
+
+// HandlePolkaWebhook serves POST /api/polka/webhooks. Deliveries are
+// verified and decoded by the Payments provider. Every accepted delivery is
+// stored, and an event ID that was already processed is acknowledged
+// without being applied again. A delivery first claims the event, so
+// concurrent deliveries of one event cannot both apply it.
+func (cfg *ApiConfig) HandlePolkaWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBytes))
+	if err != nil {
 		RespondWithError(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
+	if err := cfg.Payments.VerifyWebhook(r.Header, body, time.Now()); err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "Invalid API Key or signature")
+		return
+	}
 
-	if req.Event != "user.upgraded" {
-		w.WriteHeader(http.StatusNoContent) // We don't care about other events
+	req, err := cfg.Payments.ParseEvent(body)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
+	// deliveries without an ID cannot be deduplicated
+	eventID := req.ID
+	if eventID == "" {
+		eventID = uuid.NewString()
+	}
 
-	err = cfg.DB.UpgradeUserToChirpyRed(r.Context(), req.Data.UserID)
+	event, err := cfg.DB.CreateWebhookEvent(r.Context(), database.CreateWebhookEventParams{
+		EventID: eventID,
+		Event:   req.Type,
+		Payload: body,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// a redelivery; only apply it if the first attempt failed
+		now := time.Now().UTC()
+		event, err = cfg.DB.ClaimWebhookEvent(r.Context(), database.ClaimWebhookEventParams{
+			ProcessingStartedAt: sql.NullTime{Time: now, Valid: true},
+			EventID:             eventID,
+			StaleBefore:         sql.NullTime{Time: now.Add(-webhookClaimTimeout), Valid: true},
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			if existing, err := cfg.DB.GetWebhookEventByEventID(r.Context(), eventID); err == nil && existing.ProcessedAt.Valid {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			// another delivery is applying it; have the provider retry in
+			// case that attempt fails
+			RespondWithError(w, http.StatusConflict, "Event is already being processed")
+			return
+		}
+	}
 	if err != nil {
-		RespondWithError(w, http.StatusNotFound, "User not found")
+		log.Printf("error storing %s webhook event %s: %s", cfg.Payments.Name(), eventID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not process event")
 		return
 	}
 
+	if _, err := cfg.processWebhookEvent(r.Context(), event); err != nil {
+		respondWebhookError(w, err)
+		return
+	}
 	w.WriteHeader(http.StatusNoContent) // 204 No Content
 }
+
+// processWebhookEvent applies a stored event and records the outcome on
+// it, returning the updated event.
+func (cfg *ApiConfig) processWebhookEvent(ctx context.Context, event database.WebhookEvent) (database.WebhookEvent, error) {
+	req, err := cfg.Payments.ParseEvent(event.Payload)
+	if err == nil {
+		err = cfg.applyPaymentEvent(ctx, req)
+	}
+	if err != nil {
+		event.LastError = sql.NullString{String: err.Error(), Valid: true}
+		if markErr := cfg.DB.MarkWebhookEventFailed(ctx, database.MarkWebhookEventFailedParams{
+			ID:        event.ID,
+			LastError: event.LastError,
+		}); markErr != nil {
+			log.Printf("error recording failure of webhook event %s: %s", event.EventID, markErr)
+		}
+		return event, err
+	}
+
+	event.ProcessedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	event.LastError = sql.NullString{}
+	err = cfg.DB.MarkWebhookEventProcessed(ctx, database.MarkWebhookEventProcessedParams{
+		ID:          event.ID,
+		ProcessedAt: event.ProcessedAt,
+	})
+	return event, err
+}
+
+// applyPaymentEvent makes the membership change an event asks for. Events
+// that do not affect memberships are accepted and ignored.
+func (cfg *ApiConfig) applyPaymentEvent(ctx context.Context, req payments.Event) error {
+	if req.Transition == payments.TransitionNone {
+		return nil
+	}
+	if _, err := cfg.DB.GetUserByID(ctx, req.UserID); err != nil {
+		return err
+	}
+
+	var endsAt sql.NullTime
+	if req.EndsAt != nil {
+		endsAt = sql.NullTime{Time: req.EndsAt.UTC(), Valid: true}
+	}
+	switch req.Transition {
+	case payments.TransitionStart:
+		return cfg.startMembership(ctx, req.UserID, endsAt, req.Type)
+	case payments.TransitionDowngrade:
+		return cfg.endMembership(ctx, req.UserID, membershipDowngraded, time.Now(), req.Type)
+	case payments.TransitionCancel:
+		// a cancelled subscription runs to the end of the paid period
+		at := time.Now()
+		if endsAt.Valid {
+			at = endsAt.Time
+		}
+		return cfg.endMembership(ctx, req.UserID, membershipCancelled, at, req.Type)
+	default:
+		return fmt.Errorf("unknown membership transition %q", req.Transition)
+	}
+}
+
+// respondWebhookError reports an event that could not be applied.
+// Providers retry anything other than a 2xx.
+func respondWebhookError(w http.ResponseWriter, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	log.Printf("error processing webhook event: %s", err)
+	RespondWithError(w, http.StatusInternalServerError, "Could not process event")
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/auth"
+	"github.com/kavancamp/chirpy/internal/database"
+	"github.com/kavancamp/chirpy/internal/payments"
+)
+
+const testAdminKey = "test-admin-key"
+
+// postWebhook delivers a raw Polka webhook body with the given headers.
+func postWebhook(t *testing.T, srv *httptest.Server, headers map[string]string, body string) int {
+	t.Helper()
+	req, err := http.NewRequest("POST", srv.URL+"/api/polka/webhooks", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post webhook: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func upgradeBody(eventID string, userID uuid.UUID) string {
+	return `{"id":"` + eventID + `","event":"user.upgraded","data":{"user_id":"` + userID.String() + `"}}`
+}
+
+func isChirpyRed(t *testing.T, cfg *ApiConfig, userID uuid.UUID) bool {
+	t.Helper()
+	isRed, err := cfg.isChirpyRed(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return isRed
+}
+
+func TestPolkaWebhook(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	cfg.AdminKey = testAdminKey
+	alice := signup(t, srv, "alice@example.com")
+	apiKey := map[string]string{"Authorization": "ApiKey " + testPolkaKey}
+
+	for name, headers := range map[string]map[string]string{
+		"missing key": nil,
+		"wrong key":   {"Authorization": "ApiKey nope"},
+	} {
+		if code := postWebhook(t, srv, headers, upgradeBody("evt_1", alice.ID)); code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected 401, got %d", name, code)
+		}
+	}
+	if isChirpyRed(t, cfg, alice.ID) {
+		t.Fatal("unauthenticated webhook upgraded the user")
+	}
+
+	if code := postWebhook(t, srv, apiKey, upgradeBody("evt_1", alice.ID)); code != http.StatusNoContent {
+		t.Fatalf("upgrade: status %d", code)
+	}
+	if !isChirpyRed(t, cfg, alice.ID) {
+		t.Fatal("user was not upgraded")
+	}
+	// a redelivery is acknowledged but stored once
+	if code := postWebhook(t, srv, apiKey, upgradeBody("evt_1", alice.ID)); code != http.StatusNoContent {
+		t.Fatalf("redelivery: status %d", code)
+	}
+	if code := postWebhook(t, srv, apiKey, upgradeBody("evt_2", uuid.New())); code != http.StatusNotFound {
+		t.Fatalf("unknown user: expected 404, got %d", code)
+	}
+
+	var events []WebhookEvent
+	if code := doJSON(t, "GET", srv.URL+"/admin/webhooks", "ApiKey "+testAdminKey, nil, &events); code != http.StatusOK {
+		t.Fatalf("list events: status %d", code)
+	}
+	if len(events) != 2 || events[0].EventID != "evt_2" || events[1].EventID != "evt_1" {
+		t.Fatalf("events: %+v", events)
+	}
+	if events[1].ProcessedAt == nil || events[0].ProcessedAt != nil || events[0].LastError == "" {
+		t.Fatalf("event outcomes: %+v", events)
+	}
+
+	doJSON(t, "GET", srv.URL+"/admin/webhooks?pending=true", "ApiKey "+testAdminKey, nil, &events)
+	if len(events) != 1 || events[0].EventID != "evt_2" {
+		t.Fatalf("pending events: %+v", events)
+	}
+	var replayed WebhookEvent
+	replayURL := srv.URL + "/admin/webhooks/" + events[0].ID.String() + "/replay"
+	if code := doJSON(t, "POST", replayURL, "ApiKey "+testAdminKey, nil, &replayed); code != http.StatusOK {
+		t.Fatalf("replay: status %d", code)
+	}
+	if replayed.ProcessedAt != nil || replayed.LastError == "" {
+		t.Fatalf("replay of failing event: %+v", replayed)
+	}
+
+	for _, authz := range []string{"", "ApiKey " + testPolkaKey} {
+		if code := doJSON(t, "GET", srv.URL+"/admin/webhooks", authz, nil, nil); code != http.StatusUnauthorized {
+			t.Fatalf("list events with %q: expected 401, got %d", authz, code)
+		}
+		if code := doJSON(t, "POST", replayURL, authz, nil, nil); code != http.StatusUnauthorized {
+			t.Fatalf("replay with %q: expected 401, got %d", authz, code)
+		}
+	}
+}
+
+func TestPolkaWebhook_InFlightRedelivery(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	cfg.AdminKey = testAdminKey
+	alice := signup(t, srv, "alice@example.com")
+	apiKey := map[string]string{"Authorization": "ApiKey " + testPolkaKey}
+
+	// a first delivery that has stored and claimed the event but is still
+	// applying it
+	body := upgradeBody("evt_1", alice.ID)
+	event, err := cfg.DB.CreateWebhookEvent(context.Background(), database.CreateWebhookEventParams{
+		EventID: "evt_1",
+		Event:   "user.upgraded",
+		Payload: []byte(body),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := postWebhook(t, srv, apiKey, body); code != http.StatusConflict {
+		t.Fatalf("redelivery while in flight: expected 409, got %d", code)
+	}
+	if isChirpyRed(t, cfg, alice.ID) {
+		t.Fatal("redelivery applied an event another delivery had claimed")
+	}
+	replayURL := srv.URL + "/admin/webhooks/" + event.ID.String() + "/replay"
+	if code := doJSON(t, "POST", replayURL, "ApiKey "+testAdminKey, nil, nil); code != http.StatusConflict {
+		t.Fatalf("replay while in flight: expected 409, got %d", code)
+	}
+
+	// once the first attempt fails, a redelivery applies the event
+	err = cfg.DB.MarkWebhookEventFailed(context.Background(), database.MarkWebhookEventFailedParams{
+		ID:        event.ID,
+		LastError: sql.NullString{String: "timeout", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := postWebhook(t, srv, apiKey, body); code != http.StatusNoContent {
+		t.Fatalf("redelivery after failure: status %d", code)
+	}
+	if !isChirpyRed(t, cfg, alice.ID) {
+		t.Fatal("user was not upgraded")
+	}
+	if code := postWebhook(t, srv, apiKey, body); code != http.StatusNoContent {
+		t.Fatalf("redelivery after success: status %d", code)
+	}
+	// an applied event can still be replayed
+	if code := doJSON(t, "POST", replayURL, "ApiKey "+testAdminKey, nil, nil); code != http.StatusOK {
+		t.Fatalf("replay after success: status %d", code)
+	}
+}
+
+func TestPolkaWebhook_Signature(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	const secret = "test-signing-secret"
+	cfg.Payments = &payments.Polka{APIKey: testPolkaKey, WebhookSecret: secret}
+	alice := signup(t, srv, "alice@example.com")
+	body := upgradeBody("evt_1", alice.ID)
+
+	signed := func(at time.Time, body string) map[string]string {
+		return map[string]string{
+			"Authorization":     "ApiKey " + testPolkaKey,
+			"X-Polka-Timestamp": strconv.FormatInt(at.Unix(), 10),
+			"X-Polka-Signature": auth.SignWebhook(secret, at.Unix(), []byte(body)),
+		}
+	}
+
+	if code := postWebhook(t, srv, map[string]string{"Authorization": "ApiKey " + testPolkaKey}, body); code != http.StatusUnauthorized {
+		t.Fatalf("unsigned: expected 401, got %d", code)
+	}
+	stale := time.Now().Add(-auth.WebhookTolerance - time.Minute)
+	if code := postWebhook(t, srv, signed(stale, body), body); code != http.StatusUnauthorized {
+		t.Fatalf("stale signature: expected 401, got %d", code)
+	}
+	other := upgradeBody("evt_1", uuid.New())
+	if code := postWebhook(t, srv, signed(time.Now(), other), body); code != http.StatusUnauthorized {
+		t.Fatalf("signature for another body: expected 401, got %d", code)
+	}
+	if isChirpyRed(t, cfg, alice.ID) {
+		t.Fatal("rejected webhook upgraded the user")
+	}
+
+	if code := postWebhook(t, srv, signed(time.Now(), body), body); code != http.StatusNoContent {
+		t.Fatalf("signed: status %d", code)
+	}
+	if !isChirpyRed(t, cfg, alice.ID) {
+		t.Fatal("user was not upgraded")
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+// HandleRechirp serves POST /api/chirps/{id}/rechirps, resharing a chirp
+// without commentary. Rechirping a rechirp reshares the original. Quote
+// chirps are created through HandleCreateChirp with quote_of.
+func (cfg *ApiConfig) HandleRechirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+	original, err := cfg.DB.GetChirpsByID(r.Context(), chirpID)
+	if err != nil || original.TombstonedAt.Valid {
+		RespondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+	rechirpOf := uuid.NullUUID{UUID: original.ID, Valid: true}
+	if original.RechirpOf.Valid {
+		rechirpOf = original.RechirpOf
+	}
+
+	now := time.Now().UTC()
+	id := uuid.New()
+	dbChirp, err := cfg.DB.CreateChirp(r.Context(), database.CreateChirpParams{
+		ID:             id,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		UserID:         userID,
+		ConversationID: id,
+		RechirpOf:      rechirpOf,
+	})
+	if database.IsUniqueViolation(err) {
+		RespondWithError(w, http.StatusConflict, "Chirp already rechirped")
+		return
+	}
+	if err != nil {
+		log.Printf("error creating rechirp: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not rechirp")
+		return
+	}
+
+	chirp, err := cfg.renderChirp(r.Context(), dbChirp, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		log.Printf("error rendering chirp: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not rechirp")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, chirp)
+}
+
+// HandleUndoRechirp serves DELETE /api/chirps/{id}/rechirps, removing the
+// caller's rechirp of chirp {id}. A rechirp or quote can also be removed by
+// its own ID through HandleDeleteChirp.
+func (cfg *ApiConfig) HandleUndoRechirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+	rechirp, err := cfg.DB.GetRechirp(r.Context(), database.GetRechirpParams{
+		UserID:    userID,
+		RechirpOf: uuid.NullUUID{UUID: chirpID, Valid: true},
+	})
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "Rechirp not found")
+		return
+	}
+
+	if err := cfg.DB.DeleteChirpByID(r.Context(), rechirp.ID); err != nil {
+		log.Printf("error deleting rechirp: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not undo rechirp")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+type rechirpJSON struct {
+	ID            string       `json:"id"`
+	Body          string       `json:"body"`
+	RechirpOf     *string      `json:"rechirp_of"`
+	QuoteOf       *string      `json:"quote_of"`
+	Original      *rechirpJSON `json:"original"`
+	RechirpCount  int64        `json:"rechirp_count"`
+	QuoteCount    int64        `json:"quote_count"`
+	RechirpedByMe *bool        `json:"rechirped_by_me"`
+}
+
+func TestRechirpsAndQuotes(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+	original := postChirp(t, srv, alice.Token, "worth sharing")
+	rechirpsURL := srv.URL + "/api/chirps/" + original.ID + "/rechirps"
+
+	var rechirp rechirpJSON
+	if code := doJSON(t, "POST", rechirpsURL, "Bearer "+bob.Token, nil, &rechirp); code != http.StatusCreated {
+		t.Fatalf("rechirp: status %d", code)
+	}
+	if rechirp.RechirpOf == nil || *rechirp.RechirpOf != original.ID || rechirp.Original == nil || rechirp.Original.Body != "worth sharing" {
+		t.Fatalf("rechirp should embed the original, got %+v", rechirp)
+	}
+	if code := doJSON(t, "POST", rechirpsURL, "Bearer "+bob.Token, nil, nil); code != http.StatusConflict {
+		t.Fatalf("second rechirp: expected 409, got %d", code)
+	}
+
+	var quote rechirpJSON
+	input := map[string]string{"body": "so true", "quote_of": rechirp.ID}
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+alice.Token, input, &quote); code != http.StatusCreated {
+		t.Fatalf("quote: status %d", code)
+	}
+	if quote.QuoteOf == nil || *quote.QuoteOf != original.ID {
+		t.Fatalf("quoting a rechirp should quote its original, got %+v", quote)
+	}
+
+	var got rechirpJSON
+	doJSON(t, "GET", srv.URL+"/api/chirps/"+original.ID, "Bearer "+bob.Token, nil, &got)
+	if got.RechirpCount != 1 || got.QuoteCount != 1 || got.RechirpedByMe == nil || !*got.RechirpedByMe {
+		t.Fatalf("counts on original: %+v", got)
+	}
+
+	// only the rechirper can remove a rechirp
+	if code := doJSON(t, "DELETE", srv.URL+"/api/chirps/"+rechirp.ID, "Bearer "+alice.Token, nil, nil); code != http.StatusForbidden {
+		t.Fatalf("delete someone else's rechirp: expected 403, got %d", code)
+	}
+	if code := doJSON(t, "DELETE", rechirpsURL, "Bearer "+bob.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("undo rechirp: status %d", code)
+	}
+	if code := doJSON(t, "DELETE", rechirpsURL, "Bearer "+bob.Token, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("undo missing rechirp: expected 404, got %d", code)
+	}
+	doJSON(t, "GET", srv.URL+"/api/chirps/"+original.ID, "", nil, &got)
+	if got.RechirpCount != 0 {
+		t.Fatalf("rechirp count after undo: %d", got.RechirpCount)
+	}
+
+	// deleting the original leaves the quote standing on its own
+	if code := doJSON(t, "DELETE", srv.URL+"/api/chirps/"+original.ID, "Bearer "+alice.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("delete original: status %d", code)
+	}
+	doJSON(t, "GET", srv.URL+"/api/chirps/"+quote.ID, "", nil, &got)
+	if got.QuoteOf != nil || got.Original != nil || got.Body != "so true" {
+		t.Fatalf("quote after original deleted: %+v", got)
+	}
+}
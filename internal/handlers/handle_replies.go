@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+// getThreadChirp loads a chirp for the conversation views. A deleted chirp
+// is still found, to be shown as a tombstone, while a live chirp replies
+// somewhere beneath it.
+func (cfg *ApiConfig) getThreadChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := cfg.DB.GetChirpsByID(ctx, id)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return chirp, err
+	}
+	chirp, err = cfg.DB.GetDeletedChirp(ctx, id)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	conversation, err := cfg.DB.GetConversation(ctx, chirp.ConversationID)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if !hasLiveReplies(conversation, id) {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+// hasLiveReplies reports whether a chirp in conversation that is not
+// deleted replies to id, directly or further down.
+func hasLiveReplies(conversation []database.Chirp, id uuid.UUID) bool {
+	children := make(map[uuid.UUID][]database.Chirp)
+	for _, c := range conversation {
+		if c.InReplyTo.Valid {
+			children[c.InReplyTo.UUID] = append(children[c.InReplyTo.UUID], c)
+		}
+	}
+	pending := children[id]
+	for len(pending) > 0 {
+		c := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if !c.DeletedAt.Valid && !c.TombstonedAt.Valid {
+			return true
+		}
+		pending = append(pending, children[c.ID]...)
+	}
+	return false
+}
+
+// HandleGetReplies serves GET /api/chirps/{id}/replies, the direct replies
+// to a chirp. It takes the same sort, limit and cursor parameters as
+// HandleGetChirps. A deleted chirp's replies are listed for as long as
+// HandleGetThread shows it as a tombstone.
+func (cfg *ApiConfig) HandleGetReplies(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+	_, err = cfg.getThreadChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error getting chirp %s: %s", chirpID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve replies")
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		return
+	}
+
+	parent := uuid.NullUUID{UUID: chirpID, Valid: true}
+	desc := r.URL.Query().Get("sort") == "desc"
+	replies, links, err := paginate(page, desc, chirpCursor, func(asc bool, c *pageCursor, n int32) ([]database.Chirp, error) {
+		createdAt, id := cursorArgs(c)
+		if asc {
+			return cfg.DB.ListChirpsAfter(r.Context(), database.ListChirpsAfterParams{
+				InReplyTo:       parent,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				PageSize:        n,
+			})
+		}
+		return cfg.DB.ListChirpsBefore(r.Context(), database.ListChirpsBeforeParams{
+			InReplyTo:       parent,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageSize:        n,
+		})
+	})
+	if err != nil {
+		log.Printf("error getting replies: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve replies")
+		return
+	}
+
+	chirpList, err := cfg.renderChirps(r.Context(), replies, cfg.viewerID(r))
+	if err != nil {
+		log.Printf("error rendering chirps: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve replies")
+		return
+	}
+
+	setLinkHeader(w, r, links)
+	RespondWithJSON(w, http.StatusOK, chirpList)
+}
+
+type threadNode struct {
+	Chirp
+	Replies []*threadNode `json:"replies"`
+}
+
+// prune drops deleted replies that have nothing live beneath them.
+func (n *threadNode) prune() {
+	kept := n.Replies[:0]
+	for _, reply := range n.Replies {
+		reply.prune()
+		if !reply.Deleted || len(reply.Replies) > 0 {
+			kept = append(kept, reply)
+		}
+	}
+	n.Replies = kept
+}
+
+// HandleGetThread serves GET /api/chirps/{id}/thread: the whole conversation
+// the chirp belongs to, as a tree starting at its root. Deleted chirps that
+// still have replies appear as tombstones with "deleted": true and no body.
+func (cfg *ApiConfig) HandleGetThread(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+	chirp, err := cfg.getThreadChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error getting chirp %s: %s", chirpID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve thread")
+		return
+	}
+
+	conversation, err := cfg.DB.GetConversation(r.Context(), chirp.ConversationID)
+	if err != nil {
+		log.Printf("error getting conversation: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve thread")
+		return
+	}
+	rendered, err := cfg.renderChirps(r.Context(), conversation, cfg.viewerID(r))
+	if err != nil {
+		log.Printf("error rendering chirps: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve thread")
+		return
+	}
+
+	// conversation is in created_at order, so replies come out oldest first
+	nodes := make(map[uuid.UUID]*threadNode, len(rendered))
+	for _, c := range rendered {
+		nodes[c.ID] = &threadNode{Chirp: c, Replies: []*threadNode{}}
+	}
+	for _, c := range rendered {
+		if parent, ok := nodes[c.InReplyTo.UUID]; ok && c.InReplyTo.Valid {
+			parent.Replies = append(parent.Replies, nodes[c.ID])
+		}
+	}
+
+	// walk up to the top of the tree, which is the conversation root unless
+	// that was removed along with its author's account
+	root := nodes[chirpID]
+	for root.InReplyTo.Valid {
+		parent, ok := nodes[root.InReplyTo.UUID]
+		if !ok {
+			break
+		}
+		root = parent
+	}
+	root.prune()
+
+	RespondWithJSON(w, http.StatusOK, root)
+}
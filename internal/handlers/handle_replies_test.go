@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+type threadJSON struct {
+	ID             string        `json:"id"`
+	Body           string        `json:"body"`
+	ConversationID string        `json:"conversation_id"`
+	Deleted        bool          `json:"deleted"`
+	Replies        []*threadJSON `json:"replies"`
+}
+
+func postReply(t *testing.T, srv *httptest.Server, token, parentID, body string) chirpJSON {
+	t.Helper()
+	var c chirpJSON
+	input := map[string]string{"body": body, "in_reply_to": parentID}
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+token, input, &c); code != http.StatusCreated {
+		t.Fatalf("reply %q: status %d", body, code)
+	}
+	return c
+}
+
+func TestReplyThread(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+
+	root := postChirp(t, srv, alice.Token, "root")
+	r1 := postReply(t, srv, bob.Token, root.ID, "reply one")
+	postReply(t, srv, alice.Token, root.ID, "reply two")
+	postReply(t, srv, alice.Token, r1.ID, "nested")
+
+	replies, _ := getPage(t, srv.URL+"/api/chirps/"+root.ID+"/replies")
+	if got, want := bodies(replies), []string{"reply one", "reply two"}; !slices.Equal(got, want) {
+		t.Fatalf("replies: got %v, want %v", got, want)
+	}
+
+	// deleting a chirp with replies leaves a tombstone in the thread
+	if code := doJSON(t, "DELETE", srv.URL+"/api/chirps/"+r1.ID, "Bearer "+bob.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("delete reply: status %d", code)
+	}
+	if code := doJSON(t, "GET", srv.URL+"/api/chirps/"+r1.ID, "", nil, nil); code != http.StatusNotFound {
+		t.Fatalf("get tombstone: expected 404, got %d", code)
+	}
+	// its replies are still listed, as they are in the thread
+	replies, _ = getPage(t, srv.URL+"/api/chirps/"+r1.ID+"/replies")
+	if got, want := bodies(replies), []string{"nested"}; !slices.Equal(got, want) {
+		t.Fatalf("replies to tombstone: got %v, want %v", got, want)
+	}
+
+	// any chirp in the conversation yields the whole tree
+	var thread threadJSON
+	if code := doJSON(t, "GET", srv.URL+"/api/chirps/"+r1.ID+"/thread", "", nil, &thread); code != http.StatusOK {
+		t.Fatalf("thread: status %d", code)
+	}
+	if thread.ID != root.ID || len(thread.Replies) != 2 {
+		t.Fatalf("thread root: got %+v", thread)
+	}
+	tomb := thread.Replies[0]
+	if !tomb.Deleted || tomb.Body != "" || tomb.ConversationID != root.ID {
+		t.Errorf("expected tombstone for deleted reply, got %+v", tomb)
+	}
+	if len(tomb.Replies) != 1 || tomb.Replies[0].Body != "nested" {
+		t.Errorf("expected nested reply to survive under tombstone, got %+v", tomb.Replies)
+	}
+
+	// a leaf reply is deleted outright
+	leaf := thread.Replies[1]
+	if code := doJSON(t, "DELETE", srv.URL+"/api/chirps/"+leaf.ID, "Bearer "+alice.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("delete leaf: status %d", code)
+	}
+	doJSON(t, "GET", srv.URL+"/api/chirps/"+root.ID+"/thread", "", nil, &thread)
+	if len(thread.Replies) != 1 {
+		t.Fatalf("expected leaf to be removed from thread, got %d replies", len(thread.Replies))
+	}
+	for _, view := range []string{"/thread", "/replies"} {
+		if code := doJSON(t, "GET", srv.URL+"/api/chirps/"+leaf.ID+view, "", nil, nil); code != http.StatusNotFound {
+			t.Fatalf("deleted leaf %s: expected 404, got %d", view, code)
+		}
+	}
+
+	// replying to a tombstone is refused
+	input := map[string]string{"body": "too late", "in_reply_to": r1.ID}
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+alice.Token, input, nil); code != http.StatusNotFound {
+		t.Fatalf("reply to tombstone: expected 404, got %d", code)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+const (
+	// chirpRestoreWindow is how long after deleting a chirp its author may
+	// restore it.
+	chirpRestoreWindow = 7 * 24 * time.Hour
+	// chirpRetention is how long deleted chirps are kept before the purge
+	// removes them for good.
+	chirpRetention = 30 * 24 * time.Hour
+	// purgeBatchSize bounds how many chirps each purge query loads.
+	purgeBatchSize = 100
+)
+
+// HandleRestoreChirp serves POST /api/chirps/{id}/restore, bringing back a
+// chirp its author deleted within chirpRestoreWindow, along with the
+// rechirps that were hidden with it.
+func (cfg *ApiConfig) HandleRestoreChirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	chirp, err := cfg.DB.GetDeletedChirp(r.Context(), chirpID)
+	if err != nil || chirp.TombstonedAt.Valid {
+		RespondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+	if chirp.UserID != userID {
+		RespondWithError(w, http.StatusForbidden, "Not authorized to restore this chirp")
+		return
+	}
+	if time.Since(chirp.DeletedAt.Time) > chirpRestoreWindow {
+		RespondWithError(w, http.StatusGone, "Chirps can only be restored in the first 7 days after deletion")
+		return
+	}
+
+	err = cfg.DB.RestoreChirp(r.Context(), database.RestoreChirpParams{
+		ID:        chirpID,
+		DeletedAt: chirp.DeletedAt,
+	})
+	if err != nil {
+		log.Printf("error restoring chirp %s: %s", chirpID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not restore chirp")
+		return
+	}
+	chirp.DeletedAt = sql.NullTime{}
+
+	out, err := cfg.renderChirp(r.Context(), chirp, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		log.Printf("error rendering chirp: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not restore chirp")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, out)
+}
+
+// PurgeDeletedChirps permanently removes chirps deleted before the given
+// time, returning how many it purged. A chirp that still has replies, even
+// deleted ones that may be restored, is reduced to a tombstone so the
+// conversation below it stays intact.
+func (cfg *ApiConfig) PurgeDeletedChirps(ctx context.Context, before time.Time) (int, error) {
+	purged := 0
+	for {
+		chirps, err := cfg.DB.ListPurgeableChirps(ctx, database.ListPurgeableChirpsParams{
+			Before:    sql.NullTime{Time: before, Valid: true},
+			MaxChirps: purgeBatchSize,
+		})
+		if err != nil {
+			return purged, err
+		}
+		for _, c := range chirps {
+			if err := cfg.purgeChirp(ctx, c); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+		if len(chirps) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+func (cfg *ApiConfig) purgeChirp(ctx context.Context, c database.Chirp) error {
+	hasReplies, err := cfg.DB.ChirpHasReplies(ctx, uuid.NullUUID{UUID: c.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+	if err := cfg.deleteChirpMedia(ctx, c.ID); err != nil {
+		return err
+	}
+	if !hasReplies {
+		return cfg.DB.DeleteChirpByID(ctx, c.ID)
+	}
+	err = cfg.DB.TombstoneChirp(ctx, database.TombstoneChirpParams{
+		ID:           c.ID,
+		TombstonedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if err := cfg.DB.DeleteChirpTags(ctx, c.ID); err != nil {
+		return err
+	}
+	return cfg.DB.DeleteChirpRevisions(ctx, c.ID)
+}
+
+// RunChirpPurge purges chirps past chirpRetention every interval until ctx
+// is cancelled.
+func (cfg *ApiConfig) RunChirpPurge(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func(ctx context.Context) {
+		n, err := cfg.PurgeDeletedChirps(ctx, time.Now().UTC().Add(-chirpRetention))
+		if err != nil {
+			log.Printf("error purging deleted chirps: %s", err)
+		} else if n > 0 {
+			log.Printf("purged %d deleted chirps", n)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+func TestRestoreChirp(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+	chirp := postChirp(t, srv, alice.Token, "oops #gone")
+	chirpURL := srv.URL + "/api/chirps/" + chirp.ID
+	if code := doJSON(t, "POST", chirpURL+"/rechirps", "Bearer "+bob.Token, nil, nil); code != http.StatusCreated {
+		t.Fatalf("rechirp: status %d", code)
+	}
+
+	if code := doJSON(t, "DELETE", chirpURL, "Bearer "+alice.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("delete: status %d", code)
+	}
+	if code := doJSON(t, "GET", chirpURL, "", nil, nil); code != http.StatusNotFound {
+		t.Fatalf("get deleted chirp: expected 404, got %d", code)
+	}
+	// the rechirp is hidden along with the original
+	if page, _ := getPage(t, srv.URL+"/api/chirps"); len(page) != 0 {
+		t.Fatalf("deleted chirps still listed: %q", bodies(page))
+	}
+	if page, _ := getPage(t, srv.URL+"/api/tags/gone/chirps"); len(page) != 0 {
+		t.Fatalf("deleted chirp still tagged: %q", bodies(page))
+	}
+
+	if code := doJSON(t, "POST", chirpURL+"/restore", "Bearer "+bob.Token, nil, nil); code != http.StatusForbidden {
+		t.Fatalf("restore by another user: expected 403, got %d", code)
+	}
+	var got chirpJSON
+	if code := doJSON(t, "POST", chirpURL+"/restore", "Bearer "+alice.Token, nil, &got); code != http.StatusOK {
+		t.Fatalf("restore: status %d", code)
+	}
+	if got.Body != "oops #gone" {
+		t.Fatalf("restored chirp: %+v", got)
+	}
+	if page, _ := getPage(t, srv.URL+"/api/chirps"); len(page) != 2 {
+		t.Fatalf("after restore: got %q", bodies(page))
+	}
+	if page, _ := getPage(t, srv.URL+"/api/tags/gone/chirps"); len(page) != 1 {
+		t.Fatalf("tag after restore: got %q", bodies(page))
+	}
+	if code := doJSON(t, "POST", chirpURL+"/restore", "Bearer "+alice.Token, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("restore live chirp: expected 404, got %d", code)
+	}
+}
+
+func TestRestoreChirp_AfterWindow(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	chirp := postChirp(t, srv, alice.Token, "long gone")
+
+	err := cfg.DB.SoftDeleteChirp(context.Background(), database.SoftDeleteChirpParams{
+		ID:        uuid.MustParse(chirp.ID),
+		DeletedAt: sql.NullTime{Time: time.Now().Add(-chirpRestoreWindow - time.Minute), Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := srv.URL + "/api/chirps/" + chirp.ID + "/restore"
+	if code := doJSON(t, "POST", url, "Bearer "+alice.Token, nil, nil); code != http.StatusGone {
+		t.Fatalf("restore after window: expected 410, got %d", code)
+	}
+}
+
+func TestPurgeDeletedChirps(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+	root := postChirp(t, srv, alice.Token, "root")
+	postReply(t, srv, bob.Token, root.ID, "reply")
+	leaf := postChirp(t, srv, alice.Token, "leaf")
+	kept := postChirp(t, srv, alice.Token, "recent")
+	quiet := postChirp(t, srv, alice.Token, "quiet")
+	hidden := postReply(t, srv, bob.Token, quiet.ID, "hidden")
+	if code := doJSON(t, "DELETE", srv.URL+"/api/chirps/"+hidden.ID, "Bearer "+bob.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("delete reply: status %d", code)
+	}
+
+	for _, c := range []chirpJSON{root, leaf, kept, quiet} {
+		if code := doJSON(t, "DELETE", srv.URL+"/api/chirps/"+c.ID, "Bearer "+alice.Token, nil, nil); code != http.StatusNoContent {
+			t.Fatalf("delete %q: status %d", c.Body, code)
+		}
+	}
+	ctx := context.Background()
+	for _, c := range []chirpJSON{root, leaf, quiet} {
+		// push the deletion back past the retention window
+		id := uuid.MustParse(c.ID)
+		deleted, err := cfg.DB.GetDeletedChirp(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = cfg.DB.RestoreChirp(ctx, database.RestoreChirpParams{ID: id, DeletedAt: deleted.DeletedAt})
+		if err == nil {
+			err = cfg.DB.SoftDeleteChirp(ctx, database.SoftDeleteChirpParams{
+				ID:        id,
+				DeletedAt: sql.NullTime{Time: time.Now().Add(-chirpRetention - time.Hour), Valid: true},
+			})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := cfg.PurgeDeletedChirps(ctx, time.Now().Add(-chirpRetention))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("purged %d chirps, want 3", n)
+	}
+
+	// the leaf is gone, the root with a reply is left as a tombstone
+	if _, err := cfg.DB.GetDeletedChirp(ctx, uuid.MustParse(leaf.ID)); err != sql.ErrNoRows {
+		t.Fatalf("purged leaf: expected no rows, got %v", err)
+	}
+	tomb, err := cfg.DB.GetDeletedChirp(ctx, uuid.MustParse(root.ID))
+	if err != nil || !tomb.TombstonedAt.Valid || tomb.Body != "" {
+		t.Fatalf("purged root: %+v, %v", tomb, err)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps/"+root.ID+"/restore", "Bearer "+alice.Token, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("restore purged chirp: expected 404, got %d", code)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps/"+kept.ID+"/restore", "Bearer "+alice.Token, nil, nil); code != http.StatusOK {
+		t.Fatalf("restore recent chirp: status %d", code)
+	}
+
+	// a parent whose only reply is deleted is kept as a tombstone too, so
+	// the reply is still in the thread when it is restored
+	tomb, err = cfg.DB.GetDeletedChirp(ctx, uuid.MustParse(quiet.ID))
+	if err != nil || !tomb.TombstonedAt.Valid {
+		t.Fatalf("purged parent of a deleted reply: %+v, %v", tomb, err)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/chirps/"+hidden.ID+"/restore", "Bearer "+bob.Token, nil, nil); code != http.StatusOK {
+		t.Fatalf("restore reply: status %d", code)
+	}
+	reply, err := cfg.DB.GetChirpsByID(ctx, uuid.MustParse(hidden.ID))
+	if err != nil || reply.InReplyTo.UUID != tomb.ID {
+		t.Fatalf("restored reply: %+v, %v", reply, err)
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+type ChirpRevision struct {
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// HandleEditChirp serves PUT /api/chirps/{id}. The previous body is kept as
+// a revision, and hashtags and mentions are refreshed from the new one.
+func (cfg *ApiConfig) HandleEditChirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	var input struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	ent, err := cfg.entitlements(r.Context(), userID)
+	if err != nil {
+		log.Printf("error loading entitlements for %s: %s", userID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not edit chirp")
+		return
+	}
+	if len(input.Body) > ent.MaxChirpLength {
+		RespondWithError(w, http.StatusBadRequest, "Chirp is too long")
+		return
+	}
+
+	chirp, ok := cfg.authorChirp(w, r, chirpID, userID, "edit")
+	if !ok {
+		return
+	}
+	if chirp.RechirpOf.Valid {
+		RespondWithError(w, http.StatusBadRequest, "Rechirps cannot be edited")
+		return
+	}
+	if chirp.QuoteOf.Valid && strings.TrimSpace(input.Body) == "" {
+		RespondWithError(w, http.StatusBadRequest, "Quote chirps need a body")
+		return
+	}
+	if !ent.CanEdit {
+		RespondWithError(w, http.StatusForbidden, "Your plan does not include editing chirps")
+		return
+	}
+	if !ent.CanEditAt(chirp.CreatedAt, time.Now()) {
+		RespondWithError(w, http.StatusForbidden, "The edit window for this chirp has passed")
+		return
+	}
+
+	// saving the same body again is not a new revision
+	body := CleanProfanity(input.Body)
+	if body != chirp.Body {
+		chirp, err = cfg.DB.EditChirp(r.Context(), database.EditChirpParams{
+			ID:   chirpID,
+			Body: body,
+		})
+		if err != nil {
+			log.Printf("error editing chirp: %s", err)
+			RespondWithError(w, http.StatusInternalServerError, "Could not edit chirp")
+			return
+		}
+
+		if err := cfg.DB.DeleteChirpTags(r.Context(), chirpID); err != nil {
+			log.Printf("error clearing tags of chirp %s: %s", chirpID, err)
+		} else if err := cfg.tagChirp(r.Context(), chirp); err != nil {
+			log.Printf("error tagging chirp %s: %s", chirpID, err)
+		}
+		// users already notified about this chirp are not notified again
+		var parentAuthor uuid.NullUUID
+		if chirp.InReplyTo.Valid {
+			if parent, err := cfg.DB.GetChirpsByID(r.Context(), chirp.InReplyTo.UUID); err == nil {
+				parentAuthor = uuid.NullUUID{UUID: parent.UserID, Valid: true}
+			}
+		}
+		cfg.notifyChirp(r.Context(), chirp, parentAuthor)
+	}
+
+	out, err := cfg.renderChirp(r.Context(), chirp, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		log.Printf("error rendering chirp: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not edit chirp")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, out)
+}
+
+// HandleGetRevisions serves GET /api/chirps/{id}/revisions: the bodies a
+// chirp had before its current one, oldest first.
+func (cfg *ApiConfig) HandleGetRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+	if chirp, err := cfg.DB.GetChirpsByID(r.Context(), chirpID); err != nil || chirp.TombstonedAt.Valid {
+		RespondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	revisions, err := cfg.DB.ListChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("error listing revisions: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve revisions")
+		return
+	}
+
+	out := make([]ChirpRevision, 0, len(revisions))
+	for _, rev := range revisions {
+		out = append(out, ChirpRevision{
+			Body:       rev.Body,
+			CreatedAt:  rev.CreatedAt,
+			ReplacedAt: rev.ReplacedAt,
+		})
+	}
+	RespondWithJSON(w, http.StatusOK, out)
+}
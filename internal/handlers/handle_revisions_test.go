@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+type editedChirpJSON struct {
+	Body     string     `json:"body"`
+	EditedAt *time.Time `json:"edited_at"`
+}
+
+func TestEditChirp(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+	chirp := postChirp(t, srv, alice.Token, "first #draft")
+	chirpURL := srv.URL + "/api/chirps/" + chirp.ID
+
+	var got editedChirpJSON
+	doJSON(t, "GET", chirpURL, "", nil, &got)
+	if got.EditedAt != nil {
+		t.Fatal("new chirp is marked edited")
+	}
+
+	for _, body := range []string{"second #final", "third #final"} {
+		if code := doJSON(t, "PUT", chirpURL, "Bearer "+alice.Token, map[string]string{"body": body}, &got); code != http.StatusOK {
+			t.Fatalf("edit to %q: status %d", body, code)
+		}
+	}
+	if got.Body != "third #final" || got.EditedAt == nil {
+		t.Fatalf("edited chirp: %+v", got)
+	}
+
+	var revisions []ChirpRevision
+	if code := doJSON(t, "GET", chirpURL+"/revisions", "", nil, &revisions); code != http.StatusOK {
+		t.Fatalf("revisions: status %d", code)
+	}
+	if len(revisions) != 2 || revisions[0].Body != "first #draft" || revisions[1].Body != "second #final" {
+		t.Fatalf("revisions: %+v", revisions)
+	}
+	if !revisions[1].CreatedAt.Equal(revisions[0].ReplacedAt) {
+		t.Fatalf("second revision should date from the first edit: %+v", revisions)
+	}
+
+	// tags follow the current body
+	if page, _ := getPage(t, srv.URL+"/api/tags/draft/chirps"); len(page) != 0 {
+		t.Fatalf("stale tag still lists chirp: %q", bodies(page))
+	}
+	if page, _ := getPage(t, srv.URL+"/api/tags/final/chirps"); len(page) != 1 {
+		t.Fatalf("new tag: got %q", bodies(page))
+	}
+
+	if code := doJSON(t, "PUT", chirpURL, "Bearer "+bob.Token, map[string]string{"body": "mine now"}, nil); code != http.StatusForbidden {
+		t.Fatalf("edit by another user: expected 403, got %d", code)
+	}
+
+	// an old chirp can only be edited by Chirpy Red members
+	old, err := cfg.DB.CreateChirp(context.Background(), database.CreateChirpParams{
+		ID:             uuid.New(),
+		CreatedAt:      time.Now().Add(-2 * time.Hour),
+		UpdatedAt:      time.Now().Add(-2 * time.Hour),
+		Body:           "ancient",
+		UserID:         alice.ID,
+		ConversationID: uuid.New(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldURL := srv.URL + "/api/chirps/" + old.ID.String()
+	if code := doJSON(t, "PUT", oldURL, "Bearer "+alice.Token, map[string]string{"body": "revised"}, nil); code != http.StatusForbidden {
+		t.Fatalf("edit after window: expected 403, got %d", code)
+	}
+	if err := cfg.startMembership(context.Background(), alice.ID, sql.NullTime{}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if code := doJSON(t, "PUT", oldURL, "Bearer "+alice.Token, map[string]string{"body": "revised"}, nil); code != http.StatusOK {
+		t.Fatalf("Chirpy Red edit after window: expected 200, got %d", code)
+	}
+}
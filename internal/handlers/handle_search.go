@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+// searchHit is a matching chirp with its ts_rank score. Rank is zero when
+// results are ordered by date.
+type searchHit struct {
+	chirp database.Chirp
+	rank  float32
+}
+
+func searchHitCursor(h searchHit) pageCursor {
+	return pageCursor{Rank: h.rank, CreatedAt: h.chirp.CreatedAt, ID: h.chirp.ID}
+}
+
+// HandleSearchChirps serves GET /api/chirps/search?q=. Results are ordered
+// by relevance unless sort=asc or sort=desc asks for date order.
+func (cfg *ApiConfig) HandleSearchChirps(w http.ResponseWriter, r *http.Request) {
+	query := buildTSQuery(r.URL.Query().Get("q"))
+	if query == "" {
+		RespondWithError(w, http.StatusBadRequest, "Search query is required")
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		return
+	}
+
+	authorID, err := parseAuthorID(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid author_id")
+		return
+	}
+
+	var fetch func(asc bool, c *pageCursor, n int32) ([]searchHit, error)
+	sortOrder := r.URL.Query().Get("sort")
+	switch sortOrder {
+	case "", "relevance":
+		fetch = func(asc bool, c *pageCursor, n int32) ([]searchHit, error) {
+			createdAt, id := cursorArgs(c)
+			var rank sql.NullFloat64
+			if c != nil {
+				rank = sql.NullFloat64{Float64: float64(c.Rank), Valid: true}
+			}
+			var hits []searchHit
+			if asc {
+				rows, err := cfg.DB.SearchChirpsByRankAfter(r.Context(), database.SearchChirpsByRankAfterParams{
+					Query:           query,
+					AuthorID:        authorID,
+					CursorRank:      rank,
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+					PageSize:        n,
+				})
+				for _, row := range rows {
+					hits = append(hits, searchHit{chirp: row.Chirp, rank: row.Rank})
+				}
+				return hits, err
+			}
+			rows, err := cfg.DB.SearchChirpsByRankBefore(r.Context(), database.SearchChirpsByRankBeforeParams{
+				Query:           query,
+				AuthorID:        authorID,
+				CursorRank:      rank,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				PageSize:        n,
+			})
+			for _, row := range rows {
+				hits = append(hits, searchHit{chirp: row.Chirp, rank: row.Rank})
+			}
+			return hits, err
+		}
+		// most relevant first
+		sortOrder = "desc"
+	case "asc", "desc":
+		fetch = func(asc bool, c *pageCursor, n int32) ([]searchHit, error) {
+			createdAt, id := cursorArgs(c)
+			var chirps []database.Chirp
+			var err error
+			if asc {
+				chirps, err = cfg.DB.SearchChirpsAfter(r.Context(), database.SearchChirpsAfterParams{
+					Query:           query,
+					AuthorID:        authorID,
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+					PageSize:        n,
+				})
+			} else {
+				chirps, err = cfg.DB.SearchChirpsBefore(r.Context(), database.SearchChirpsBeforeParams{
+					Query:           query,
+					AuthorID:        authorID,
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+					PageSize:        n,
+				})
+			}
+			hits := make([]searchHit, len(chirps))
+			for i, c := range chirps {
+				hits[i] = searchHit{chirp: c}
+			}
+			return hits, err
+		}
+	default:
+		RespondWithError(w, http.StatusBadRequest, "Invalid sort")
+		return
+	}
+
+	hits, links, err := paginate(page, sortOrder == "desc", searchHitCursor, fetch)
+	if err != nil {
+		log.Printf("error searching chirps: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not search chirps")
+		return
+	}
+
+	chirps := make([]database.Chirp, len(hits))
+	for i, h := range hits {
+		chirps[i] = h.chirp
+	}
+	chirpList, err := cfg.renderChirps(r.Context(), chirps, cfg.viewerID(r))
+	if err != nil {
+		log.Printf("error rendering chirps: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not search chirps")
+		return
+	}
+
+	setLinkHeader(w, r, links)
+	RespondWithJSON(w, http.StatusOK, chirpList)
+}
+
+// buildTSQuery turns user search input into to_tsquery syntax. Every term
+// must match; "quoted words" match as a phrase and a trailing * matches a
+// prefix. Anything other than letters and digits is treated as a word
+// separator, so the result never contains tsquery operators from the input.
+// It returns "" when q has no searchable words.
+func buildTSQuery(q string) string {
+	var terms []string
+	addTerm := func(text string, prefix bool) {
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) == 0 {
+			return
+		}
+		if prefix {
+			words[len(words)-1] += ":*"
+		}
+		if len(words) == 1 {
+			terms = append(terms, words[0])
+			return
+		}
+		terms = append(terms, "("+strings.Join(words, " <-> ")+")")
+	}
+
+	for q != "" {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if q == "" {
+			break
+		}
+		if q[0] == '"' {
+			phrase, rest, _ := strings.Cut(q[1:], `"`)
+			addTerm(phrase, false)
+			q = rest
+			continue
+		}
+		end := strings.IndexFunc(q, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			end = len(q)
+		}
+		word := q[:end]
+		addTerm(word, strings.HasSuffix(word, "*"))
+		q = q[end:]
+	}
+	return strings.Join(terms, " & ")
+}
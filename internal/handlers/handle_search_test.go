@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestBuildTSQuery(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"hello", "hello"},
+		{"Hello World", "hello & world"},
+		{`"good morning" chirpy`, "(good <-> morning) & chirpy"},
+		{"chirp*", "chirp:*"},
+		{`"big bir*"`, "(big <-> bir)"},
+		{"foo-bar*", "(foo <-> bar:*)"},
+		{"a & b | !c", "a & b & c"},
+		{`unterminated "phrase here`, "unterminated & (phrase <-> here)"},
+		{"  !!  ", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := buildTSQuery(tt.in); got != tt.want {
+			t.Errorf("buildTSQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSearchChirps(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+	postChirp(t, srv, alice.Token, "good morning chirpy")
+	postChirp(t, srv, bob.Token, "morning coffee is good")
+	postChirp(t, srv, alice.Token, "chirping all night")
+	postChirp(t, srv, alice.Token, "good good good morning")
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"phrase", `?q="good+morning"&sort=asc`, []string{"good morning chirpy", "good good good morning"}},
+		{"prefix", "?q=chirp*&sort=asc", []string{"good morning chirpy", "chirping all night"}},
+		{"author", "?q=morning&sort=desc&author_id=" + bob.ID.String(), []string{"morning coffee is good"}},
+		{"relevance", "?q=good", []string{"good good good morning", "good morning chirpy", "morning coffee is good"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chirps, _ := getPage(t, srv.URL+"/api/chirps/search"+tt.query)
+			if got := bodies(chirps); !slices.Equal(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// relevance order pages the same way as date order
+	first, links := getPage(t, srv.URL+"/api/chirps/search?q=good&limit=2")
+	second, _ := getPage(t, srv.URL+links["next"])
+	if got := bodies(append(first, second...)); !slices.Equal(got, tests[3].want) {
+		t.Fatalf("paged relevance results: got %v", got)
+	}
+
+	if code := doJSON(t, "GET", srv.URL+"/api/chirps/search?q=", "", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("empty query: expected 400, got %d", code)
+	}
+}
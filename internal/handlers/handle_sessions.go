@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+// Session is a login on one device: a family of refresh tokens, described
+// by its live token.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	StartedAt  time.Time `json:"started_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// HandleListSessions serves GET /api/sessions, most recently used first.
+// The session the caller's access token belongs to is marked current.
+func (cfg *ApiConfig) HandleListSessions(w http.ResponseWriter, r *http.Request) {
+	token, ok := cfg.requireAccessToken(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.DB.ListUserSessions(r.Context(), database.ListUserSessionsParams{
+		UserID: token.UserID,
+		Now:    time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("error listing sessions: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not list sessions")
+		return
+	}
+
+	sessions := make([]Session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, Session{
+			ID:         row.FamilyID,
+			UserAgent:  row.UserAgent,
+			IP:         row.Ip,
+			StartedAt:  row.StartedAt,
+			LastUsedAt: row.LastUsedAt,
+			ExpiresAt:  row.ExpiresAt,
+			Current:    token.SessionID.Valid && token.SessionID.UUID == row.FamilyID,
+		})
+	}
+	RespondWithJSON(w, http.StatusOK, sessions)
+}
+
+// HandleRevokeSession serves DELETE /api/sessions/{id}, signing the device
+// out once its access token expires.
+func (cfg *ApiConfig) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	n, err := cfg.DB.RevokeRefreshTokenFamily(r.Context(), database.RevokeRefreshTokenFamilyParams{
+		UserID:    userID,
+		FamilyID:  sessionID,
+		RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error revoking session: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not revoke session")
+		return
+	}
+	if n == 0 {
+		RespondWithError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRevokeAllSessions serves POST /api/sessions/revoke-all. Bumping
+// the token version invalidates every access token already issued,
+// including the caller's, so the user is signed out everywhere at once.
+func (cfg *ApiConfig) HandleRevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := cfg.DB.IncrementUserTokenVersion(r.Context(), userID); err != nil {
+		log.Printf("error bumping token version: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not revoke sessions")
+		return
+	}
+	err := cfg.DB.RevokeUserRefreshTokens(r.Context(), database.RevokeUserRefreshTokensParams{
+		UserID:    userID,
+		RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error revoking refresh tokens: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not revoke sessions")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
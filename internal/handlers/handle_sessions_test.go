@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSessions(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	var laptop loginResponse
+	creds := map[string]string{"email": "alice@example.com", "password": "hunter2"}
+	if code := doJSON(t, "POST", srv.URL+"/api/login", "", creds, &laptop); code != http.StatusOK {
+		t.Fatalf("second login: status %d", code)
+	}
+
+	var sessions []Session
+	if code := doJSON(t, "GET", srv.URL+"/api/sessions", "Bearer "+alice.Token, nil, &sessions); code != http.StatusOK {
+		t.Fatalf("list sessions: status %d", code)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %+v", sessions)
+	}
+	var current, other Session
+	for _, s := range sessions {
+		if s.Current {
+			current = s
+		} else {
+			other = s
+		}
+		if s.UserAgent == "" || s.IP == "" {
+			t.Errorf("session missing device details: %+v", s)
+		}
+	}
+	if current.ID == other.ID {
+		t.Fatalf("expected exactly one current session, got %+v", sessions)
+	}
+
+	// bob cannot revoke alice's sessions
+	bob := signup(t, srv, "bob@example.com")
+	if code := doJSON(t, "DELETE", srv.URL+"/api/sessions/"+other.ID.String(), "Bearer "+bob.Token, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("revoke other user's session: expected 404, got %d", code)
+	}
+
+	if code := doJSON(t, "DELETE", srv.URL+"/api/sessions/"+other.ID.String(), "Bearer "+alice.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("revoke session: status %d", code)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/refresh", "Bearer "+laptop.RefreshToken, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("refresh revoked session: expected 401, got %d", code)
+	}
+	sessions = nil
+	doJSON(t, "GET", srv.URL+"/api/sessions", "Bearer "+alice.Token, nil, &sessions)
+	if len(sessions) != 1 || sessions[0].ID != current.ID {
+		t.Fatalf("expected only the current session, got %+v", sessions)
+	}
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+
+	if code := doJSON(t, "POST", srv.URL+"/api/sessions/revoke-all", "Bearer "+alice.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("revoke all: status %d", code)
+	}
+
+	// outstanding access and refresh tokens stop working at once...
+	if code := doJSON(t, "GET", srv.URL+"/api/sessions", "Bearer "+alice.Token, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("old access token: expected 401, got %d", code)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/refresh", "Bearer "+alice.RefreshToken, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("old refresh token: expected 401, got %d", code)
+	}
+	// ...other users are unaffected...
+	if code := doJSON(t, "GET", srv.URL+"/api/sessions", "Bearer "+bob.Token, nil, nil); code != http.StatusOK {
+		t.Fatalf("bob: status %d", code)
+	}
+	// ...and logging in again works
+	var again loginResponse
+	creds := map[string]string{"email": "alice@example.com", "password": "hunter2"}
+	if code := doJSON(t, "POST", srv.URL+"/api/login", "", creds, &again); code != http.StatusOK {
+		t.Fatalf("login after revoke all: status %d", code)
+	}
+	if code := doJSON(t, "GET", srv.URL+"/api/sessions", "Bearer "+again.Token, nil, nil); code != http.StatusOK {
+		t.Fatalf("new access token: status %d", code)
+	}
+}
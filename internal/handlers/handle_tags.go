@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	defaultTrendingTags   = 10
+	maxTrendingTags       = 50
+)
+
+type TrendingTag struct {
+	Tag        string `json:"tag"`
+	ChirpCount int64  `json:"chirp_count"`
+}
+
+// tagChirp links a newly stored chirp to the hashtags in its body.
+func (cfg *ApiConfig) tagChirp(ctx context.Context, chirp database.Chirp) error {
+	for _, name := range ExtractHashtags(chirp.Body) {
+		tag, err := cfg.DB.UpsertTag(ctx, name)
+		if err != nil {
+			return err
+		}
+		err = cfg.DB.AddChirpTag(ctx, database.AddChirpTagParams{
+			ChirpID:   chirp.ID,
+			TagID:     tag.ID,
+			CreatedAt: chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleGetTagChirps serves GET /api/tags/{tag}/chirps, newest first unless
+// sort=asc. The tag may be given with or without its leading #.
+func (cfg *ApiConfig) HandleGetTagChirps(w http.ResponseWriter, r *http.Request) {
+	tags := ExtractHashtags("#" + strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if len(tags) != 1 {
+		RespondWithError(w, http.StatusBadRequest, "Invalid tag")
+		return
+	}
+	tag := tags[0]
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		return
+	}
+
+	desc := r.URL.Query().Get("sort") != "asc"
+	chirps, links, err := paginate(page, desc, chirpCursor, func(asc bool, c *pageCursor, n int32) ([]database.Chirp, error) {
+		createdAt, id := cursorArgs(c)
+		if asc {
+			return cfg.DB.ListTagChirpsAfter(r.Context(), database.ListTagChirpsAfterParams{
+				Tag:             tag,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				PageSize:        n,
+			})
+		}
+		return cfg.DB.ListTagChirpsBefore(r.Context(), database.ListTagChirpsBeforeParams{
+			Tag:             tag,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageSize:        n,
+		})
+	})
+	if err != nil {
+		log.Printf("error getting tag chirps: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve chirps")
+		return
+	}
+
+	chirpList, err := cfg.renderChirps(r.Context(), chirps, cfg.viewerID(r))
+	if err != nil {
+		log.Printf("error rendering chirps: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve chirps")
+		return
+	}
+
+	setLinkHeader(w, r, links)
+	RespondWithJSON(w, http.StatusOK, chirpList)
+}
+
+// HandleGetTrendingTags serves GET /api/tags/trending: the most used tags
+// over the trailing window (a Go duration such as 6h, default 24h, at most
+// 7 days), limited to limit tags (default 10, at most 50).
+func (cfg *ApiConfig) HandleGetTrendingTags(w http.ResponseWriter, r *http.Request) {
+	window := defaultTrendingWindow
+	if s := r.URL.Query().Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 || d > maxTrendingWindow {
+			RespondWithError(w, http.StatusBadRequest, "Invalid window")
+			return
+		}
+		window = d
+	}
+	limit := defaultTrendingTags
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			RespondWithError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = min(n, maxTrendingTags)
+	}
+
+	rows, err := cfg.DB.GetTrendingTags(r.Context(), database.GetTrendingTagsParams{
+		Since:   time.Now().UTC().Add(-window),
+		MaxTags: int32(limit),
+	})
+	if err != nil {
+		log.Printf("error getting trending tags: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve trending tags")
+		return
+	}
+
+	trending := make([]TrendingTag, 0, len(rows))
+	for _, row := range rows {
+		trending = append(trending, TrendingTag{Tag: row.Name, ChirpCount: row.ChirpCount})
+	}
+	RespondWithJSON(w, http.StatusOK, trending)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	cases := []struct {
+		body string
+		want []string
+	}{
+		{"no tags here", nil},
+		{"#Go and #go again", []string{"go"}},
+		{"mixed #one, #two_2! (#three)", []string{"one", "two_2", "three"}},
+		{"numbers only #2024 skipped", nil},
+		{"email a#b and &#39; are not tags", nil},
+		{"#café works", []string{"café"}},
+	}
+	for _, c := range cases {
+		if got := ExtractHashtags(c.body); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ExtractHashtags(%q) = %q, want %q", c.body, got, c.want)
+		}
+	}
+
+	if got := ExtractHashtags(CleanProfanity("hello #Fornax #ok")); !reflect.DeepEqual(got, []string{"ok"}) {
+		t.Errorf("profane tag not masked: %q", got)
+	}
+}
+
+func TestTagFeedAndTrending(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	postChirp(t, srv, alice.Token, "first #Go post")
+	postChirp(t, srv, alice.Token, "unrelated #rust")
+	postChirp(t, srv, alice.Token, "second #go #rust")
+	postChirp(t, srv, alice.Token, "third #GO")
+
+	got, _ := getPage(t, srv.URL+"/api/tags/%23go/chirps")
+	want := []string{"third #GO", "second #go #rust", "first #Go post"}
+	if !reflect.DeepEqual(bodies(got), want) {
+		t.Fatalf("tag feed: got %q, want %q", bodies(got), want)
+	}
+
+	page, links := getPage(t, srv.URL+"/api/tags/go/chirps?sort=asc&limit=2")
+	if !reflect.DeepEqual(bodies(page), []string{"first #Go post", "second #go #rust"}) || links["next"] == "" {
+		t.Fatalf("tag feed asc page: got %q, links %v", bodies(page), links)
+	}
+
+	var trending []TrendingTag
+	if code := doJSON(t, "GET", srv.URL+"/api/tags/trending?window=1h", "", nil, &trending); code != http.StatusOK {
+		t.Fatalf("trending: status %d", code)
+	}
+	wantTrending := []TrendingTag{{Tag: "go", ChirpCount: 3}, {Tag: "rust", ChirpCount: 2}}
+	if !reflect.DeepEqual(trending, wantTrending) {
+		t.Fatalf("trending: got %+v, want %+v", trending, wantTrending)
+	}
+
+	if code := doJSON(t, "GET", srv.URL+"/api/tags/trending?window=30d", "", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("trending bad window: expected 400, got %d", code)
+	}
+	if code := doJSON(t, "GET", srv.URL+"/api/tags/123/chirps", "", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("invalid tag: expected 400, got %d", code)
+	}
+}
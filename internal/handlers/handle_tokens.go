@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
 	"time"
 	
@@ -29,51 +31,81 @@ func (cfg *ApiConfig) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// locked and failed logins all get the same response, so it does not
+	// tell which emails have accounts
+	now := time.Now().UTC()
+	keys := cfg.loginKeys(r, body.Email)
+	wait, err := cfg.loginRetryAfter(r.Context(), keys, now)
+	if err != nil {
+		log.Printf("error checking login throttle: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not log in")
+		return
+	}
+	if wait > 0 {
+		cfg.recordLoginAttempt(r.Context(), r, body.Email, uuid.NullUUID{}, loginReasonLocked)
+		w.Header().Set("Retry-After", ceilSeconds(wait))
+		RespondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+		return
+	}
+
 	dbUser, err := cfg.DB.GetUserByEmail(r.Context(), body.Email)
 	if err != nil {
+		_ = auth.CheckPasswordHash(body.Password, dummyPasswordHash())
+		cfg.recordLoginFailure(r.Context(), keys, now)
+		cfg.recordLoginAttempt(r.Context(), r, body.Email, uuid.NullUUID{}, loginReasonUnknownUser)
 		RespondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
 		return
 	}
 
 	if err := auth.CheckPasswordHash(body.Password, dbUser.HashedPassword); err != nil {
+		cfg.recordLoginFailure(r.Context(), keys, now)
+		cfg.recordLoginAttempt(r.Context(), r, body.Email, uuid.NullUUID{UUID: dbUser.ID, Valid: true}, loginReasonBadPassword)
 		RespondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
 		return
 	}
+	// a correct password clears the email's failures but not the IP's
+	if err := cfg.DB.DeleteLoginThrottle(r.Context(), emailThrottleKey(body.Email)); err != nil {
+		log.Printf("error clearing login failures for %s: %s", dbUser.ID, err)
+	}
+	cfg.recordLoginAttempt(r.Context(), r, body.Email, uuid.NullUUID{UUID: dbUser.ID, Valid: true}, "")
 
-	accessToken, err := auth.MakeJWT(dbUser.ID, cfg.JWTSecret, time.Hour)
+	// every login starts a session: a new family of refresh tokens
+	sessionID := uuid.New()
+	accessToken, err := auth.MakeJWT(dbUser.ID, cfg.keys().SigningKey(), accessTokenTTL, dbUser.TokenVersion, sessionID)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Failed to create access token")
 		return
 	}
 
-	refreshToken, err := auth.MakeRefreshToken()
+	refreshToken, err := cfg.issueRefreshToken(r, dbUser.ID, sessionID)
 	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Failed to create refresh token")
+		RespondWithError(w, http.StatusInternalServerError, "Failed to store refresh token")
 		return
 	}
 
-	expiresAt := time.Now().Add(60 * 24 * time.Hour) // 60 days
-	err = cfg.DB.InsertRefreshToken(r.Context(), database.InsertRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    dbUser.ID,
-		ExpiresAt: expiresAt,
-	})
+	isChirpyRed, err := cfg.isChirpyRed(r.Context(), dbUser.ID)
 	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Failed to store refresh token")
+		RespondWithError(w, http.StatusInternalServerError, "Failed to load membership")
 		return
 	}
 
 	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"id":            dbUser.ID,
-		"email":         dbUser.Email,
-		"created_at":    dbUser.CreatedAt,
-		"updated_at":    dbUser.UpdatedAt,
-		"token":         accessToken,
-		"refresh_token": refreshToken,
-		"is_chirpy_red": dbUser.IsChirpyRed,
+		"id":             dbUser.ID,
+		"email":          dbUser.Email,
+		"created_at":     dbUser.CreatedAt,
+		"updated_at":     dbUser.UpdatedAt,
+		"token":          accessToken,
+		"refresh_token":  refreshToken,
+		"is_chirpy_red":  isChirpyRed,
+		"username":       dbUser.Username.String,
+		"email_verified": dbUser.VerifiedAt.Valid,
 	})
 }
 
+// HandleRefresh serves POST /api/refresh. The refresh token is rotated:
+// the response carries a new one from the same family and the one
+// presented stops working. Presenting a token that was already rotated
+// means it has leaked, so every token in its family is revoked.
 func (cfg *ApiConfig) HandleRefresh(w http.ResponseWriter, r *http.Request) {
 	tokenStr, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -82,22 +114,66 @@ func (cfg *ApiConfig) HandleRefresh(w http.ResponseWriter, r *http.Request) {
 	}
 
 	refreshToken, err := cfg.DB.GetUserFromRefreshToken(r.Context(), tokenStr)
-	if err != nil || isRevokedOrExpired(refreshToken) {
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "Refresh token is invalid or expired")
+		return
+	}
+	if refreshToken.RotatedAt.Valid {
+		cfg.revokeReusedFamily(r.Context(), refreshToken)
+		RespondWithError(w, http.StatusUnauthorized, "Refresh token is invalid or expired")
+		return
+	}
+	if isRevokedOrExpired(refreshToken) {
 		RespondWithError(w, http.StatusUnauthorized, "Refresh token is invalid or expired")
 		return
 	}
 
-	accessToken, err := auth.MakeJWT(refreshToken.UserID, cfg.JWTSecret, time.Hour)
+	newToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Could not generate token")
+		return
+	}
+	now := time.Now().UTC()
+	userAgent, ip := cfg.deviceDetails(r)
+	n, err := cfg.DB.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+		RotatedAt: sql.NullTime{Time: now, Valid: true},
+		NewToken:  newToken,
+		Token:     tokenStr,
+		ExpiresAt: now.Add(refreshTokenTTL),
+		UserAgent: userAgent,
+		Ip:        ip,
+	})
+	if err != nil {
+		log.Printf("error rotating refresh token: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not generate token")
+		return
+	}
+	if n == 0 {
+		// another request rotated or revoked it since we looked
+		cfg.revokeReusedFamily(r.Context(), refreshToken)
+		RespondWithError(w, http.StatusUnauthorized, "Refresh token is invalid or expired")
+		return
+	}
+
+	version, err := cfg.DB.GetUserTokenVersion(r.Context(), refreshToken.UserID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Could not generate token")
+		return
+	}
+	accessToken, err := auth.MakeJWT(refreshToken.UserID, cfg.keys().SigningKey(), accessTokenTTL, version, refreshToken.FamilyID)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Could not generate token")
 		return
 	}
 
 	RespondWithJSON(w, http.StatusOK, map[string]string{
-		"token": accessToken,
+		"token":         accessToken,
+		"refresh_token": newToken,
 	})
 }
 
+// HandleRevoke serves POST /api/revoke, logging out the session the
+// refresh token belongs to by revoking its whole family.
 func (cfg *ApiConfig) HandleRevoke(w http.ResponseWriter, r *http.Request) {
 	tokenStr, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -105,11 +181,15 @@ func (cfg *ApiConfig) HandleRevoke(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	now := time.Now().UTC()
-	err = cfg.DB.RevokeRefreshToken(r.Context(), database.RevokeRefreshTokenParams{
-		RevokedAt: sql.NullTime{Time: now, Valid: true},
-		UpdatedAt: now,
-		Token:     tokenStr,
+	refreshToken, err := cfg.DB.GetUserFromRefreshToken(r.Context(), tokenStr)
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "Token not found or could not be revoked")
+		return
+	}
+	_, err = cfg.DB.RevokeRefreshTokenFamily(r.Context(), database.RevokeRefreshTokenFamilyParams{
+		UserID:    refreshToken.UserID,
+		FamilyID:  refreshToken.FamilyID,
+		RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
 	})
 	if err != nil {
 		RespondWithError(w, http.StatusUnauthorized, "Token not found or could not be revoked")
@@ -119,6 +199,66 @@ func (cfg *ApiConfig) HandleRevoke(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent) // 204 No Content
 }
 
+// accessTokenTTL is how long access tokens last. They cannot be revoked
+// one by one, so it is kept short.
+const accessTokenTTL = time.Hour
+
+// refreshTokenTTL is how long a refresh token lasts. Rotation issues each
+// new token with the full lifetime.
+const refreshTokenTTL = 60 * 24 * time.Hour // 60 days
+
+// maxUserAgentLength bounds the user agent stored with a refresh token.
+const maxUserAgentLength = 512
+
+// issueRefreshToken creates and stores a new refresh token in familyID for
+// the device making r.
+func (cfg *ApiConfig) issueRefreshToken(r *http.Request, userID, familyID uuid.UUID) (string, error) {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	if err := cfg.storeRefreshToken(r, token, userID, familyID); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (cfg *ApiConfig) storeRefreshToken(r *http.Request, token string, userID, familyID uuid.UUID) error {
+	userAgent, ip := cfg.deviceDetails(r)
+	return cfg.DB.InsertRefreshToken(r.Context(), database.InsertRefreshTokenParams{
+		Token:     token,
+		UserID:    userID,
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().UTC().Add(refreshTokenTTL),
+		UserAgent: userAgent,
+		Ip:        ip,
+	})
+}
+
+// deviceDetails are the user agent and client IP a refresh token records
+// for the session list.
+func (cfg *ApiConfig) deviceDetails(r *http.Request) (userAgent, ip string) {
+	userAgent = r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return userAgent, cfg.clientIP(r)
+}
+
+// revokeReusedFamily revokes every token in the family of a token that
+// was presented after being rotated.
+func (cfg *ApiConfig) revokeReusedFamily(ctx context.Context, token database.GetUserFromRefreshTokenRow) {
+	log.Printf("reuse of rotated refresh token detected for user %s; revoking family %s", token.UserID, token.FamilyID)
+	_, err := cfg.DB.RevokeRefreshTokenFamily(ctx, database.RevokeRefreshTokenFamilyParams{
+		UserID:    token.UserID,
+		FamilyID:  token.FamilyID,
+		RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error revoking refresh token family %s: %s", token.FamilyID, err)
+	}
+}
+
 func isRevokedOrExpired(token database.GetUserFromRefreshTokenRow) bool {
 	return token.RevokedAt.Valid || time.Now().After(token.ExpiresAt)
-}
\ No newline at end of file
+}
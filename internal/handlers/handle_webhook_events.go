@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+type WebhookEvent struct {
+	ID          uuid.UUID       `json:"id"`
+	EventID     string          `json:"event_id"`
+	Event       string          `json:"event"`
+	Payload     json.RawMessage `json:"payload"`
+	ReceivedAt  time.Time       `json:"received_at"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+func webhookEventFromDB(e database.WebhookEvent) WebhookEvent {
+	out := WebhookEvent{
+		ID:         e.ID,
+		EventID:    e.EventID,
+		Event:      e.Event,
+		Payload:    e.Payload,
+		ReceivedAt: e.ReceivedAt,
+		LastError:  e.LastError.String,
+	}
+	if e.ProcessedAt.Valid {
+		out.ProcessedAt = &e.ProcessedAt.Time
+	}
+	return out
+}
+
+// HandleListWebhookEvents serves GET /admin/webhooks: stored webhook
+// deliveries, newest first unless sort=asc, and only those not yet applied
+// when pending=true. It takes the usual limit and cursor parameters.
+func (cfg *ApiConfig) HandleListWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		return
+	}
+	pendingOnly := r.URL.Query().Get("pending") == "true"
+
+	key := func(e database.WebhookEvent) pageCursor {
+		return pageCursor{CreatedAt: e.ReceivedAt, ID: e.ID}
+	}
+	desc := r.URL.Query().Get("sort") != "asc"
+	events, links, err := paginate(page, desc, key, func(asc bool, c *pageCursor, n int32) ([]database.WebhookEvent, error) {
+		receivedAt, id := cursorArgs(c)
+		if asc {
+			return cfg.DB.ListWebhookEventsAfter(r.Context(), database.ListWebhookEventsAfterParams{
+				PendingOnly:      pendingOnly,
+				CursorReceivedAt: receivedAt,
+				CursorID:         id,
+				PageSize:         n,
+			})
+		}
+		return cfg.DB.ListWebhookEventsBefore(r.Context(), database.ListWebhookEventsBeforeParams{
+			PendingOnly:      pendingOnly,
+			CursorReceivedAt: receivedAt,
+			CursorID:         id,
+			PageSize:         n,
+		})
+	})
+	if err != nil {
+		log.Printf("error listing webhook events: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve webhook events")
+		return
+	}
+
+	out := make([]WebhookEvent, 0, len(events))
+	for _, e := range events {
+		out = append(out, webhookEventFromDB(e))
+	}
+	setLinkHeader(w, r, links)
+	RespondWithJSON(w, http.StatusOK, out)
+}
+
+// HandleReplayWebhookEvent serves POST /admin/webhooks/{id}/replay, applying
+// a stored delivery again whether or not it succeeded before. It responds
+// with the event and its new outcome, or 409 while a delivery or another
+// replay of the event is applying it.
+func (cfg *ApiConfig) HandleReplayWebhookEvent(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+	if _, err := cfg.DB.GetWebhookEvent(r.Context(), id); err != nil {
+		RespondWithError(w, http.StatusNotFound, "Webhook event not found")
+		return
+	}
+	now := time.Now().UTC()
+	event, err := cfg.DB.ClaimWebhookEventForReplay(r.Context(), database.ClaimWebhookEventForReplayParams{
+		ProcessingStartedAt: sql.NullTime{Time: now, Valid: true},
+		ID:                  id,
+		StaleBefore:         sql.NullTime{Time: now.Add(-webhookClaimTimeout), Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(w, http.StatusConflict, "Event is already being processed")
+		return
+	}
+	if err != nil {
+		log.Printf("error claiming webhook event %s: %s", id, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not replay event")
+		return
+	}
+
+	event, err = cfg.processWebhookEvent(r.Context(), event)
+	if err != nil {
+		log.Printf("replay of webhook event %s failed: %s", event.EventID, err)
+	}
+	RespondWithJSON(w, http.StatusOK, webhookEventFromDB(event))
+}
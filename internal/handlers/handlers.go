@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -12,14 +13,47 @@ import (
 	"github.com/google/uuid"
 	"github.com/kavancamp/chirpy/internal/auth"
 	"github.com/kavancamp/chirpy/internal/database"
+	"github.com/kavancamp/chirpy/internal/entitlements"
+	"github.com/kavancamp/chirpy/internal/mail"
+	"github.com/kavancamp/chirpy/internal/media"
+	"github.com/kavancamp/chirpy/internal/payments"
+	"github.com/kavancamp/chirpy/internal/ratelimit"
 )
 
 type ApiConfig struct {
 	fileserverHits atomic.Int32
-	DB             *database.Queries
+	DB             database.Store
 	Platform        string
-	JWTSecret 		string
-	PolkaKey		string
+	// JWTKey signs access tokens until a key in jwt_keys is promoted.
+	JWTKey		*auth.Key
+	// JWTKeyOverlap is how long a replaced signing key is still accepted;
+	// zero means MinJWTKeyOverlap.
+	JWTKeyOverlap	time.Duration
+	// JWTKeyPromotionDelay is how long after it is created a key can be
+	// promoted; zero means KeyRingRefreshInterval plus the JWKS max-age.
+	JWTKeyPromotionDelay	time.Duration
+	// JWTKeyEncryptionKey is the 32 byte key private keys in jwt_keys are
+	// encrypted with; without it no key can be created there.
+	JWTKeyEncryptionKey	[]byte
+	keyRing		atomic.Pointer[auth.KeyRing]
+	Payments	payments.Provider
+	AdminKey	string
+	Blobs		media.BlobStore
+	// Entitlements are the per-tier limits; nil means entitlements.Default().
+	Entitlements	entitlements.Config
+	// RateLimiter stores rate limit buckets; nil turns limiting off.
+	RateLimiter	ratelimit.Backend
+	// RateLimits override the default limit of each rate-limited route.
+	RateLimits	map[string]ratelimit.Limit
+	// TrustForwardedFor takes client IPs from X-Forwarded-For.
+	TrustForwardedFor	bool
+	// Mailer sends verification emails; nil sends none.
+	Mailer	mail.Mailer
+	// PublicURL is the base of links in emails.
+	PublicURL	string
+	// RequireVerifiedEmail keeps users from posting chirps until they have
+	// verified their email address.
+	RequireVerifiedEmail	bool
 }
 
 type User struct {
@@ -28,6 +62,18 @@ type User struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	Email     string    `json:"email"`
 	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	Username  string    `json:"username,omitempty"`
+	EmailVerified	bool	`json:"email_verified"`
+}
+
+// parseUsername validates an optional username from a request body. An
+// empty name means none was given.
+func parseUsername(name string) (sql.NullString, bool) {
+	if strings.TrimSpace(name) == "" {
+		return sql.NullString{}, true
+	}
+	normalized, ok := NormalizeUsername(name)
+	return sql.NullString{String: normalized, Valid: ok}, ok
 }
 
 func (cfg *ApiConfig) MiddlewareMetricsInc(next http.Handler) http.Handler {
@@ -57,7 +103,17 @@ func (cfg *ApiConfig) AdminResetHandler(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
-	err := cfg.DB.DeleteAllUsers(r.Context())
+	// the rows would cascade away with their users, leaving the blobs behind
+	attachments, err := cfg.DB.DeleteAllMedia(r.Context())
+	if err != nil {
+		log.Printf("Error deleting media: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Failed to reset users")
+		return
+	}
+	for _, a := range attachments {
+		cfg.deleteBlobs(r.Context(), a.BlobKey, a.ThumbnailKey)
+	}
+	err = cfg.DB.DeleteAllUsers(r.Context())
 	if err != nil {
 		log.Printf("Error deleting users: %s", err)
 		RespondWithError(w, http.StatusInternalServerError, "Failed to reset users")
@@ -73,6 +129,7 @@ func (cfg *ApiConfig) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
 	type userInput struct {
 		Email string `json:"email"`
 		Password string `json:"password"`
+		Username string `json:"username"`
 	}
 	
 	var input userInput
@@ -84,10 +141,19 @@ func (cfg *ApiConfig) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
 		RespondWithError(w, http.StatusBadRequest, "Email is required")
 		return
 	}
+	if !validEmail(input.Email) {
+		RespondWithError(w, http.StatusBadRequest, "Invalid email address")
+		return
+	}
 	if strings.TrimSpace(input.Password) == "" {
 		RespondWithError(w, http.StatusBadRequest, "Password is required")
 		return
 	}
+	username, ok := parseUsername(input.Username)
+	if !ok {
+		RespondWithError(w, http.StatusBadRequest, "Usernames are 1-30 letters, digits or underscores")
+		return
+	}
 	hashed, err := auth.HashPassword(input.Password)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Failed to hash password")
@@ -96,18 +162,28 @@ func (cfg *ApiConfig) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
 	dbUser, err := cfg.DB.CreateUser(r.Context(), database.CreateUserParams{
 		Email: input.Email,
 		HashedPassword: hashed,
+		Username: username,
 	})
+	if database.IsUniqueViolation(err) {
+		RespondWithError(w, http.StatusConflict, "Email or username already in use")
+		return
+	}
 	if err != nil {
 		log.Printf("Error creating user: %s", err)
 		RespondWithError(w, http.StatusInternalServerError, "Could not create user")
 		return
 	}
+	if err := cfg.sendVerificationEmail(r.Context(), dbUser.ID, dbUser.Email); err != nil {
+		// the user can ask for another link once signed in
+		log.Printf("error sending verification email to %s: %s", dbUser.ID, err)
+	}
 	user := User {
 		ID: dbUser.ID,
 		CreatedAt: dbUser.CreatedAt,
 		UpdatedAt: dbUser.UpdatedAt,
 		Email: dbUser.Email,
-		IsChirpyRed: dbUser.IsChirpyRed,
+		Username: dbUser.Username.String,
+		EmailVerified: dbUser.VerifiedAt.Valid,
 	}
 	RespondWithJSON(w, http.StatusCreated, user)
 }
@@ -119,7 +195,7 @@ func (cfg *ApiConfig) HandleUpdateUser(w http.ResponseWriter, r *http.Request){
 		return
 	}
 
-	userID, err := auth.ValidateJWT(tokenStr, cfg.JWTSecret)
+	userID, err := cfg.validateJWT(r.Context(), tokenStr)
 	if err != nil {
 		RespondWithError(w, http.StatusUnauthorized, "Invalid or expired token")
 		return
@@ -128,6 +204,7 @@ func (cfg *ApiConfig) HandleUpdateUser(w http.ResponseWriter, r *http.Request){
 	type requestBody struct {
 		Email string `json:"email"`
 		Password string `json:"password"`
+		Username string `json:"username"`
 	}
 	var input requestBody
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
@@ -138,6 +215,16 @@ func (cfg *ApiConfig) HandleUpdateUser(w http.ResponseWriter, r *http.Request){
 		RespondWithError(w, http.StatusBadRequest, "Email and password required")
 		return
 	}
+	if !validEmail(input.Email) {
+		RespondWithError(w, http.StatusBadRequest, "Invalid email address")
+		return
+	}
+	// leaving username out keeps the current one
+	username, ok := parseUsername(input.Username)
+	if !ok {
+		RespondWithError(w, http.StatusBadRequest, "Usernames are 1-30 letters, digits or underscores")
+		return
+	}
 	//new hashed password
 
 	hashedPassword, err := auth.HashPassword(input.Password)
@@ -145,23 +232,46 @@ func (cfg *ApiConfig) HandleUpdateUser(w http.ResponseWriter, r *http.Request){
 		RespondWithError(w, http.StatusInternalServerError, "Failed to hash password")
 		return
 	}
+	current, err := cfg.DB.GetUserByID(r.Context(), userID)
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "User not found")
+		return
+	}
 	// update user in db
 	updatedUser, err := cfg.DB.UpdateUser(r.Context(), database.UpdateUserParams {
 		ID: userID,
 		Email: input.Email,
 		HashedPassword: hashedPassword,
+		Username: username,
 	})
+	if database.IsUniqueViolation(err) {
+		RespondWithError(w, http.StatusConflict, "Email or username already in use")
+		return
+	}
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Failed to update user")
 		return
 	}
+	isChirpyRed, err := cfg.isChirpyRed(r.Context(), userID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+	if updatedUser.Email != current.Email {
+		// a changed address needs verifying again
+		if err := cfg.sendVerificationEmail(r.Context(), updatedUser.ID, updatedUser.Email); err != nil {
+			log.Printf("error sending verification email to %s: %s", updatedUser.ID, err)
+		}
+	}
 	//return updated user without pword
 	userResp := User{
 		ID: updatedUser.ID,
 		CreatedAt: updatedUser.CreatedAt,
 		UpdatedAt: updatedUser.UpdatedAt,
 		Email: updatedUser.Email,
-		IsChirpyRed: updatedUser.IsChirpyRed,
+		IsChirpyRed: isChirpyRed,
+		Username: updatedUser.Username.String,
+		EmailVerified: updatedUser.VerifiedAt.Valid,
 	}
 	RespondWithJSON(w, http.StatusOK, userResp)
 }
\ No newline at end of file
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kavancamp/chirpy/internal/auth"
+	"github.com/kavancamp/chirpy/internal/database"
+	"github.com/kavancamp/chirpy/internal/media"
+	"github.com/kavancamp/chirpy/internal/payments"
+)
+
+const (
+	testJWTSecret = "test-jwt-secret"
+	testPolkaKey  = "test-polka-key"
+)
+
+func newTestAPI(t *testing.T) (*ApiConfig, *httptest.Server) {
+	t.Helper()
+	blobs, err := media.NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ApiConfig{
+		DB:                  database.NewMemStore(),
+		Platform:            "dev",
+		JWTKey:              auth.NewHMACKey("", testJWTSecret),
+		JWTKeyEncryptionKey: bytes.Repeat([]byte{7}, 32),
+		Payments:            &payments.Polka{APIKey: testPolkaKey},
+		Blobs:               blobs,
+	}
+	mux := http.NewServeMux()
+	cfg.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return cfg, srv
+}
+
+// doJSON sends body (if non-nil) as JSON and decodes the response into out
+// (if non-nil), returning the status code.
+func doJSON(t *testing.T, method, url, authz string, body, out interface{}) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encode request: %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if authz != "" {
+		req.Header.Set("Authorization", authz)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("decode %s %s response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+type loginResponse struct {
+	User
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// signup creates a user and logs them in.
+func signup(t *testing.T, srv *httptest.Server, email string) loginResponse {
+	t.Helper()
+	creds := map[string]string{"email": email, "password": "hunter2"}
+	if code := doJSON(t, "POST", srv.URL+"/api/users", "", creds, nil); code != http.StatusCreated {
+		t.Fatalf("create user %s: status %d", email, code)
+	}
+	var login loginResponse
+	if code := doJSON(t, "POST", srv.URL+"/api/login", "", creds, &login); code != http.StatusOK {
+		t.Fatalf("login %s: status %d", email, code)
+	}
+	return login
+}
+
+func TestCreateUser_DuplicateEmail(t *testing.T) {
+	_, srv := newTestAPI(t)
+	signup(t, srv, "dup@example.com")
+
+	creds := map[string]string{"email": "dup@example.com", "password": "other"}
+	if code := doJSON(t, "POST", srv.URL+"/api/users", "", creds, nil); code == http.StatusCreated {
+		t.Fatal("expected duplicate email to be rejected")
+	}
+}
+
+func TestLogin_WrongPassword(t *testing.T) {
+	_, srv := newTestAPI(t)
+	signup(t, srv, "a@example.com")
+
+	creds := map[string]string{"email": "a@example.com", "password": "wrong"}
+	if code := doJSON(t, "POST", srv.URL+"/api/login", "", creds, nil); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", code)
+	}
+}
+
+func TestChirpLifecycle(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+
+	var created struct {
+		ID   string `json:"id"`
+		Body string `json:"body"`
+	}
+	code := doJSON(t, "POST", srv.URL+"/api/chirps", "Bearer "+alice.Token,
+		map[string]string{"body": "what a kerfuffle"}, &created)
+	if code != http.StatusCreated {
+		t.Fatalf("create chirp: status %d", code)
+	}
+	if created.Body != "what a ****" {
+		t.Errorf("expected profanity to be cleaned, got %q", created.Body)
+	}
+
+	if code := doJSON(t, "GET", srv.URL+"/api/chirps/"+created.ID, "", nil, nil); code != http.StatusOK {
+		t.Fatalf("get chirp: status %d", code)
+	}
+	if code := doJSON(t, "DELETE", srv.URL+"/api/chirps/"+created.ID, "Bearer "+bob.Token, nil, nil); code != http.StatusForbidden {
+		t.Fatalf("delete by non-author: expected 403, got %d", code)
+	}
+	if code := doJSON(t, "DELETE", srv.URL+"/api/chirps/"+created.ID, "Bearer "+alice.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("delete by author: expected 204, got %d", code)
+	}
+	if code := doJSON(t, "GET", srv.URL+"/api/chirps/"+created.ID, "", nil, nil); code != http.StatusNotFound {
+		t.Fatalf("get deleted chirp: expected 404, got %d", code)
+	}
+}
+
+func TestRefreshAndRevoke(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+
+	var refreshed struct {
+		Token string `json:"token"`
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/refresh", "Bearer "+alice.RefreshToken, nil, &refreshed); code != http.StatusOK {
+		t.Fatalf("refresh: status %d", code)
+	}
+	if refreshed.Token == "" {
+		t.Fatal("expected a new access token")
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/revoke", "Bearer "+alice.RefreshToken, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("revoke: status %d", code)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/api/refresh", "Bearer "+alice.RefreshToken, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("refresh after revoke: expected 401, got %d", code)
+	}
+}
+
+func TestRefreshRotation(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	var otherSession loginResponse
+	creds := map[string]string{"email": "alice@example.com", "password": "hunter2"}
+	if code := doJSON(t, "POST", srv.URL+"/api/login", "", creds, &otherSession); code != http.StatusOK {
+		t.Fatalf("second login: status %d", code)
+	}
+
+	type refreshResponse struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	refresh := func(token string) (refreshResponse, int) {
+		t.Helper()
+		var out refreshResponse
+		code := doJSON(t, "POST", srv.URL+"/api/refresh", "Bearer "+token, nil, &out)
+		return out, code
+	}
+
+	first, code := refresh(alice.RefreshToken)
+	if code != http.StatusOK || first.RefreshToken == "" || first.RefreshToken == alice.RefreshToken {
+		t.Fatalf("refresh: status %d, %+v", code, first)
+	}
+	second, code := refresh(first.RefreshToken)
+	if code != http.StatusOK {
+		t.Fatalf("refresh with rotated token: status %d", code)
+	}
+
+	// replaying a rotated token revokes the whole family...
+	if _, code := refresh(alice.RefreshToken); code != http.StatusUnauthorized {
+		t.Fatalf("reuse: expected 401, got %d", code)
+	}
+	if _, code := refresh(second.RefreshToken); code != http.StatusUnauthorized {
+		t.Fatalf("latest token after reuse: expected 401, got %d", code)
+	}
+	// ...but not other sessions
+	if _, code := refresh(otherSession.RefreshToken); code != http.StatusOK {
+		t.Fatalf("other session: status %d", code)
+	}
+}
@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 
 	"log"
 	"net/http"
+	"regexp"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/auth"
 )
 
 func RespondWithError(w http.ResponseWriter, code int, msg string) {
@@ -26,11 +33,88 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.WriteHeader(code)
 	w.Write(d)
 }
+// requireUser validates the bearer access token and returns its user ID. On
+// failure it writes a 401 and returns false.
+func (cfg *ApiConfig) requireUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	token, ok := cfg.requireAccessToken(w, r)
+	return token.UserID, ok
+}
+
+// requireAccessToken is requireUser for handlers that also need to know
+// which session the caller's access token belongs to.
+func (cfg *ApiConfig) requireAccessToken(w http.ResponseWriter, r *http.Request) (auth.AccessToken, bool) {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "Missing or invalid token")
+		return auth.AccessToken{}, false
+	}
+	token, err := cfg.parseJWT(r.Context(), tokenStr)
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return auth.AccessToken{}, false
+	}
+	return token, true
+}
+
+// parseJWT validates an access token, rejecting it if the user has
+// revoked all sessions since it was issued.
+func (cfg *ApiConfig) parseJWT(ctx context.Context, tokenStr string) (auth.AccessToken, error) {
+	return auth.ParseJWT(tokenStr, cfg.keys(), func(userID uuid.UUID) (int32, error) {
+		return cfg.DB.GetUserTokenVersion(ctx, userID)
+	})
+}
+
+func (cfg *ApiConfig) validateJWT(ctx context.Context, tokenStr string) (uuid.UUID, error) {
+	token, err := cfg.parseJWT(ctx, tokenStr)
+	return token.UserID, err
+}
+
+// requireAdmin checks the admin API key, writing a 401 if it is missing or
+// wrong. Admin endpoints are disabled when no AdminKey is configured.
+func (cfg *ApiConfig) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if err := auth.CheckAPIKey(r.Header, cfg.AdminKey); err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "Invalid API Key")
+		return false
+	}
+	return true
+}
+
+// runEvery calls fn straight away and then every interval until ctx is
+// cancelled.
+func runEvery(ctx context.Context, interval time.Duration, fn func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fn(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// viewerID returns the caller's user ID on endpoints where signing in is
+// optional. A missing or invalid token just means an anonymous viewer.
+func (cfg *ApiConfig) viewerID(r *http.Request) uuid.NullUUID {
+	tokenStr, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	userID, err := cfg.validateJWT(r.Context(), tokenStr)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+// CleanProfanity masks profane words, including when used as a #hashtag,
+// so tags extracted from the cleaned body never contain them.
 func CleanProfanity(input string) string {
 	profaneWords := []string{"kerfuffle", "sharbert", "fornax"}
 	words := strings.Split(input, " ")
 	for i, word := range words {
-		lower := strings.ToLower(word)
+		lower := strings.TrimPrefix(strings.ToLower(word), "#")
 		for _, profane := range profaneWords {
 			if lower == profane {
 				words[i] = "****"
@@ -40,3 +124,54 @@ func CleanProfanity(input string) string {
 	return strings.Join(words, " ")
 }
 
+
+const maxHashtagLen = 50
+
+var hashtagRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+
+// ExtractHashtags returns the distinct hashtags in body, lowercased and
+// without the leading #, in order of first use. Tags must contain a letter
+// and be at most 50 characters.
+func ExtractHashtags(body string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, m := range hashtagRe.FindAllStringSubmatch(body, -1) {
+		tag := strings.ToLower(m[1])
+		if seen[tag] || utf8.RuneCountInString(tag) > maxHashtagLen || !strings.ContainsFunc(tag, unicode.IsLetter) {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+const maxUsernameLen = 30
+
+var (
+	mentionRe  = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#@])@([A-Za-z0-9_]+)`)
+	usernameRe = regexp.MustCompile(`^[a-z0-9_]{1,30}$`)
+)
+
+// NormalizeUsername lowercases name and strips a leading @, reporting
+// whether the result is a valid username.
+func NormalizeUsername(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "@"))
+	return name, usernameRe.MatchString(name)
+}
+
+// ExtractMentions returns the distinct usernames @mentioned in body,
+// lowercased and without the leading @, in order of first use.
+func ExtractMentions(body string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range mentionRe.FindAllStringSubmatch(body, -1) {
+		name := strings.ToLower(m[1])
+		if seen[name] || len(name) > maxUsernameLen {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
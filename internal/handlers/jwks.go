@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// jwksMaxAge is how long verifiers may cache the JWKS.
+const jwksMaxAge = 5 * time.Minute
+
+// HandleJWKS serves GET /.well-known/jwks.json: the public keys access
+// tokens can be verified with, including keys not yet signing and keys
+// replaced within the overlap period. Keys signed with a shared HMAC
+// secret are never published.
+func (cfg *ApiConfig) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(jwksMaxAge.Seconds())))
+	RespondWithJSON(w, http.StatusOK, cfg.keys().JWKS(time.Now()))
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kavancamp/chirpy/internal/auth"
+)
+
+func TestJWKS(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+
+	var set auth.JWKS
+	if code := doJSON(t, "GET", srv.URL+"/.well-known/jwks.json", "", nil, &set); code != http.StatusOK {
+		t.Fatalf("jwks: status %d", code)
+	}
+	if len(set.Keys) != 0 {
+		t.Fatalf("expected no public keys for an HMAC secret, got %+v", set.Keys)
+	}
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.JWTKey, err = auth.NewKey("", private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := signup(t, srv, "alice@example.com")
+
+	set = auth.JWKS{}
+	doJSON(t, "GET", srv.URL+"/.well-known/jwks.json", "", nil, &set)
+	if len(set.Keys) != 1 || set.Keys[0].Kid != cfg.JWTKey.ID {
+		t.Fatalf("expected the signing key, got %+v", set.Keys)
+	}
+
+	// a third party can verify the access token with only the JWKS
+	x, err := base64.RawURLEncoding.DecodeString(set.Keys[0].X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Parse(alice.Token, func(tok *jwt.Token) (interface{}, error) {
+		return ed25519.PublicKey(x), nil
+	}, jwt.WithValidMethods([]string{"EdDSA"}))
+	if err != nil || !token.Valid {
+		t.Fatalf("verify with published key: %v", err)
+	}
+	if token.Header["kid"] != set.Keys[0].Kid {
+		t.Errorf("token kid %v, want %s", token.Header["kid"], set.Keys[0].Kid)
+	}
+
+	if code := doJSON(t, "GET", srv.URL+"/api/sessions", "Bearer "+alice.Token, nil, nil); code != http.StatusOK {
+		t.Fatalf("use EdDSA token: status %d", code)
+	}
+}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/kavancamp/chirpy/internal/auth"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+// JWTKey describes a signing key in jwt_keys, without its private half.
+type JWTKey struct {
+	ID          string     `json:"id"`
+	Algorithm   string     `json:"algorithm"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ActivatedAt *time.Time `json:"activated_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Signing     bool       `json:"signing"`
+}
+
+// keys is the key ring access tokens are signed and verified with. Until
+// LoadKeyRing has run it holds only JWTKey.
+func (cfg *ApiConfig) keys() *auth.KeyRing {
+	if ring := cfg.keyRing.Load(); ring != nil {
+		return ring
+	}
+	return auth.NewKeyRing(cfg.JWTKey)
+}
+
+// MinJWTKeyOverlap is the shortest overlap that lets everything a replaced
+// key signed run out naturally: access tokens, and the email verification
+// links signed with the same keys.
+const MinJWTKeyOverlap = max(accessTokenTTL, emailVerificationTTL)
+
+// KeyRingRefreshInterval is how often every instance reloads jwt_keys.
+const KeyRingRefreshInterval = time.Minute
+
+func (cfg *ApiConfig) jwtKeyPromotionDelay() time.Duration {
+	if cfg.JWTKeyPromotionDelay > 0 {
+		return cfg.JWTKeyPromotionDelay
+	}
+	return KeyRingRefreshInterval + jwksMaxAge
+}
+
+func (cfg *ApiConfig) jwtKeyOverlap() time.Duration {
+	if cfg.JWTKeyOverlap > 0 {
+		return cfg.JWTKeyOverlap
+	}
+	return MinJWTKeyOverlap
+}
+
+// parseJWTKey reads the private key of a row in jwt_keys, decrypting it
+// with JWTKeyEncryptionKey. Rows stored before keys were encrypted hold
+// plain PEM.
+func (cfg *ApiConfig) parseJWTKey(row database.JwtKey) (*auth.Key, error) {
+	data := []byte(row.PrivateKey)
+	if auth.IsSealedKey(row.PrivateKey) {
+		if cfg.JWTKeyEncryptionKey == nil {
+			return nil, errors.New("no key-encryption key configured")
+		}
+		var err error
+		if data, err = auth.OpenKeyPEM(cfg.JWTKeyEncryptionKey, row.ID, row.PrivateKey); err != nil {
+			return nil, err
+		}
+	}
+	return auth.ParseKeyPEM(row.ID, data)
+}
+
+// sealJWTKey encrypts a plain PEM key left over from before keys were
+// encrypted.
+func (cfg *ApiConfig) sealJWTKey(ctx context.Context, row database.JwtKey) error {
+	sealed, err := auth.SealKeyPEM(cfg.JWTKeyEncryptionKey, row.ID, []byte(row.PrivateKey))
+	if err != nil {
+		return err
+	}
+	return cfg.DB.UpdateJWTKeyPrivateKey(ctx, database.UpdateJWTKeyPrivateKeyParams{
+		ID:         row.ID,
+		PrivateKey: sealed,
+	})
+}
+
+// LoadKeyRing rebuilds the key ring from jwt_keys. The most recently
+// activated key signs new tokens, falling back to JWTKey until a key has
+// been activated; JWTKey is then accepted for the overlap period after the
+// first activation, as a replaced key would be. Keys still stored as
+// plain PEM are encrypted along the way.
+func (cfg *ApiConfig) LoadKeyRing(ctx context.Context) error {
+	rows, err := cfg.DB.ListJWTKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	type verificationKey struct {
+		key       *auth.Key
+		expiresAt time.Time
+	}
+	var (
+		signing        *auth.Key
+		signingSince   time.Time
+		firstActivated time.Time
+		verify         []verificationKey
+	)
+	for _, row := range rows {
+		if row.ActivatedAt.Valid && (firstActivated.IsZero() || row.ActivatedAt.Time.Before(firstActivated)) {
+			firstActivated = row.ActivatedAt.Time
+		}
+		if row.ExpiresAt.Valid && !row.ExpiresAt.Time.After(now) {
+			continue
+		}
+		key, err := cfg.parseJWTKey(row)
+		if err != nil {
+			log.Printf("error loading JWT key %s: %s", row.ID, err)
+			continue
+		}
+		if !auth.IsSealedKey(row.PrivateKey) && cfg.JWTKeyEncryptionKey != nil {
+			if err := cfg.sealJWTKey(ctx, row); err != nil {
+				log.Printf("error encrypting JWT key %s: %s", row.ID, err)
+			}
+		}
+		if row.ActivatedAt.Valid && !row.ActivatedAt.Time.After(now) && row.ActivatedAt.Time.After(signingSince) {
+			signing, signingSince = key, row.ActivatedAt.Time
+		}
+		verify = append(verify, verificationKey{key: key, expiresAt: row.ExpiresAt.Time})
+	}
+
+	if signing == nil {
+		signing = cfg.JWTKey
+	}
+	ring := auth.NewKeyRing(signing)
+	if !firstActivated.IsZero() {
+		if expiresAt := firstActivated.Add(cfg.jwtKeyOverlap()); now.Before(expiresAt) {
+			ring.AddVerificationKey(cfg.JWTKey, expiresAt)
+		}
+	}
+	for _, v := range verify {
+		ring.AddVerificationKey(v.key, v.expiresAt)
+	}
+	cfg.keyRing.Store(ring)
+	return nil
+}
+
+// RunKeyRingRefresh reloads the key ring every interval until ctx is done,
+// picking up keys added, promoted or retired through another instance.
+func (cfg *ApiConfig) RunKeyRingRefresh(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func(ctx context.Context) {
+		if err := cfg.LoadKeyRing(ctx); err != nil {
+			log.Printf("error loading JWT keys: %s", err)
+		}
+	})
+}
+
+func (cfg *ApiConfig) jwtKeyJSON(row database.JwtKey) JWTKey {
+	out := JWTKey{
+		ID:        row.ID,
+		CreatedAt: row.CreatedAt,
+		Signing:   cfg.keys().SigningKey().ID == row.ID,
+	}
+	if key, err := cfg.parseJWTKey(row); err == nil {
+		out.Algorithm = key.Algorithm()
+	}
+	if row.ActivatedAt.Valid {
+		out.ActivatedAt = &row.ActivatedAt.Time
+	}
+	if row.ExpiresAt.Valid {
+		out.ExpiresAt = &row.ExpiresAt.Time
+	}
+	return out
+}
+
+// HandleListJWTKeys serves GET /admin/jwt-keys, oldest first.
+func (cfg *ApiConfig) HandleListJWTKeys(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+
+	rows, err := cfg.DB.ListJWTKeys(r.Context())
+	if err != nil {
+		log.Printf("error listing JWT keys: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not list keys")
+		return
+	}
+	out := make([]JWTKey, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, cfg.jwtKeyJSON(row))
+	}
+	RespondWithJSON(w, http.StatusOK, out)
+}
+
+// HandleCreateJWTKey serves POST /admin/jwt-keys. The new key is published
+// in the JWKS straight away but only signs tokens once promoted, so every
+// instance and verifier can learn it first.
+func (cfg *ApiConfig) HandleCreateJWTKey(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+
+	body := struct {
+		Algorithm string `json:"algorithm"`
+	}{Algorithm: "EdDSA"}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if cfg.JWTKeyEncryptionKey == nil {
+		RespondWithError(w, http.StatusInternalServerError, "Set JWT_KEY_ENCRYPTION_KEY to create keys")
+		return
+	}
+	key, err := auth.GenerateKey(body.Algorithm)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Algorithm must be RS256 or EdDSA")
+		return
+	}
+	var sealed string
+	private, err := key.MarshalPEM()
+	if err == nil {
+		sealed, err = auth.SealKeyPEM(cfg.JWTKeyEncryptionKey, key.ID, private)
+	}
+	if err != nil {
+		log.Printf("error encoding JWT key: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not create key")
+		return
+	}
+	row, err := cfg.DB.CreateJWTKey(r.Context(), database.CreateJWTKeyParams{
+		ID:         key.ID,
+		PrivateKey: sealed,
+		CreatedAt:  time.Now().UTC(),
+	})
+	if database.IsUniqueViolation(err) {
+		RespondWithError(w, http.StatusConflict, "Key already exists")
+		return
+	}
+	if err != nil {
+		log.Printf("error storing JWT key: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not create key")
+		return
+	}
+
+	cfg.reloadKeyRing(r.Context())
+	RespondWithJSON(w, http.StatusCreated, cfg.jwtKeyJSON(row))
+}
+
+// HandlePromoteJWTKey serves POST /admin/jwt-keys/{id}/promote: the key
+// signs new tokens from now on, and the keys it replaces are accepted for
+// the overlap period so tokens they signed run out naturally. A key is only
+// promoted once every instance and JWKS cache can have picked it up.
+func (cfg *ApiConfig) HandlePromoteJWTKey(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+
+	id := r.PathValue("id")
+	now := time.Now().UTC()
+	rows, err := cfg.DB.ListJWTKeys(r.Context())
+	if err != nil {
+		log.Printf("error listing JWT keys: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not promote key")
+		return
+	}
+	for _, row := range rows {
+		if row.ID != id {
+			continue
+		}
+		if ready := row.CreatedAt.Add(cfg.jwtKeyPromotionDelay()); now.Before(ready) {
+			RespondWithError(w, http.StatusConflict, "Key can be promoted from "+ready.Format(time.RFC3339))
+			return
+		}
+	}
+
+	n, err := cfg.DB.ActivateJWTKey(r.Context(), database.ActivateJWTKeyParams{
+		ID:          id,
+		ActivatedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		log.Printf("error promoting JWT key %s: %s", id, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not promote key")
+		return
+	}
+	if n == 0 {
+		RespondWithError(w, http.StatusNotFound, "Key not found or retired")
+		return
+	}
+
+	rows, err = cfg.DB.ListJWTKeys(r.Context())
+	if err != nil {
+		log.Printf("error listing JWT keys: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not promote key")
+		return
+	}
+	var promoted database.JwtKey
+	for _, row := range rows {
+		if row.ID == id {
+			promoted = row
+			continue
+		}
+		if !row.ActivatedAt.Valid {
+			continue
+		}
+		_, err := cfg.DB.ExpireJWTKey(r.Context(), database.ExpireJWTKeyParams{
+			ID:        row.ID,
+			ExpiresAt: sql.NullTime{Time: now.Add(cfg.jwtKeyOverlap()), Valid: true},
+		})
+		if err != nil {
+			log.Printf("error retiring JWT key %s: %s", row.ID, err)
+			RespondWithError(w, http.StatusInternalServerError, "Could not promote key")
+			return
+		}
+	}
+
+	cfg.reloadKeyRing(r.Context())
+	RespondWithJSON(w, http.StatusOK, cfg.jwtKeyJSON(promoted))
+}
+
+// HandleRetireJWTKey serves DELETE /admin/jwt-keys/{id}, which stops
+// accepting tokens signed by the key at once. The signing key cannot be
+// retired; promote another key first.
+func (cfg *ApiConfig) HandleRetireJWTKey(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+
+	id := r.PathValue("id")
+	if cfg.keys().SigningKey().ID == id {
+		RespondWithError(w, http.StatusConflict, "Promote another key before retiring the signing key")
+		return
+	}
+	n, err := cfg.DB.ExpireJWTKey(r.Context(), database.ExpireJWTKeyParams{
+		ID:        id,
+		ExpiresAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error retiring JWT key %s: %s", id, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retire key")
+		return
+	}
+	if n == 0 {
+		RespondWithError(w, http.StatusNotFound, "Key not found or already retired")
+		return
+	}
+
+	cfg.reloadKeyRing(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reloadKeyRing applies a change to jwt_keys on this instance without
+// waiting for the next refresh.
+func (cfg *ApiConfig) reloadKeyRing(ctx context.Context) {
+	if err := cfg.LoadKeyRing(ctx); err != nil {
+		log.Printf("error loading JWT keys: %s", err)
+	}
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kavancamp/chirpy/internal/auth"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+// tokenKid returns the kid header of an access token.
+func tokenKid(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	cfg.AdminKey = testAdminKey
+	cfg.JWTKeyPromotionDelay = 1 // effectively none
+	admin := "ApiKey " + testAdminKey
+	creds := map[string]string{"email": "alice@example.com", "password": "hunter2"}
+	login := func() loginResponse {
+		t.Helper()
+		var out loginResponse
+		if code := doJSON(t, "POST", srv.URL+"/api/login", "", creds, &out); code != http.StatusOK {
+			t.Fatalf("login: status %d", code)
+		}
+		return out
+	}
+	authorized := func(token string) bool {
+		t.Helper()
+		return doJSON(t, "GET", srv.URL+"/api/sessions", "Bearer "+token, nil, nil) == http.StatusOK
+	}
+	jwks := func() auth.JWKS {
+		t.Helper()
+		var set auth.JWKS
+		doJSON(t, "GET", srv.URL+"/.well-known/jwks.json", "", nil, &set)
+		return set
+	}
+
+	hmacToken := signup(t, srv, "alice@example.com").Token
+
+	if code := doJSON(t, "POST", srv.URL+"/admin/jwt-keys", "", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("create key without admin key: expected 401, got %d", code)
+	}
+	var first JWTKey
+	if code := doJSON(t, "POST", srv.URL+"/admin/jwt-keys", admin, map[string]string{"algorithm": "EdDSA"}, &first); code != http.StatusCreated {
+		t.Fatalf("create key: status %d", code)
+	}
+	if first.Algorithm != "EdDSA" || first.Signing || first.ActivatedAt != nil {
+		t.Fatalf("unexpected new key %+v", first)
+	}
+	// published ahead of use...
+	if set := jwks(); len(set.Keys) != 1 || set.Keys[0].Kid != first.ID {
+		t.Fatalf("expected the new key in the JWKS, got %+v", set.Keys)
+	}
+	// ...but not signing yet
+	if kid := tokenKid(t, login().Token); kid != "" {
+		t.Fatalf("expected the configured key to sign before promotion, got kid %q", kid)
+	}
+
+	var promoted JWTKey
+	if code := doJSON(t, "POST", srv.URL+"/admin/jwt-keys/"+first.ID+"/promote", admin, nil, &promoted); code != http.StatusOK {
+		t.Fatalf("promote: status %d", code)
+	}
+	if !promoted.Signing || promoted.ActivatedAt == nil {
+		t.Fatalf("unexpected promoted key %+v", promoted)
+	}
+	firstToken := login().Token
+	if kid := tokenKid(t, firstToken); kid != first.ID {
+		t.Fatalf("expected tokens signed by %s, got kid %q", first.ID, kid)
+	}
+	// tokens signed before the rotation keep working through the overlap
+	if !authorized(hmacToken) || !authorized(firstToken) {
+		t.Fatal("expected tokens from both keys to be accepted")
+	}
+
+	if code := doJSON(t, "DELETE", srv.URL+"/admin/jwt-keys/"+first.ID, admin, nil, nil); code != http.StatusConflict {
+		t.Fatalf("retire signing key: expected 409, got %d", code)
+	}
+
+	var second JWTKey
+	if code := doJSON(t, "POST", srv.URL+"/admin/jwt-keys", admin, map[string]string{"algorithm": "RS256"}, &second); code != http.StatusCreated {
+		t.Fatalf("create second key: status %d", code)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/admin/jwt-keys/"+second.ID+"/promote", admin, nil, nil); code != http.StatusOK {
+		t.Fatalf("promote second key: status %d", code)
+	}
+	if kid := tokenKid(t, login().Token); kid != second.ID {
+		t.Fatalf("expected tokens signed by %s, got kid %q", second.ID, kid)
+	}
+
+	var keys []JWTKey
+	doJSON(t, "GET", srv.URL+"/admin/jwt-keys", admin, nil, &keys)
+	if len(keys) != 2 || keys[0].ExpiresAt == nil || keys[1].ExpiresAt != nil || !keys[1].Signing {
+		t.Fatalf("expected the first key to be expiring and the second signing, got %+v", keys)
+	}
+
+	// retiring a replaced key cuts its overlap short
+	if code := doJSON(t, "DELETE", srv.URL+"/admin/jwt-keys/"+first.ID, admin, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("retire first key: status %d", code)
+	}
+	if authorized(firstToken) {
+		t.Fatal("expected tokens from a retired key to be rejected")
+	}
+	if code := doJSON(t, "POST", srv.URL+"/admin/jwt-keys/"+first.ID+"/promote", admin, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("promote retired key: expected 404, got %d", code)
+	}
+	if set := jwks(); len(set.Keys) != 1 || set.Keys[0].Kid != second.ID {
+		t.Fatalf("expected only the second key in the JWKS, got %+v", set.Keys)
+	}
+}
+
+func TestJWTKeyRotation_ZeroOverlapEndsOldTokens(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	cfg.AdminKey = testAdminKey
+	cfg.JWTKeyPromotionDelay = 1 // effectively none
+	cfg.JWTKeyOverlap = 1        // effectively none
+	admin := "ApiKey " + testAdminKey
+	alice := signup(t, srv, "alice@example.com")
+
+	var key JWTKey
+	doJSON(t, "POST", srv.URL+"/admin/jwt-keys", admin, nil, &key)
+	if key.Algorithm != "EdDSA" {
+		t.Fatalf("expected EdDSA by default, got %+v", key)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/admin/jwt-keys/"+key.ID+"/promote", admin, nil, nil); code != http.StatusOK {
+		t.Fatalf("promote: status %d", code)
+	}
+	if code := doJSON(t, "GET", srv.URL+"/api/sessions", "Bearer "+alice.Token, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("token from the replaced key: expected 401, got %d", code)
+	}
+}
+
+func TestJWTKeyRotation_RepromoteDuringOverlap(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	cfg.AdminKey = testAdminKey
+	cfg.JWTKeyPromotionDelay = 1 // effectively none
+	cfg.JWTKeyOverlap = 50 * time.Millisecond
+	admin := "ApiKey " + testAdminKey
+	signup(t, srv, "alice@example.com")
+
+	var a, b JWTKey
+	doJSON(t, "POST", srv.URL+"/admin/jwt-keys", admin, nil, &a)
+	doJSON(t, "POST", srv.URL+"/admin/jwt-keys", admin, nil, &b)
+	for _, id := range []string{a.ID, b.ID, a.ID} {
+		if code := doJSON(t, "POST", srv.URL+"/admin/jwt-keys/"+id+"/promote", admin, nil, nil); code != http.StatusOK {
+			t.Fatalf("promote %s: status %d", id, code)
+		}
+	}
+
+	// once b's overlap has run out, a is still the signing key
+	time.Sleep(2 * cfg.JWTKeyOverlap)
+	if err := cfg.LoadKeyRing(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var out loginResponse
+	creds := map[string]string{"email": "alice@example.com", "password": "hunter2"}
+	if code := doJSON(t, "POST", srv.URL+"/api/login", "", creds, &out); code != http.StatusOK {
+		t.Fatalf("login: status %d", code)
+	}
+	if kid := tokenKid(t, out.Token); kid != a.ID {
+		t.Fatalf("expected tokens signed by %s, got kid %q", a.ID, kid)
+	}
+	if code := doJSON(t, "GET", srv.URL+"/api/sessions", "Bearer "+out.Token, nil, nil); code != http.StatusOK {
+		t.Fatalf("token from the re-promoted key: status %d", code)
+	}
+}
+
+func TestJWTKeyRotation_PromotionWaitsForPropagation(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	cfg.AdminKey = testAdminKey
+	admin := "ApiKey " + testAdminKey
+
+	var key JWTKey
+	doJSON(t, "POST", srv.URL+"/admin/jwt-keys", admin, nil, &key)
+	if code := doJSON(t, "POST", srv.URL+"/admin/jwt-keys/"+key.ID+"/promote", admin, nil, nil); code != http.StatusConflict {
+		t.Fatalf("promote a new key: expected 409, got %d", code)
+	}
+	if signing := cfg.keys().SigningKey(); signing.ID == key.ID {
+		t.Fatal("expected the new key not to sign yet")
+	}
+}
+
+func TestJWTKeysEncryptedAtRest(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	cfg.AdminKey = testAdminKey
+	admin := "ApiKey " + testAdminKey
+	ctx := context.Background()
+
+	var created JWTKey
+	if code := doJSON(t, "POST", srv.URL+"/admin/jwt-keys", admin, nil, &created); code != http.StatusCreated {
+		t.Fatalf("create key: status %d", code)
+	}
+	// a key stored in plain PEM before keys were encrypted
+	legacy, err := auth.GenerateKey("EdDSA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := legacy.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cfg.DB.CreateJWTKey(ctx, database.CreateJWTKeyParams{ID: legacy.ID, PrivateKey: string(private), CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.LoadKeyRing(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := cfg.DB.ListJWTKeys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if !auth.IsSealedKey(row.PrivateKey) {
+			t.Errorf("key %s stored unencrypted", row.ID)
+		}
+	}
+	if set := cfg.keys().JWKS(time.Now()); len(set.Keys) != 2 {
+		t.Fatalf("expected both keys to load, got %+v", set.Keys)
+	}
+
+	cfg.JWTKeyEncryptionKey = nil
+	if code := doJSON(t, "POST", srv.URL+"/admin/jwt-keys", admin, nil, nil); code != http.StatusInternalServerError {
+		t.Fatalf("create key without a key-encryption key: expected 500, got %d", code)
+	}
+}
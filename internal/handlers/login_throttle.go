@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/auth"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+// loginPolicy says how repeated login failures against one key are slowed
+// down: after FreeFailures, each failure makes the key wait twice as long
+// as the last before trying again, and LockAfter failures lock it for
+// Lockout.
+type loginPolicy struct {
+	FreeFailures int
+	LockAfter    int
+	Lockout      time.Duration
+}
+
+// Failures are counted per email and per client IP. An IP may be shared by
+// many people, so it is allowed more.
+var (
+	emailLoginPolicy = loginPolicy{FreeFailures: 3, LockAfter: 10, Lockout: 15 * time.Minute}
+	ipLoginPolicy    = loginPolicy{FreeFailures: 10, LockAfter: 50, Lockout: 15 * time.Minute}
+)
+
+// loginFailureWindow is how long a key's failures are remembered; a
+// failure after a longer quiet spell starts the count again.
+const loginFailureWindow = time.Hour
+
+// loginAttemptRetention is how long login attempts are kept for auditing.
+const loginAttemptRetention = 90 * 24 * time.Hour
+
+// Reasons recorded on failed login attempts.
+const (
+	loginReasonLocked      = "locked"
+	loginReasonUnknownUser = "unknown_user"
+	loginReasonBadPassword = "bad_password"
+)
+
+func (p loginPolicy) delay(failures int) time.Duration {
+	switch {
+	case failures >= p.LockAfter:
+		return p.Lockout
+	case failures <= p.FreeFailures:
+		return 0
+	default:
+		return min(time.Second<<(failures-p.FreeFailures-1), p.Lockout)
+	}
+}
+
+type loginKey struct {
+	key    string
+	policy loginPolicy
+}
+
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func (cfg *ApiConfig) loginKeys(r *http.Request, email string) []loginKey {
+	return []loginKey{
+		{emailThrottleKey(email), emailLoginPolicy},
+		{"ip:" + cfg.clientIP(r), ipLoginPolicy},
+	}
+}
+
+// loginRetryAfter returns how long until a login may be tried for keys,
+// or zero if it may be tried now.
+func (cfg *ApiConfig) loginRetryAfter(ctx context.Context, keys []loginKey, now time.Time) (time.Duration, error) {
+	var wait time.Duration
+	for _, k := range keys {
+		t, err := cfg.DB.GetLoginThrottle(ctx, k.key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if t.LockedUntil.Valid {
+			wait = max(wait, t.LockedUntil.Time.Sub(now))
+		}
+	}
+	return wait, nil
+}
+
+// recordLoginFailure counts a failure against every key, locking any that
+// have failed too often.
+func (cfg *ApiConfig) recordLoginFailure(ctx context.Context, keys []loginKey, now time.Time) {
+	for _, k := range keys {
+		t, err := cfg.DB.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+			Key:         k.key,
+			Now:         now,
+			ResetBefore: now.Add(-loginFailureWindow),
+		})
+		if err != nil {
+			log.Printf("error recording login failure for %s: %s", k.key, err)
+			continue
+		}
+		if d := k.policy.delay(int(t.Failures)); d > 0 {
+			if err := cfg.DB.SetLoginThrottleLock(ctx, database.SetLoginThrottleLockParams{
+				Key:         k.key,
+				LockedUntil: sql.NullTime{Time: now.Add(d), Valid: true},
+			}); err != nil {
+				log.Printf("error locking %s: %s", k.key, err)
+			}
+		}
+	}
+}
+
+// recordLoginAttempt adds an attempt to the audit log. reason is empty for
+// a successful login.
+func (cfg *ApiConfig) recordLoginAttempt(ctx context.Context, r *http.Request, email string, userID uuid.NullUUID, reason string) {
+	err := cfg.DB.CreateLoginAttempt(ctx, database.CreateLoginAttemptParams{
+		Email:     email,
+		Ip:        cfg.clientIP(r),
+		UserID:    userID,
+		Succeeded: reason == "",
+		Reason:    sql.NullString{String: reason, Valid: reason != ""},
+	})
+	if err != nil {
+		log.Printf("error recording login attempt for %s: %s", email, err)
+	}
+}
+
+// dummyPasswordHash is checked against when a login names no account, so
+// that it takes as long as a wrong password and does not give away which
+// emails are registered.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword("not the password of any account")
+	if err != nil {
+		log.Printf("error hashing dummy password: %s", err)
+	}
+	return hash
+})
+
+// RunLoginThrottleSweep forgets login failures older than the failure
+// window every interval until ctx is done.
+func (cfg *ApiConfig) RunLoginThrottleSweep(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func(ctx context.Context) {
+		if _, err := cfg.DB.DeleteStaleLoginThrottles(ctx, time.Now().UTC().Add(-loginFailureWindow)); err != nil {
+			log.Printf("error sweeping login throttles: %s", err)
+		}
+	})
+}
+
+// RunLoginAttemptSweep deletes login attempts older than
+// loginAttemptRetention every interval until ctx is done.
+func (cfg *ApiConfig) RunLoginAttemptSweep(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func(ctx context.Context) {
+		if _, err := cfg.DB.DeleteOldLoginAttempts(ctx, time.Now().UTC().Add(-loginAttemptRetention)); err != nil {
+			log.Printf("error sweeping login attempts: %s", err)
+		}
+	})
+}
+
+type LoginAttempt struct {
+	ID        uuid.UUID  `json:"id"`
+	Email     string     `json:"email"`
+	IP        string     `json:"ip"`
+	UserID    *uuid.UUID `json:"user_id"`
+	Succeeded bool       `json:"succeeded"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// HandleListLoginAttempts serves GET /admin/login-attempts?email=..., the
+// most recent login attempts for an email, newest first. limit defaults
+// to 50.
+func (cfg *ApiConfig) HandleListLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+
+	email := r.URL.Query().Get("email")
+	if email == "" {
+		RespondWithError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+	limit := 50
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 500 {
+			RespondWithError(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = n
+	}
+
+	attempts, err := cfg.DB.ListLoginAttempts(r.Context(), database.ListLoginAttemptsParams{
+		Email: email,
+		Limit: int32(limit),
+	})
+	if err != nil {
+		log.Printf("error listing login attempts: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve login attempts")
+		return
+	}
+	out := make([]LoginAttempt, 0, len(attempts))
+	for _, a := range attempts {
+		attempt := LoginAttempt{
+			ID:        a.ID,
+			Email:     a.Email,
+			IP:        a.Ip,
+			Succeeded: a.Succeeded,
+			Reason:    a.Reason.String,
+			CreatedAt: a.CreatedAt,
+		}
+		if a.UserID.Valid {
+			attempt.UserID = &a.UserID.UUID
+		}
+		out = append(out, attempt)
+	}
+	RespondWithJSON(w, http.StatusOK, out)
+}
+
+// HandleUnlockUser serves POST /admin/users/{id}/unlock, clearing the
+// failed logins counted against the user's email so they can log in again
+// straight away. Failures counted against client IPs are left alone.
+func (cfg *ApiConfig) HandleUnlockUser(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	user, err := cfg.DB.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error loading user %s: %s", userID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not unlock user")
+		return
+	}
+	if err := cfg.DB.DeleteLoginThrottle(r.Context(), emailThrottleKey(user.Email)); err != nil {
+		log.Printf("error unlocking %s: %s", userID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not unlock user")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
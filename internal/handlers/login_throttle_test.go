@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLoginPolicyDelay(t *testing.T) {
+	p := loginPolicy{FreeFailures: 3, LockAfter: 10, Lockout: 15 * time.Minute}
+	for failures, want := range map[int]time.Duration{
+		0:  0,
+		3:  0,
+		4:  time.Second,
+		5:  2 * time.Second,
+		9:  32 * time.Second,
+		10: 15 * time.Minute,
+		40: 15 * time.Minute,
+	} {
+		if got := p.delay(failures); got != want {
+			t.Errorf("delay(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+// loginFrom logs in as if from ip, returning the response with its error
+// message decoded.
+func loginFrom(t *testing.T, srv *httptest.Server, ip, email, password string) (*http.Response, string) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	req, err := http.NewRequest("POST", srv.URL+"/api/login", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Forwarded-For", ip)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp, out.Error
+}
+
+func TestLoginLockout(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	cfg.AdminKey = testAdminKey
+	cfg.TrustForwardedFor = true
+	alice := signup(t, srv, "alice@example.com")
+	signup(t, srv, "bob@example.com")
+
+	defer func(email, ip loginPolicy) { emailLoginPolicy, ipLoginPolicy = email, ip }(emailLoginPolicy, ipLoginPolicy)
+	emailLoginPolicy = loginPolicy{FreeFailures: 2, LockAfter: 3, Lockout: time.Hour}
+	ipLoginPolicy = loginPolicy{FreeFailures: 5, LockAfter: 6, Lockout: time.Hour}
+
+	_, wrongPassword := loginFrom(t, srv, "192.0.2.1", "alice@example.com", "wrong")
+	for i := 0; i < 2; i++ {
+		loginFrom(t, srv, "192.0.2.1", "alice@example.com", "wrong")
+	}
+
+	// locked out, even with the right password and from another address,
+	// with the same message as a wrong password
+	resp, msg := loginFrom(t, srv, "192.0.2.2", "ALICE@example.com", "hunter2")
+	if resp.StatusCode != http.StatusUnauthorized || msg != wrongPassword || resp.Header.Get("Retry-After") != "3600" {
+		t.Fatalf("locked account: %d %q Retry-After %q", resp.StatusCode, msg, resp.Header.Get("Retry-After"))
+	}
+	// an unknown email gets the same response too
+	if resp, msg := loginFrom(t, srv, "192.0.2.2", "nobody@example.com", "wrong"); resp.StatusCode != http.StatusUnauthorized || msg != wrongPassword {
+		t.Fatalf("unknown email: %d %q", resp.StatusCode, msg)
+	}
+
+	var attempts []LoginAttempt
+	url := srv.URL + "/admin/login-attempts?email=alice@example.com"
+	if code := doJSON(t, "GET", url, "ApiKey "+testAdminKey, nil, &attempts); code != http.StatusOK {
+		t.Fatalf("list attempts: %d", code)
+	}
+	if len(attempts) != 4 || attempts[0].Reason != loginReasonBadPassword || attempts[0].UserID == nil || *attempts[0].UserID != alice.ID {
+		t.Fatalf("attempts: %+v", attempts)
+	}
+	if code := doJSON(t, "GET", url, "", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("list attempts without admin key: expected 401, got %d", code)
+	}
+
+	unlockURL := srv.URL + "/admin/users/" + alice.ID.String() + "/unlock"
+	if code := doJSON(t, "POST", unlockURL, "", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("unlock without admin key: expected 401, got %d", code)
+	}
+	if code := doJSON(t, "POST", unlockURL, "ApiKey "+testAdminKey, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("unlock: %d", code)
+	}
+	if code := doJSON(t, "POST", srv.URL+"/admin/users/"+uuid.NewString()+"/unlock", "ApiKey "+testAdminKey, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("unlock unknown user: expected 404, got %d", code)
+	}
+	if resp, _ := loginFrom(t, srv, "192.0.2.2", "alice@example.com", "hunter2"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("login after unlock: %d", resp.StatusCode)
+	}
+
+	// guessing at many emails from one address locks out that address
+	for i := 0; i < 6; i++ {
+		loginFrom(t, srv, "192.0.2.3", "guess"+string(rune('a'+i))+"@example.com", "wrong")
+	}
+	if resp, _ := loginFrom(t, srv, "192.0.2.3", "bob@example.com", "hunter2"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("locked address: expected 401, got %d", resp.StatusCode)
+	}
+	if resp, _ := loginFrom(t, srv, "192.0.2.4", "bob@example.com", "hunter2"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("another address: %d", resp.StatusCode)
+	}
+
+	// attempts are kept until they pass the retention
+	ctx := context.Background()
+	if n, err := cfg.DB.DeleteOldLoginAttempts(ctx, time.Now().Add(-loginAttemptRetention)); err != nil || n != 0 {
+		t.Fatalf("sweep recent attempts: %d, %v", n, err)
+	}
+	if n, err := cfg.DB.DeleteOldLoginAttempts(ctx, time.Now().Add(time.Minute)); err != nil || n == 0 {
+		t.Fatalf("sweep old attempts: %d, %v", n, err)
+	}
+	if code := doJSON(t, "GET", url, "ApiKey "+testAdminKey, nil, &attempts); code != http.StatusOK || len(attempts) != 0 {
+		t.Fatalf("attempts after sweep: %d %+v", code, attempts)
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+const tierChirpyRed = "chirpy_red"
+
+const (
+	membershipActive     = "active"
+	membershipCancelled  = "cancelled"
+	membershipDowngraded = "downgraded"
+	membershipExpired    = "expired"
+)
+
+type Membership struct {
+	Tier      string     `json:"tier"`
+	Status    string     `json:"status"`
+	StartedAt time.Time  `json:"started_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+}
+
+type MembershipChange struct {
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MembershipStatus struct {
+	IsChirpyRed bool               `json:"is_chirpy_red"`
+	Membership  *Membership        `json:"membership"`
+	History     []MembershipChange `json:"history"`
+}
+
+// isChirpyRed reports whether userID has a Chirpy Red membership that has
+// not run out.
+func (cfg *ApiConfig) isChirpyRed(ctx context.Context, userID uuid.UUID) (bool, error) {
+	_, err := cfg.DB.GetActiveMembership(ctx, database.GetActiveMembershipParams{
+		UserID: userID,
+		Now:    time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// startMembership gives userID Chirpy Red until endsAt, or until further
+// notice when endsAt is not set. An open membership is extended in place,
+// which also takes back a pending cancellation.
+func (cfg *ApiConfig) startMembership(ctx context.Context, userID uuid.UUID, endsAt sql.NullTime, reason string) error {
+	cur, err := cfg.DB.GetCurrentMembership(ctx, userID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	case cur.EndsAt.Valid && !cur.EndsAt.Time.After(time.Now().UTC()):
+		// the open period has already run out; close it and start afresh
+		if err := cfg.setMembershipStatus(ctx, cur, membershipExpired, cur.EndsAt, cur.EndsAt, membershipExpired); err != nil {
+			return err
+		}
+	default:
+		if cur.Status == membershipActive && sameTime(cur.EndsAt, endsAt) {
+			return nil
+		}
+		return cfg.setMembershipStatus(ctx, cur, membershipActive, endsAt, sql.NullTime{}, reason)
+	}
+
+	ms, err := cfg.DB.CreateMembership(ctx, database.CreateMembershipParams{
+		UserID: userID,
+		Tier:   tierChirpyRed,
+		EndsAt: endsAt,
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.recordMembership(ctx, ms, reason)
+}
+
+// endMembership moves userID's open membership to status, ending it at the
+// given time. A time in the future keeps the membership active until then;
+// otherwise it ends straight away. Users without a membership are left
+// alone.
+func (cfg *ApiConfig) endMembership(ctx context.Context, userID uuid.UUID, status string, at time.Time, reason string) error {
+	cur, err := cfg.DB.GetCurrentMembership(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	endsAt := sql.NullTime{Time: at.UTC(), Valid: true}
+	var endedAt sql.NullTime
+	if !at.After(now) {
+		endsAt.Time = now
+		endedAt = endsAt
+	}
+	return cfg.setMembershipStatus(ctx, cur, status, endsAt, endedAt, reason)
+}
+
+func (cfg *ApiConfig) setMembershipStatus(ctx context.Context, ms database.Membership, status string, endsAt, endedAt sql.NullTime, reason string) error {
+	ms, err := cfg.DB.UpdateMembershipStatus(ctx, database.UpdateMembershipStatusParams{
+		ID:      ms.ID,
+		Status:  status,
+		EndsAt:  endsAt,
+		EndedAt: endedAt,
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.recordMembership(ctx, ms, reason)
+}
+
+// recordMembership adds the membership's current status to its history.
+func (cfg *ApiConfig) recordMembership(ctx context.Context, ms database.Membership, reason string) error {
+	return cfg.DB.CreateMembershipHistory(ctx, database.CreateMembershipHistoryParams{
+		MembershipID: ms.ID,
+		UserID:       ms.UserID,
+		Status:       ms.Status,
+		Reason:       reason,
+	})
+}
+
+func sameTime(a, b sql.NullTime) bool {
+	return a.Valid == b.Valid && (!a.Valid || a.Time.Equal(b.Time))
+}
+
+// ExpireMemberships closes memberships whose end time has passed,
+// returning how many it expired.
+func (cfg *ApiConfig) ExpireMemberships(ctx context.Context, now time.Time) (int, error) {
+	expired, err := cfg.DB.ExpireLapsedMemberships(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	for _, ms := range expired {
+		if err := cfg.recordMembership(ctx, ms, membershipExpired); err != nil {
+			return 0, err
+		}
+	}
+	return len(expired), nil
+}
+
+// RunMembershipExpiry expires lapsed memberships every interval until ctx
+// is cancelled.
+func (cfg *ApiConfig) RunMembershipExpiry(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func(ctx context.Context) {
+		n, err := cfg.ExpireMemberships(ctx, time.Now().UTC())
+		if err != nil {
+			log.Printf("error expiring memberships: %s", err)
+		} else if n > 0 {
+			log.Printf("expired %d memberships", n)
+		}
+	})
+}
+
+// HandleGetMembership serves GET /api/users/me/membership: the caller's
+// current membership, if any, and every status change it has been through.
+func (cfg *ApiConfig) HandleGetMembership(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	out := MembershipStatus{History: []MembershipChange{}}
+	ms, err := cfg.DB.GetActiveMembership(r.Context(), database.GetActiveMembershipParams{
+		UserID: userID,
+		Now:    time.Now().UTC(),
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("error loading membership for %s: %s", userID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve membership")
+		return
+	}
+	if err == nil {
+		out.IsChirpyRed = true
+		out.Membership = &Membership{
+			Tier:      ms.Tier,
+			Status:    ms.Status,
+			StartedAt: ms.StartedAt,
+		}
+		if ms.EndsAt.Valid {
+			out.Membership.EndsAt = &ms.EndsAt.Time
+		}
+	}
+
+	history, err := cfg.DB.ListMembershipHistory(r.Context(), userID)
+	if err != nil {
+		log.Printf("error loading membership history for %s: %s", userID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not retrieve membership")
+		return
+	}
+	for _, h := range history {
+		out.History = append(out.History, MembershipChange{
+			Status:    h.Status,
+			Reason:    h.Reason,
+			CreatedAt: h.CreatedAt,
+		})
+	}
+	RespondWithJSON(w, http.StatusOK, out)
+}
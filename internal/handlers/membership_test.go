@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMembershipTransitions(t *testing.T) {
+	_, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	apiKey := map[string]string{"Authorization": "ApiKey " + testPolkaKey}
+
+	send := func(event string, endsAt time.Time) {
+		t.Helper()
+		data := map[string]interface{}{"user_id": alice.ID}
+		if !endsAt.IsZero() {
+			data["ends_at"] = endsAt
+		}
+		body, err := json.Marshal(map[string]interface{}{"event": event, "data": data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := postWebhook(t, srv, apiKey, string(body)); code != http.StatusNoContent {
+			t.Fatalf("%s: status %d", event, code)
+		}
+	}
+	status := func() MembershipStatus {
+		t.Helper()
+		var out MembershipStatus
+		if code := doJSON(t, "GET", srv.URL+"/api/users/me/membership", "Bearer "+alice.Token, nil, &out); code != http.StatusOK {
+			t.Fatalf("membership: status %d", code)
+		}
+		return out
+	}
+
+	if got := status(); got.IsChirpyRed || got.Membership != nil || len(got.History) != 0 {
+		t.Fatalf("new user: %+v", got)
+	}
+
+	send("user.upgraded", time.Time{})
+	if got := status(); !got.IsChirpyRed || got.Membership.Status != membershipActive || got.Membership.EndsAt != nil {
+		t.Fatalf("after upgrade: %+v", got)
+	}
+
+	// a cancellation keeps Chirpy Red until the end of the paid period
+	paidUntil := time.Now().Add(24 * time.Hour)
+	send("subscription.cancelled", paidUntil)
+	got := status()
+	if !got.IsChirpyRed || got.Membership.Status != membershipCancelled || got.Membership.EndsAt == nil {
+		t.Fatalf("after cancellation: %+v", got)
+	}
+
+	// upgrading again takes the cancellation back
+	send("user.upgraded", time.Time{})
+	if got := status(); !got.IsChirpyRed || got.Membership.Status != membershipActive || got.Membership.EndsAt != nil {
+		t.Fatalf("after resubscribing: %+v", got)
+	}
+
+	send("user.downgraded", time.Time{})
+	got = status()
+	if got.IsChirpyRed || got.Membership != nil {
+		t.Fatalf("after downgrade: %+v", got)
+	}
+	var statuses []string
+	for _, h := range got.History {
+		statuses = append(statuses, h.Status+":"+h.Reason)
+	}
+	want := []string{
+		"active:user.upgraded",
+		"cancelled:subscription.cancelled",
+		"active:user.upgraded",
+		"downgraded:user.downgraded",
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("history: got %v, want %v", statuses, want)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("history: got %v, want %v", statuses, want)
+		}
+	}
+
+	var login loginResponse
+	creds := map[string]string{"email": "alice@example.com", "password": "hunter2"}
+	doJSON(t, "POST", srv.URL+"/api/login", "", creds, &login)
+	if login.IsChirpyRed {
+		t.Fatal("login still reports Chirpy Red after downgrade")
+	}
+}
+
+func TestExpireMemberships(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+	ctx := context.Background()
+
+	lapsed := sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+	for _, id := range []uuid.UUID{alice.ID, bob.ID} {
+		if err := cfg.startMembership(ctx, id, lapsed, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if red, err := cfg.isChirpyRed(ctx, alice.ID); err != nil || red {
+		t.Fatalf("lapsed membership counts as Chirpy Red: %v, %v", red, err)
+	}
+
+	// renewing before the job runs closes the lapsed period first
+	if err := cfg.startMembership(ctx, bob.ID, sql.NullTime{}, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := cfg.ExpireMemberships(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expired %d memberships, want 1", n)
+	}
+	history, err := cfg.DB.ListMembershipHistory(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[1].Status != membershipExpired {
+		t.Fatalf("alice's history: %+v", history)
+	}
+	if red, err := cfg.isChirpyRed(ctx, bob.ID); err != nil || !red {
+		t.Fatalf("renewed membership: %v, %v", red, err)
+	}
+	if n, err := cfg.ExpireMemberships(ctx, time.Now()); err != nil || n != 0 {
+		t.Fatalf("second run expired %d, %v", n, err)
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+var (
+	errInvalidCursor = errors.New("invalid cursor")
+	errInvalidLimit  = errors.New("invalid limit")
+)
+
+// pageCursor is a keyset position: the (created_at, id) of the item at the
+// edge of a page, plus its rank for relevance-ordered listings. Prev marks a
+// cursor that pages backwards from that item.
+type pageCursor struct {
+	Rank      float32
+	CreatedAt time.Time
+	ID        uuid.UUID
+	Prev      bool
+}
+
+// encode returns the opaque form handed to clients.
+func (c pageCursor) encode() string {
+	dir := "n"
+	if c.Prev {
+		dir = "p"
+	}
+	raw := dir + ":" + strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.ID.String() +
+		":" + strconv.FormatFloat(float64(c.Rank), 'g', -1, 32)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 || (parts[0] != "n" && parts[0] != "p") {
+		return pageCursor{}, errInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	rank, err := strconv.ParseFloat(parts[3], 32)
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	return pageCursor{
+		Rank:      float32(rank),
+		CreatedAt: time.Unix(0, nanos).UTC(),
+		ID:        id,
+		Prev:      parts[0] == "p",
+	}, nil
+}
+
+// pageRequest holds the limit and cursor query parameters.
+type pageRequest struct {
+	Limit  int32
+	Cursor *pageCursor
+}
+
+func parsePageRequest(r *http.Request) (pageRequest, error) {
+	p := pageRequest{Limit: defaultPageSize}
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return p, errInvalidLimit
+		}
+		p.Limit = int32(min(n, maxPageSize))
+	}
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		c, err := decodeCursor(s)
+		if err != nil {
+			return p, err
+		}
+		p.Cursor = &c
+	}
+	return p, nil
+}
+
+// pageLinks are the cursors for the neighbouring pages, nil when there are
+// none.
+type pageLinks struct {
+	Next *pageCursor
+	Prev *pageCursor
+}
+
+// paginate fetches one page of a keyset-ordered listing. fetch must return up
+// to n items strictly after cursor in ascending order when asc is true, or
+// strictly before it in descending order otherwise; a nil cursor means from
+// the start. desc is the order the client asked for.
+func paginate[T any](p pageRequest, desc bool, key func(T) pageCursor, fetch func(asc bool, cursor *pageCursor, n int32) ([]T, error)) ([]T, pageLinks, error) {
+	backwards := p.Cursor != nil && p.Cursor.Prev
+	asc := desc == backwards
+
+	items, err := fetch(asc, p.Cursor, p.Limit+1)
+	if err != nil {
+		return nil, pageLinks{}, err
+	}
+	hasMore := len(items) > int(p.Limit)
+	if hasMore {
+		items = items[:p.Limit]
+	}
+	if backwards {
+		slices.Reverse(items)
+	}
+
+	var links pageLinks
+	if len(items) == 0 {
+		return items, links, nil
+	}
+	if backwards || hasMore {
+		next := key(items[len(items)-1])
+		links.Next = &next
+	}
+	if (!backwards && p.Cursor != nil) || (backwards && hasMore) {
+		prev := key(items[0])
+		prev.Prev = true
+		links.Prev = &prev
+	}
+	return items, links, nil
+}
+
+// setLinkHeader advertises the neighbouring pages using RFC 8288 Link
+// relations, preserving the rest of the request's query string.
+func setLinkHeader(w http.ResponseWriter, r *http.Request, links pageLinks) {
+	var parts []string
+	add := func(rel string, c *pageCursor) {
+		if c == nil {
+			return
+		}
+		q := r.URL.Query()
+		q.Set("cursor", c.encode())
+		parts = append(parts, "<"+r.URL.Path+"?"+q.Encode()+`>; rel="`+rel+`"`)
+	}
+	add("next", links.Next)
+	add("prev", links.Prev)
+	if len(parts) > 0 {
+		w.Header().Set("Link", strings.Join(parts, ", "))
+	}
+}
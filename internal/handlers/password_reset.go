@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kavancamp/chirpy/internal/auth"
+	"github.com/kavancamp/chirpy/internal/database"
+	"github.com/kavancamp/chirpy/internal/mail"
+)
+
+// passwordResetTTL is how long a password reset token works.
+const passwordResetTTL = time.Hour
+
+// HandleForgotPassword serves POST /api/password/forgot. It answers 202
+// whether or not the email has an account, and mails the reset token in
+// the background, so the response gives away nothing about which
+// addresses are registered.
+func (cfg *ApiConfig) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || strings.TrimSpace(body.Email) == "" {
+		RespondWithError(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	go func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		if err := cfg.sendPasswordReset(ctx, body.Email); err != nil {
+			log.Printf("error sending password reset: %s", err)
+		}
+	}(context.WithoutCancel(r.Context()))
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendPasswordReset mails a new reset token to email if it has an account.
+func (cfg *ApiConfig) sendPasswordReset(ctx context.Context, email string) error {
+	if cfg.Mailer == nil {
+		return errors.New("no mailer configured")
+	}
+	user, err := cfg.DB.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+	err = cfg.DB.CreatePasswordReset(ctx, database.CreatePasswordResetParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: "Someone asked to reset the password of your Chirpy account. " +
+			"Use this reset token within an hour to choose a new one:\n\n" + token +
+			"\n\nIf it was not you, ignore this email; your password has not changed.\n",
+	})
+}
+
+// HandleResetPassword serves POST /api/password/reset. A valid token sets
+// the new password, uses up every other reset token of the user and signs
+// them out everywhere, as whoever knew the old password may still hold a
+// session.
+func (cfg *ApiConfig) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(body.Password) == "" {
+		RespondWithError(w, http.StatusBadRequest, "Password is required")
+		return
+	}
+
+	// check the token before hashing, so bad tokens cost no bcrypt work
+	tokenHash := auth.HashToken(body.Token)
+	now := time.Now().UTC()
+	_, err := cfg.DB.GetPasswordResetUser(r.Context(), database.GetPasswordResetUserParams{
+		TokenHash: tokenHash,
+		ExpiresAt: now,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(w, http.StatusBadRequest, "Reset token is invalid or has expired")
+		return
+	}
+	if err != nil {
+		log.Printf("error looking up password reset: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not reset password")
+		return
+	}
+	hashed, err := auth.HashPassword(body.Password)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to hash password")
+		return
+	}
+
+	user, err := cfg.DB.ResetPassword(r.Context(), database.ResetPasswordParams{
+		UsedAt:         sql.NullTime{Time: now, Valid: true},
+		TokenHash:      tokenHash,
+		HashedPassword: hashed,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(w, http.StatusBadRequest, "Reset token is invalid or has expired")
+		return
+	}
+	if err != nil {
+		log.Printf("error resetting password: %s", err)
+		RespondWithError(w, http.StatusInternalServerError, "Could not reset password")
+		return
+	}
+	// a user locked out by failed logins can sign in with the new password;
+	// the reset has happened either way, so a failure here is only logged
+	if err := cfg.DB.DeleteLoginThrottle(r.Context(), emailThrottleKey(user.Email)); err != nil {
+		log.Printf("error clearing login throttle for %s: %s", user.ID, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunPasswordResetSweep deletes expired password reset tokens every
+// interval until ctx is done.
+func (cfg *ApiConfig) RunPasswordResetSweep(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func(ctx context.Context) {
+		if _, err := cfg.DB.DeleteStalePasswordResets(ctx, time.Now().UTC()); err != nil {
+			log.Printf("error sweeping password resets: %s", err)
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var resetTokenRe = regexp.MustCompile(`\b[0-9a-f]{64}\b`)
+
+// waitForResetToken waits for the nth message to arrive in o (counting
+// from 1) and returns the reset token in it.
+func (o *outbox) waitForResetToken(t *testing.T, n int, to string) string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for o.count() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for mail %d", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	msg := o.msgs[n-1]
+	if msg.To != to {
+		t.Fatalf("expected mail to %s, got %s", to, msg.To)
+	}
+	token := resetTokenRe.FindString(msg.Body)
+	if token == "" {
+		t.Fatalf("no reset token in %q", msg.Body)
+	}
+	return token
+}
+
+func TestPasswordReset(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	mails := &outbox{}
+	cfg.Mailer = mails
+	alice := signup(t, srv, "alice@example.com")
+	sent := mails.count() // the verification email
+
+	forgot := func(email string) {
+		t.Helper()
+		if code := doJSON(t, "POST", srv.URL+"/api/password/forgot", "", map[string]string{"email": email}, nil); code != http.StatusAccepted {
+			t.Fatalf("forgot %s: status %d", email, code)
+		}
+	}
+	reset := func(token, password string) int {
+		t.Helper()
+		return doJSON(t, "POST", srv.URL+"/api/password/reset", "", map[string]string{"token": token, "password": password}, nil)
+	}
+	login := func(password string) int {
+		t.Helper()
+		creds := map[string]string{"email": "alice@example.com", "password": password}
+		return doJSON(t, "POST", srv.URL+"/api/login", "", creds, nil)
+	}
+
+	forgot("nobody@example.com")
+	forgot("alice@example.com")
+	first := mails.waitForResetToken(t, sent+1, "alice@example.com")
+	forgot("alice@example.com")
+	second := mails.waitForResetToken(t, sent+2, "alice@example.com")
+
+	if code := reset("not-a-token", "newpass"); code != http.StatusBadRequest {
+		t.Fatalf("bad token: expected 400, got %d", code)
+	}
+	if code := reset(first, ""); code != http.StatusBadRequest {
+		t.Fatalf("empty password: expected 400, got %d", code)
+	}
+	if code := reset(first, "newpass"); code != http.StatusNoContent {
+		t.Fatalf("reset: status %d", code)
+	}
+
+	// every token is now used up...
+	if code := reset(first, "again"); code != http.StatusBadRequest {
+		t.Fatalf("reused token: expected 400, got %d", code)
+	}
+	if code := reset(second, "again"); code != http.StatusBadRequest {
+		t.Fatalf("other outstanding token: expected 400, got %d", code)
+	}
+	// ...existing sessions are signed out...
+	if code := doJSON(t, "POST", srv.URL+"/api/refresh", "Bearer "+alice.RefreshToken, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("old refresh token: expected 401, got %d", code)
+	}
+	if code := doJSON(t, "GET", srv.URL+"/api/sessions", "Bearer "+alice.Token, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("old access token: expected 401, got %d", code)
+	}
+	// ...and only the new password works
+	if code := login("hunter2"); code != http.StatusUnauthorized {
+		t.Fatalf("old password: expected 401, got %d", code)
+	}
+	if code := login("newpass"); code != http.StatusOK {
+		t.Fatalf("new password: status %d", code)
+	}
+
+	mails.mu.Lock()
+	defer mails.mu.Unlock()
+	for _, msg := range mails.msgs {
+		if msg.To == "nobody@example.com" {
+			t.Error("expected no mail for an address without an account")
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kavancamp/chirpy/internal/ratelimit"
+)
+
+// Rate-limited routes, as named in ApiConfig.RateLimits.
+const (
+	routeCreateChirp   = "create_chirp"
+	routeLogin         = "login"
+	routeSignup        = "signup"
+	routeVerifyEmail   = "verify_email"
+	routePasswordReset = "password_reset"
+	routeUploadMedia   = "upload_media"
+)
+
+// defaultRateLimits apply to routes missing from ApiConfig.RateLimits.
+var defaultRateLimits = map[string]ratelimit.Limit{
+	routeCreateChirp:   {Burst: 30, Per: time.Minute},
+	routeLogin:         {Burst: 10, Per: time.Minute},
+	routeSignup:        {Burst: 5, Per: time.Hour},
+	routeVerifyEmail:   {Burst: 3, Per: time.Hour},
+	routePasswordReset: {Burst: 5, Per: time.Hour},
+	routeUploadMedia:   {Burst: 30, Per: time.Hour},
+}
+
+func (cfg *ApiConfig) rateLimitFor(route string) ratelimit.Limit {
+	if l, ok := cfg.RateLimits[route]; ok {
+		return l
+	}
+	return defaultRateLimits[route]
+}
+
+// rateLimit limits calls to next per signed-in user, or per client IP for
+// anonymous callers, under the route's limit. A user's limit is scaled by
+// their entitlements. Every response carries RateLimit-* headers, and one
+// over the limit is refused with 429 and Retry-After. Limiting is off when
+// no RateLimiter is configured, and a failing backend lets requests
+// through rather than take the API down with it.
+func (cfg *ApiConfig) rateLimit(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := cfg.rateLimitFor(route)
+		if cfg.RateLimiter == nil || limit.Burst == 0 {
+			next(w, r)
+			return
+		}
+
+		key := route + ":ip:" + cfg.clientIP(r)
+		if userID := cfg.viewerID(r); userID.Valid {
+			key = route + ":user:" + userID.UUID.String()
+			ent, err := cfg.entitlements(r.Context(), userID.UUID)
+			if err != nil {
+				log.Printf("error loading entitlements for %s: %s", userID.UUID, err)
+			} else if ent.RateLimitScale > 0 {
+				limit = limit.Scale(ent.RateLimitScale)
+			}
+		}
+
+		res, err := cfg.RateLimiter.Take(r.Context(), key, limit, time.Now())
+		if err != nil {
+			log.Printf("error checking rate limit %s: %s", key, err)
+			next(w, r)
+			return
+		}
+		setRateLimitHeaders(w.Header(), res)
+		if !res.Allowed {
+			w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+			RespondWithError(w, http.StatusTooManyRequests, "Too many requests")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// setRateLimitHeaders describes the caller's quota with the IETF
+// RateLimit header fields.
+func setRateLimitHeaders(h http.Header, res ratelimit.Result) {
+	h.Set("RateLimit-Policy", strconv.Itoa(res.Limit.Burst)+";w="+ceilSeconds(res.Limit.Per))
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit.Burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// clientIP is the address the request came from. Behind a proxy that
+// appends to X-Forwarded-For, TrustForwardedFor takes the last address in
+// that header instead.
+func (cfg *ApiConfig) clientIP(r *http.Request) string {
+	if cfg.TrustForwardedFor {
+		if fwd := r.Header.Values("X-Forwarded-For"); len(fwd) > 0 {
+			hops := strings.Split(fwd[len(fwd)-1], ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RunRateLimitSweep forgets idle rate limit buckets every interval until
+// ctx is done. A bucket idle for longer than the longest limit period is
+// full again, so dropping it changes nothing.
+func (cfg *ApiConfig) RunRateLimitSweep(ctx context.Context, interval time.Duration) {
+	if cfg.RateLimiter == nil {
+		return
+	}
+	var longest time.Duration
+	for route := range defaultRateLimits {
+		longest = max(longest, cfg.rateLimitFor(route).Per)
+	}
+	runEvery(ctx, interval, func(ctx context.Context) {
+		if _, err := cfg.RateLimiter.Sweep(ctx, time.Now().Add(-longest)); err != nil {
+			log.Printf("error sweeping rate limit buckets: %s", err)
+		}
+	})
+}
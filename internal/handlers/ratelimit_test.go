@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kavancamp/chirpy/internal/ratelimit"
+)
+
+func TestRateLimit_Login(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	cfg.RateLimiter = ratelimit.NewMemory()
+	cfg.RateLimits = map[string]ratelimit.Limit{routeLogin: {Burst: 2, Per: time.Minute}}
+	cfg.TrustForwardedFor = true
+
+	login := func(ip string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest("POST", srv.URL+"/api/login", strings.NewReader(`{"email":"a@example.com","password":"x"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Forwarded-For", "203.0.113.9, "+ip)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	for i := 0; i < 2; i++ {
+		resp := login("198.51.100.1")
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("login %d: expected 401, got %d", i, resp.StatusCode)
+		}
+		if got := resp.Header.Get("RateLimit-Remaining"); got != []string{"1", "0"}[i] {
+			t.Fatalf("login %d: RateLimit-Remaining %q", i, got)
+		}
+	}
+	resp := login("198.51.100.1")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("over the limit: expected 429, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") != "30" || resp.Header.Get("RateLimit-Limit") != "2" || resp.Header.Get("RateLimit-Reset") != "60" {
+		t.Fatalf("429 headers: %v", resp.Header)
+	}
+
+	// limits are per client, taken from the proxy's hop
+	if resp := login("198.51.100.2"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("another client: expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestRateLimit_PerUser(t *testing.T) {
+	cfg, srv := newTestAPI(t)
+	alice := signup(t, srv, "alice@example.com")
+	bob := signup(t, srv, "bob@example.com")
+	cfg.RateLimiter = &ratelimit.Postgres{DB: cfg.DB}
+	cfg.RateLimits = map[string]ratelimit.Limit{routeCreateChirp: {Burst: 1, Per: time.Hour}}
+
+	post := func(token string) int {
+		t.Helper()
+		req, err := http.NewRequest("POST", srv.URL+"/api/chirps", bytes.NewBufferString(`{"body":"hi"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post(alice.Token); code != http.StatusCreated {
+		t.Fatalf("first chirp: %d", code)
+	}
+	if code := post(alice.Token); code != http.StatusTooManyRequests {
+		t.Fatalf("second chirp: expected 429, got %d", code)
+	}
+
+	// every user has their own bucket, even from the same address, and
+	// Chirpy Red doubles the limit
+	if err := cfg.startMembership(context.Background(), bob.ID, sql.NullTime{}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if code := post(bob.Token); code != http.StatusCreated {
+			t.Fatalf("Chirpy Red chirp %d: %d", i, code)
+		}
+	}
+	if code := post(bob.Token); code != http.StatusTooManyRequests {
+		t.Fatalf("Chirpy Red third chirp: expected 429, got %d", code)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RegisterRoutes mounts the API and admin endpoints on mux.
+func (cfg *ApiConfig) RegisterRoutes(mux *http.ServeMux) {
+	//readiness endpoint
+	mux.HandleFunc("GET /api/healthz", HandleReadiness)
+	mux.HandleFunc("GET /.well-known/jwks.json", cfg.HandleJWKS)
+
+	mux.HandleFunc("GET /admin/metrics", cfg.AdminMetricsHandler)
+	mux.HandleFunc("POST /admin/reset", cfg.AdminResetHandler)
+	mux.HandleFunc("GET /admin/webhooks", cfg.HandleListWebhookEvents)
+	mux.HandleFunc("POST /admin/webhooks/{id}/replay", cfg.HandleReplayWebhookEvent)
+	mux.HandleFunc("GET /admin/login-attempts", cfg.HandleListLoginAttempts)
+	mux.HandleFunc("POST /admin/users/{id}/unlock", cfg.HandleUnlockUser)
+	mux.HandleFunc("GET /admin/jwt-keys", cfg.HandleListJWTKeys)
+	mux.HandleFunc("POST /admin/jwt-keys", cfg.HandleCreateJWTKey)
+	mux.HandleFunc("POST /admin/jwt-keys/{id}/promote", cfg.HandlePromoteJWTKey)
+	mux.HandleFunc("DELETE /admin/jwt-keys/{id}", cfg.HandleRetireJWTKey)
+	mux.HandleFunc("POST /api/users", cfg.rateLimit(routeSignup, cfg.HandleCreateUser))
+	mux.HandleFunc("PUT /api/users", cfg.HandleUpdateUser)
+	mux.HandleFunc("GET /api/users/verify", cfg.HandleVerifyEmail)
+	mux.HandleFunc("POST /api/users/verify/resend", cfg.rateLimit(routeVerifyEmail, cfg.HandleResendVerification))
+	mux.HandleFunc("GET /api/users/me/membership", cfg.HandleGetMembership)
+	mux.HandleFunc("GET /api/users/me/entitlements", cfg.HandleGetEntitlements)
+	mux.HandleFunc("POST /api/users/{id}/follow", cfg.HandleFollowUser)
+	mux.HandleFunc("DELETE /api/users/{id}/follow", cfg.HandleUnfollowUser)
+	mux.HandleFunc("GET /api/users/{id}/followers", cfg.HandleGetFollowers)
+	mux.HandleFunc("GET /api/users/{id}/following", cfg.HandleGetFollowing)
+	mux.HandleFunc("GET /api/timeline", cfg.HandleGetTimeline)
+	mux.HandleFunc("POST /api/chirps", cfg.rateLimit(routeCreateChirp, cfg.HandleCreateChirp))
+	mux.HandleFunc("GET /api/chirps", cfg.HandleGetChirps)
+	mux.HandleFunc("GET /api/chirps/search", cfg.HandleSearchChirps)
+	mux.HandleFunc("GET /api/chirps/", cfg.HandleGetChirpByID)
+	mux.HandleFunc("DELETE /api/chirps/", cfg.HandleDeleteChirp)
+	mux.HandleFunc("PUT /api/chirps/{id}", cfg.HandleEditChirp)
+	mux.HandleFunc("GET /api/chirps/{id}/revisions", cfg.HandleGetRevisions)
+	mux.HandleFunc("POST /api/chirps/{id}/restore", cfg.HandleRestoreChirp)
+	mux.HandleFunc("GET /api/chirps/{id}/replies", cfg.HandleGetReplies)
+	mux.HandleFunc("GET /api/chirps/{id}/thread", cfg.HandleGetThread)
+	mux.HandleFunc("POST /api/chirps/{id}/likes", cfg.HandleLikeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{id}/likes", cfg.HandleUnlikeChirp)
+	mux.HandleFunc("POST /api/chirps/{id}/rechirps", cfg.HandleRechirp)
+	mux.HandleFunc("DELETE /api/chirps/{id}/rechirps", cfg.HandleUndoRechirp)
+	mux.HandleFunc("POST /api/media", cfg.rateLimit(routeUploadMedia, cfg.HandleUploadMedia))
+	mux.HandleFunc("GET /api/media/{id}", cfg.HandleGetMedia)
+	mux.HandleFunc("GET /api/media/{id}/thumbnail", cfg.HandleGetMediaThumbnail)
+	mux.HandleFunc("GET /api/notifications", cfg.HandleGetNotifications)
+	mux.HandleFunc("POST /api/notifications/read", cfg.HandleMarkNotificationsRead)
+	mux.HandleFunc("GET /api/tags/trending", cfg.HandleGetTrendingTags)
+	mux.HandleFunc("GET /api/tags/{tag}/chirps", cfg.HandleGetTagChirps)
+	mux.HandleFunc("POST /api/login", cfg.rateLimit(routeLogin, cfg.HandleLogin))
+	mux.HandleFunc("POST /api/refresh", cfg.HandleRefresh)
+	mux.HandleFunc("POST /api/revoke", cfg.HandleRevoke)
+	mux.HandleFunc("POST /api/password/forgot", cfg.rateLimit(routePasswordReset, cfg.HandleForgotPassword))
+	mux.HandleFunc("POST /api/password/reset", cfg.rateLimit(routePasswordReset, cfg.HandleResetPassword))
+	mux.HandleFunc("GET /api/sessions", cfg.HandleListSessions)
+	mux.HandleFunc("DELETE /api/sessions/{id}", cfg.HandleRevokeSession)
+	mux.HandleFunc("POST /api/sessions/revoke-all", cfg.HandleRevokeAllSessions)
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.HandlePolkaWebhook)
+}
+
+func HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "OK")
+}
@@ -0,0 +1,53 @@
+// Package mail sends the emails Chirpy needs, such as address
+// verification links, through a Mailer: SMTP in production, or a file or
+// the log for local development.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	netmail "net/mail"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var errHeaderInjection = errors.New("mail: line break in header")
+
+// Format renders msg as an RFC 5322 message from from, dated date.
+func (msg Message) Format(from string, date time.Time) ([]byte, error) {
+	if _, err := netmail.ParseAddress(msg.To); err != nil {
+		return nil, fmt.Errorf("mail: invalid recipient %q: %w", msg.To, err)
+	}
+	for _, h := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(h, "\r\n") {
+			return nil, errHeaderInjection
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return b.Bytes(), nil
+}
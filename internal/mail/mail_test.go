@@ -0,0 +1,57 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	msg := Message{To: "alice@example.com", Subject: "Bienvenue à Chirpy", Body: "line one\nline two"}
+	data, err := msg.Format("Chirpy <no-reply@example.com>", time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		"From: Chirpy <no-reply@example.com>\r\n",
+		"To: alice@example.com\r\n",
+		"Subject: =?utf-8?q?Bienvenue_=C3=A0_Chirpy?=\r\n",
+		"Date: Thu, 02 Jan 2025 03:04:05 +0000\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in\n%s", want, got)
+		}
+	}
+}
+
+func TestFormat_Rejects(t *testing.T) {
+	for name, msg := range map[string]Message{
+		"bad recipient":     {To: "not an address", Subject: "hi"},
+		"subject injection": {To: "alice@example.com", Subject: "hi\r\nBcc: eve@example.com"},
+	} {
+		if _, err := msg.Format("no-reply@example.com", time.Now()); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := &FileMailer{Dir: dir, From: "no-reply@example.com"}
+	if err := m.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hello", Body: "Hi Alice"}); err != nil {
+		t.Fatal(err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil || len(files) != 1 || !strings.HasSuffix(files[0].Name(), "-alice@example.com.eml") {
+		t.Fatalf("expected one .eml file, got %v, %v", files, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil || !strings.Contains(string(data), "Hi Alice") {
+		t.Fatalf("unexpected message %q, %v", data, err)
+	}
+}
@@ -0,0 +1,90 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"time"
+)
+
+// sendTimeout bounds how long SMTPMailer takes over one message, from
+// dialing to QUIT.
+const sendTimeout = 30 * time.Second
+
+// SMTPMailer sends mail through an SMTP server, upgrading the connection
+// with STARTTLS when the server offers it. Username and Password are
+// optional; PLAIN authentication is only used over TLS or to localhost.
+type SMTPMailer struct {
+	Addr     string // host:port, e.g. smtp.example.com:587
+	Username string
+	Password string
+	From     string // e.g. Chirpy <no-reply@example.com>
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	from, err := netmail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("mail: invalid sender %q: %w", m.From, err)
+	}
+	to, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("mail: invalid recipient %q: %w", msg.To, err)
+	}
+	data, err := msg.Format(m.From, time.Now())
+	if err != nil {
+		return err
+	}
+	host, _, err := net.SplitHostPort(m.Addr)
+	if err != nil {
+		return err
+	}
+
+	// callers may pass a request context without a deadline; a stalled
+	// server must not hold them forever
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.Addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,41 @@
+// Package media stores chirp image attachments. Uploads are validated and
+// re-encoded by Process, and the resulting bytes are kept in a BlobStore.
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ErrNotFound is returned by BlobStore.Get when no blob has the key.
+var ErrNotFound = errors.New("media: blob not found")
+
+var errInvalidKey = errors.New("media: invalid blob key")
+
+// BlobStore is where uploaded media bytes live. Keys are slash-separated
+// paths such as "media/<id>/original".
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+var keyRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+(/[A-Za-z0-9_.-]+)*$`)
+
+// validKey rejects keys that could escape a DiskStore root or need escaping
+// in an S3 path.
+func validKey(key string) bool {
+	if !keyRe.MatchString(key) {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
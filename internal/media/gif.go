@@ -0,0 +1,76 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errMalformedGIF = errors.New("media: malformed GIF")
+
+// gifFrames walks the blocks of a GIF without decompressing any pixel data
+// and returns its number of frames and the pixels they hold between them,
+// which is what decoding every frame allocates.
+func gifFrames(data []byte) (frames, pixels int, err error) {
+	if len(data) < 13 {
+		return 0, 0, errMalformedGIF
+	}
+	i := 13 // header and logical screen descriptor
+	i += colorTableSize(data[10])
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension: label, then sub-blocks
+			if i += 2; i > len(data) {
+				return 0, 0, errMalformedGIF
+			}
+			if i, err = skipSubBlocks(data, i); err != nil {
+				return 0, 0, err
+			}
+		case 0x2C: // image descriptor
+			if i+10 > len(data) {
+				return 0, 0, errMalformedGIF
+			}
+			w := int(binary.LittleEndian.Uint16(data[i+5:]))
+			h := int(binary.LittleEndian.Uint16(data[i+7:]))
+			frames++
+			pixels += w * h
+			i += 10 + colorTableSize(data[i+9])
+			// LZW minimum code size, then the compressed pixels
+			if i += 1; i > len(data) {
+				return 0, 0, errMalformedGIF
+			}
+			if i, err = skipSubBlocks(data, i); err != nil {
+				return 0, 0, err
+			}
+		case 0x3B: // trailer
+			return frames, pixels, nil
+		default:
+			return 0, 0, errMalformedGIF
+		}
+	}
+	return 0, 0, errMalformedGIF
+}
+
+// colorTableSize is the size of the color table a GIF packed field says
+// follows it, if any.
+func colorTableSize(packed byte) int {
+	if packed&0x80 == 0 {
+		return 0
+	}
+	return 3 << ((packed & 0x07) + 1)
+}
+
+// skipSubBlocks returns the offset just past the chain of data sub-blocks
+// starting at i.
+func skipSubBlocks(data []byte, i int) (int, error) {
+	for {
+		if i >= len(data) {
+			return 0, errMalformedGIF
+		}
+		n := int(data[i])
+		i++
+		if n == 0 {
+			return i, nil
+		}
+		i += n
+	}
+}
@@ -0,0 +1,195 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// MaxUploadBytes caps the size of an uploaded file.
+	MaxUploadBytes = 5 << 20
+	// MaxDimension caps either side of an uploaded image, checked before
+	// decoding so small files cannot expand into huge bitmaps.
+	MaxDimension = 4096
+	// MaxGIFFrames caps the frames of an animated GIF, and MaxGIFPixels
+	// the pixels of all its frames together, as every frame is decoded.
+	MaxGIFFrames = 500
+	MaxGIFPixels = 64 << 20
+	// ThumbnailSize is the longest side of a generated thumbnail.
+	ThumbnailSize = 320
+
+	jpegQuality = 90
+)
+
+var (
+	ErrUnsupportedType = errors.New("media: unsupported image type")
+	ErrTooLarge        = errors.New("media: image too large")
+)
+
+// Image is an upload after processing. Data is re-encoded from the decoded
+// pixels, so EXIF and any other metadata in the upload is dropped; JPEG
+// orientation is applied to the pixels first so photos stay upright.
+type Image struct {
+	ContentType   string
+	Width         int
+	Height        int
+	Data          []byte
+	Thumbnail     []byte
+	ThumbnailType string
+}
+
+// Process validates an uploaded image by sniffing its content rather than
+// trusting the client's Content-Type, strips its metadata and renders a
+// thumbnail. JPEG, PNG and GIF (including animated GIF) are accepted.
+func Process(data []byte) (Image, error) {
+	if len(data) > MaxUploadBytes {
+		return Image{}, ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return Image{}, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, err
+	}
+	if cfg.Width > MaxDimension || cfg.Height > MaxDimension {
+		return Image{}, ErrTooLarge
+	}
+
+	var (
+		out           bytes.Buffer
+		frame         image.Image
+		width, height int
+	)
+	switch contentType {
+	case "image/jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		frame = orient(img, jpegOrientation(data))
+		width, height = frame.Bounds().Dx(), frame.Bounds().Dy()
+		err = jpeg.Encode(&out, frame, &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return Image{}, err
+		}
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		frame = img
+		width, height = cfg.Width, cfg.Height
+		if err := png.Encode(&out, img); err != nil {
+			return Image{}, err
+		}
+	case "image/gif":
+		frames, pixels, err := gifFrames(data)
+		if err != nil {
+			return Image{}, err
+		}
+		if frames > MaxGIFFrames || pixels > MaxGIFPixels {
+			return Image{}, ErrTooLarge
+		}
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		frame = g.Image[0]
+		// frames may cover only part of the logical screen
+		width, height = g.Config.Width, g.Config.Height
+		// EncodeAll only writes frames, delays and the loop count, which
+		// drops comment and application extensions
+		if err := gif.EncodeAll(&out, g); err != nil {
+			return Image{}, err
+		}
+	}
+
+	thumb, thumbType, err := thumbnail(frame, contentType)
+	if err != nil {
+		return Image{}, err
+	}
+	return Image{
+		ContentType:   contentType,
+		Width:         width,
+		Height:        height,
+		Data:          out.Bytes(),
+		Thumbnail:     thumb,
+		ThumbnailType: thumbType,
+	}, nil
+}
+
+// thumbnail scales img down to fit ThumbnailSize. Photos stay JPEG; PNG and
+// GIF thumbnails are PNG to keep transparency.
+func thumbnail(img image.Image, contentType string) ([]byte, string, error) {
+	small := resize(toRGBA(img), ThumbnailSize)
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		err := jpeg.Encode(&buf, small, &jpeg.Options{Quality: jpegQuality})
+		return buf.Bytes(), "image/jpeg", err
+	}
+	err := png.Encode(&buf, small)
+	return buf.Bytes(), "image/png", err
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// resize scales src so its longest side is at most size, averaging the
+// source pixels that fall in each destination pixel. Images that already
+// fit are returned as is.
+func resize(src *image.RGBA, size int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw <= size && sh <= size {
+		return src
+	}
+	dw, dh := size, size
+	if sw > sh {
+		dh = max(1, sh*size/sw)
+	} else {
+		dw = max(1, sw*size/sh)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		y0, y1 := dy*sh/dh, (dy+1)*sh/dh
+		for dx := 0; dx < dw; dx++ {
+			x0, x1 := dx*sw/dw, (dx+1)*sw/dw
+			// RGBA is premultiplied, so a plain average is correct even
+			// across transparent edges
+			var sum [4]int
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride+x0*4 : y*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			o := dy*dst.Stride + dx*4
+			for c := range sum {
+				dst.Pix[o+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
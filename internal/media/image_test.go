@@ -0,0 +1,171 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// exifSegment builds an APP1 segment holding only an orientation tag.
+func exifSegment(orientation uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00\x2A")
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+func TestProcess_JPEGOrientationAndEXIF(t *testing.T) {
+	// 40x20, red on the left and blue on the right
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 20 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	raw := buf.Bytes()
+	data := append(append(append([]byte{}, raw[:2]...), exifSegment(6)...), raw[2:]...)
+
+	got, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if got.ContentType != "image/jpeg" || got.Width != 20 || got.Height != 40 {
+		t.Fatalf("got %s %dx%d, want image/jpeg 20x40", got.ContentType, got.Width, got.Height)
+	}
+	if bytes.Contains(got.Data, []byte("Exif")) {
+		t.Fatal("EXIF segment survived processing")
+	}
+
+	// turning clockwise puts the left (red) half on top
+	out, err := jpeg.Decode(bytes.NewReader(got.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, b, _ := out.At(10, 5).RGBA(); r < b {
+		t.Fatalf("top of rotated image is not red")
+	}
+	if got.ThumbnailType != "image/jpeg" || len(got.Thumbnail) == 0 {
+		t.Fatalf("thumbnail: %s, %d bytes", got.ThumbnailType, len(got.Thumbnail))
+	}
+}
+
+func TestProcess_Thumbnail(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1000, 500))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	thumb, err := png.DecodeConfig(bytes.NewReader(got.Thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ThumbnailType != "image/png" || thumb.Width != ThumbnailSize || thumb.Height != ThumbnailSize/2 {
+		t.Fatalf("thumbnail: %s %dx%d", got.ThumbnailType, thumb.Width, thumb.Height)
+	}
+}
+
+func TestProcess_AnimatedGIF(t *testing.T) {
+	// frames smaller than the 16x12 logical screen
+	frame := func() *image.Paletted { return image.NewPaletted(image.Rect(2, 2, 10, 10), palette.Plan9) }
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:  []*image.Paletted{frame(), frame()},
+		Delay:  []int{10, 10},
+		Config: image.Config{Width: 16, Height: 12},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if got.Width != 16 || got.Height != 12 {
+		t.Errorf("got %dx%d, want the 16x12 logical screen", got.Width, got.Height)
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(got.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 2 {
+		t.Fatalf("got %d frames, want 2", len(g.Image))
+	}
+}
+
+// bigFramesGIF builds a GIF of n MaxDimension-sided frames whose pixel
+// data is empty, so it is tiny however many pixels it claims.
+func bigFramesGIF(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("GIF89a")
+	binary.Write(&buf, binary.LittleEndian, []uint16{MaxDimension, MaxDimension})
+	buf.Write([]byte{0, 0, 0})
+	for i := 0; i < n; i++ {
+		buf.WriteByte(0x2C)
+		binary.Write(&buf, binary.LittleEndian, []uint16{0, 0, MaxDimension, MaxDimension})
+		buf.Write([]byte{0x80, 0, 0, 0, 0xFF, 0xFF, 0xFF}) // two-color local table
+		buf.Write([]byte{2, 0})                            // LZW code size, no data
+	}
+	buf.WriteByte(0x3B)
+	return buf.Bytes()
+}
+
+func TestProcess_Rejects(t *testing.T) {
+	var huge bytes.Buffer
+	if err := png.Encode(&huge, image.NewGray(image.Rect(0, 0, MaxDimension+1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	many := &gif.GIF{}
+	for i := 0; i <= MaxGIFFrames; i++ {
+		many.Image = append(many.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), palette.Plan9))
+		many.Delay = append(many.Delay, 1)
+	}
+	var manyFrames bytes.Buffer
+	if err := gif.EncodeAll(&manyFrames, many); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		data []byte
+		want error
+	}{
+		"text":            {[]byte("definitely not an image"), ErrUnsupportedType},
+		"too wide":        {huge.Bytes(), ErrTooLarge},
+		"too many bytes":  {make([]byte, MaxUploadBytes+1), ErrTooLarge},
+		"too many frames": {manyFrames.Bytes(), ErrTooLarge},
+		"too many pixels": {bigFramesGIF(MaxGIFPixels/(MaxDimension*MaxDimension) + 1), ErrTooLarge},
+	}
+	for name, c := range cases {
+		if _, err := Process(c.data); !errors.Is(err, c.want) {
+			t.Errorf("%s: got %v, want %v", name, err, c.want)
+		}
+	}
+}
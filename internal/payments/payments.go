@@ -0,0 +1,53 @@
+// Package payments abstracts the payment providers that tell Chirpy about
+// paid memberships through webhooks.
+package payments
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnauthorized is returned by VerifyWebhook for deliveries that cannot
+// be shown to come from the provider.
+var ErrUnauthorized = errors.New("payments: webhook not from provider")
+
+// Transition is the membership change a provider event asks for.
+type Transition string
+
+const (
+	// TransitionNone marks events that do not affect memberships.
+	TransitionNone Transition = ""
+	// TransitionStart starts or renews a membership.
+	TransitionStart Transition = "start"
+	// TransitionDowngrade ends a membership immediately.
+	TransitionDowngrade Transition = "downgrade"
+	// TransitionCancel ends a membership at Event.EndsAt, or immediately
+	// when it is not set.
+	TransitionCancel Transition = "cancel"
+)
+
+// Event is a provider webhook in provider-neutral form.
+type Event struct {
+	// ID identifies the event for deduplication. It is empty when the
+	// provider did not send one.
+	ID         string
+	Type       string
+	Transition Transition
+	UserID     uuid.UUID
+	// EndsAt is when the membership runs out, if the event says.
+	EndsAt *time.Time
+}
+
+// Provider verifies and decodes one provider's webhooks.
+type Provider interface {
+	// Name identifies the provider in logs.
+	Name() string
+	// VerifyWebhook checks that a delivery with the given headers and body
+	// came from the provider, returning ErrUnauthorized when it did not.
+	VerifyWebhook(header http.Header, body []byte, now time.Time) error
+	// ParseEvent decodes a webhook body.
+	ParseEvent(body []byte) (Event, error)
+}
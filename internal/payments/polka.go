@@ -0,0 +1,105 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kavancamp/chirpy/internal/auth"
+)
+
+// Polka event types.
+const (
+	PolkaUserUpgraded          = "user.upgraded"
+	PolkaUserDowngraded        = "user.downgraded"
+	PolkaSubscriptionCancelled = "subscription.cancelled"
+)
+
+// Polka webhook signature headers.
+const (
+	PolkaTimestampHeader = "X-Polka-Timestamp"
+	PolkaSignatureHeader = "X-Polka-Signature"
+)
+
+// Polka is the Polka payment provider. Deliveries carry APIKey in an
+// "Authorization: ApiKey" header and, when WebhookSecret is set, a
+// signature made with auth.SignWebhook.
+type Polka struct {
+	APIKey        string
+	WebhookSecret string
+}
+
+// PolkaEvent is the body of a Polka webhook. ID is optional; EndsAt is when
+// a cancelled or fixed-term membership runs out.
+type PolkaEvent struct {
+	ID    string `json:"id,omitempty"`
+	Event string `json:"event"`
+	Data  struct {
+		UserID uuid.UUID  `json:"user_id"`
+		EndsAt *time.Time `json:"ends_at,omitempty"`
+	} `json:"data"`
+}
+
+func (p *Polka) Name() string { return "polka" }
+
+func (p *Polka) VerifyWebhook(header http.Header, body []byte, now time.Time) error {
+	if err := auth.CheckAPIKey(header, p.APIKey); err != nil {
+		return ErrUnauthorized
+	}
+	if p.WebhookSecret == "" {
+		return nil
+	}
+	err := auth.VerifyWebhookSignature(p.WebhookSecret,
+		header.Get(PolkaTimestampHeader), header.Get(PolkaSignatureHeader), body, now)
+	if err != nil {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func (p *Polka) ParseEvent(body []byte) (Event, error) {
+	var pe PolkaEvent
+	if err := json.Unmarshal(body, &pe); err != nil {
+		return Event{}, err
+	}
+	ev := Event{
+		ID:     pe.ID,
+		Type:   pe.Event,
+		UserID: pe.Data.UserID,
+		EndsAt: pe.Data.EndsAt,
+	}
+	switch pe.Event {
+	case PolkaUserUpgraded:
+		ev.Transition = TransitionStart
+	case PolkaUserDowngraded:
+		ev.Transition = TransitionDowngrade
+	case PolkaSubscriptionCancelled:
+		ev.Transition = TransitionCancel
+	}
+	return ev, nil
+}
+
+// NewWebhookRequest builds a delivery of e to url as Polka would send it,
+// signed at now when WebhookSecret is set.
+func (p *Polka) NewWebhookRequest(ctx context.Context, url string, e PolkaEvent, now time.Time) (*http.Request, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "ApiKey "+p.APIKey)
+	if p.WebhookSecret != "" {
+		ts := now.Unix()
+		req.Header.Set(PolkaTimestampHeader, strconv.FormatInt(ts, 10))
+		req.Header.Set(PolkaSignatureHeader, auth.SignWebhook(p.WebhookSecret, ts, body))
+	}
+	return req, nil
+}
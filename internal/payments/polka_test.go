@@ -0,0 +1,74 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPolka_RoundTrip(t *testing.T) {
+	p := &Polka{APIKey: "key", WebhookSecret: "secret"}
+	now := time.Now()
+	endsAt := now.Add(24 * time.Hour).UTC()
+
+	var e PolkaEvent
+	e.ID = "evt_1"
+	e.Event = PolkaSubscriptionCancelled
+	e.Data.UserID = uuid.New()
+	e.Data.EndsAt = &endsAt
+	req, err := p.NewWebhookRequest(context.Background(), "http://chirpy.test/api/polka/webhooks", e, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.VerifyWebhook(req.Header, body, now); err != nil {
+		t.Fatalf("verify: %s", err)
+	}
+	ev, err := p.ParseEvent(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.ID != "evt_1" || ev.Transition != TransitionCancel || ev.UserID != e.Data.UserID || !ev.EndsAt.Equal(endsAt) {
+		t.Errorf("parsed %+v", ev)
+	}
+
+	// tampering with the body, the key or the clock fails verification
+	if err := p.VerifyWebhook(req.Header, append(body, ' '), now); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("tampered body: %v", err)
+	}
+	if err := p.VerifyWebhook(req.Header, body, now.Add(time.Hour)); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("stale delivery: %v", err)
+	}
+	other := &Polka{APIKey: "other", WebhookSecret: "secret"}
+	if err := other.VerifyWebhook(req.Header, body, now); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("wrong key: %v", err)
+	}
+}
+
+func TestPolka_ParseEvent(t *testing.T) {
+	p := &Polka{}
+	for event, want := range map[string]Transition{
+		PolkaUserUpgraded:   TransitionStart,
+		PolkaUserDowngraded: TransitionDowngrade,
+		"user.renamed":      TransitionNone,
+	} {
+		ev, err := p.ParseEvent([]byte(`{"event": "` + event + `", "data": {"user_id": "` + uuid.NewString() + `"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Transition != want {
+			t.Errorf("%s: got %q, want %q", event, ev.Transition, want)
+		}
+	}
+	if _, err := p.ParseEvent([]byte("{")); err == nil {
+		t.Error("expected an error for malformed JSON")
+	}
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Memory keeps buckets in process memory. Limits are not shared between
+// instances.
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: make(map[string]bucket)}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, l Limit, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = bucket{tokens: float64(l.Burst), updatedAt: now}
+	}
+	elapsed := math.Max(0, now.Sub(b.updatedAt).Seconds())
+	b.tokens = math.Min(float64(l.Burst), b.tokens+elapsed*l.rate())
+	if now.After(b.updatedAt) {
+		b.updatedAt = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	m.buckets[key] = b
+	return newResult(l, b.tokens, allowed), nil
+}
+
+func (m *Memory) Sweep(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for key, b := range m.buckets {
+		if b.updatedAt.Before(before) {
+			delete(m.buckets, key)
+			n++
+		}
+	}
+	return n, nil
+}
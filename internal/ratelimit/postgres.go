@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+// Postgres keeps buckets in the rate_limit_buckets table so that every
+// instance sharing the database shares limits. Each Take is one atomic
+// statement, plus a read when the request is refused.
+type Postgres struct {
+	DB database.Store
+}
+
+func (p *Postgres) Take(ctx context.Context, key string, l Limit, now time.Time) (Result, error) {
+	// at the precision of the updated_at column
+	now = now.UTC().Truncate(time.Microsecond)
+	tokens, err := p.DB.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(l.Burst),
+		Now:   now,
+		Rate:  l.rate(),
+	})
+	if err == nil {
+		return newResult(l, tokens, true), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Result{}, err
+	}
+
+	// refused; read the bucket back to say when to retry
+	b, err := p.DB.GetRateLimitBucket(ctx, key)
+	if err != nil {
+		return Result{}, err
+	}
+	elapsed := math.Max(0, now.Sub(b.UpdatedAt).Seconds())
+	tokens = math.Min(float64(l.Burst), b.Tokens+elapsed*l.rate())
+	return newResult(l, tokens, false), nil
+}
+
+func (p *Postgres) Sweep(ctx context.Context, before time.Time) (int64, error) {
+	return p.DB.DeleteStaleRateLimitBuckets(ctx, before.UTC())
+}
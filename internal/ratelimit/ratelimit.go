@@ -0,0 +1,113 @@
+// Package ratelimit implements token-bucket rate limiting with in-memory
+// and Postgres-backed bucket storage.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Burst requests at once, refilled evenly over Per: a client
+// that has used up its burst may make another request every Per/Burst.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// rate is the refill rate in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Per.Seconds()
+}
+
+// Scale returns l with its burst multiplied by f, keeping Per, so the
+// refill rate scales too. The burst never drops below one.
+func (l Limit) Scale(f float64) Limit {
+	l.Burst = max(1, int(math.Round(float64(l.Burst)*f)))
+	return l
+}
+
+func (l Limit) String() string {
+	return strconv.Itoa(l.Burst) + "/" + l.Per.String()
+}
+
+// ParseLimit parses a limit written as "burst/per", e.g. "30/1m".
+func ParseLimit(s string) (Limit, error) {
+	burst, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("ratelimit: %q: want burst/duration", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(burst))
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("ratelimit: %q: burst must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: %q: period must be a positive duration", s)
+	}
+	return Limit{Burst: n, Per: d}, nil
+}
+
+// ParseLimits parses comma-separated name=limit pairs, e.g.
+// "login=5/1m,signup=3/1h".
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("ratelimit: %q: want name=limit", pair)
+		}
+		l, err := ParseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(name)] = l
+	}
+	return limits, nil
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	Limit   Limit
+	// Remaining is how many whole tokens are left.
+	Remaining int
+	// RetryAfter is how long until a token is available when the request
+	// was not allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+func newResult(l Limit, tokens float64, allowed bool) Result {
+	r := Result{
+		Allowed:   allowed,
+		Limit:     l,
+		Remaining: max(0, int(math.Floor(tokens))),
+		Reset:     secondsDuration((float64(l.Burst) - tokens) / l.rate()),
+	}
+	if !allowed {
+		r.RetryAfter = secondsDuration((1 - tokens) / l.rate())
+	}
+	return r
+}
+
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(math.Max(0, s) * float64(time.Second))
+}
+
+// Backend stores token buckets.
+type Backend interface {
+	// Take takes a token from the bucket for key under limit l.
+	Take(ctx context.Context, key string, l Limit, now time.Time) (Result, error)
+	// Sweep forgets buckets untouched since before. A bucket idle for
+	// longer than its limit's Per is full, which is the same as having
+	// none.
+	Sweep(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kavancamp/chirpy/internal/database"
+)
+
+func TestParseLimits(t *testing.T) {
+	got, err := ParseLimits("login=5/1m, signup = 3/1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["login"] != (Limit{5, time.Minute}) || got["signup"] != (Limit{3, time.Hour}) {
+		t.Errorf("got %v", got)
+	}
+	for _, bad := range []string{"login", "login=5", "login=0/1m", "login=5/soon", "login=5/-1m"} {
+		if _, err := ParseLimits(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestBackends(t *testing.T) {
+	for name, backend := range map[string]Backend{
+		"memory":   NewMemory(),
+		"postgres": &Postgres{DB: database.NewMemStore()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			l := Limit{Burst: 3, Per: 3 * time.Second}
+			start := time.Now()
+
+			for i := 0; i < 3; i++ {
+				r, err := backend.Take(ctx, "k", l, start)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !r.Allowed || r.Remaining != 2-i {
+					t.Fatalf("take %d: %+v", i, r)
+				}
+			}
+			r, err := backend.Take(ctx, "k", l, start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Allowed || r.RetryAfter != time.Second || r.Reset != 3*time.Second {
+				t.Fatalf("over the limit: %+v", r)
+			}
+
+			// other keys have their own bucket
+			if r, _ := backend.Take(ctx, "other", l, start); !r.Allowed {
+				t.Fatal("separate key was limited")
+			}
+
+			// one token a second comes back
+			if r, _ := backend.Take(ctx, "k", l, start.Add(time.Second)); !r.Allowed || r.Remaining != 0 {
+				t.Fatalf("after refill: %+v", r)
+			}
+			if r, _ := backend.Take(ctx, "k", l, start.Add(10*time.Second)); !r.Allowed || r.Remaining != 2 {
+				t.Fatalf("after full refill: %+v", r)
+			}
+
+			if n, err := backend.Sweep(ctx, start.Add(5*time.Second)); err != nil || n != 1 {
+				t.Fatalf("sweep: %d, %v", n, err)
+			}
+		})
+	}
+}
+
+func TestLimitScale(t *testing.T) {
+	l := Limit{Burst: 10, Per: time.Minute}
+	if got := l.Scale(2.5); got.Burst != 25 || got.Per != time.Minute {
+		t.Errorf("scale up: %v", got)
+	}
+	if got := l.Scale(0.01); got.Burst != 1 {
+		t.Errorf("scale down: %v", got)
+	}
+}
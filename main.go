@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"time"
+	"github.com/kavancamp/chirpy/internal/auth"
 	"github.com/kavancamp/chirpy/internal/database"
+	"github.com/kavancamp/chirpy/internal/entitlements"
 	"github.com/kavancamp/chirpy/internal/handlers"
+	"github.com/kavancamp/chirpy/internal/mail"
+	"github.com/kavancamp/chirpy/internal/media"
+	"github.com/kavancamp/chirpy/internal/payments"
+	"github.com/kavancamp/chirpy/internal/ratelimit"
 	"database/sql"
+	"encoding/base64"
 	"net/http"
-	"fmt"
 
 	"os"
 	"log"
+	"fmt"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -32,35 +41,92 @@ func main() {
 
 	defer db.Close()
 
+	blobs, err := newBlobStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ents := entitlements.Default()
+	if path := os.Getenv("ENTITLEMENTS_FILE"); path != "" {
+		ents, err = entitlements.Load(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	dbQueries := database.New(db)
+	rateLimits, err := ratelimit.ParseLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	jwtKey, err := newJWTKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var jwtKeyOverlap time.Duration
+	if v := os.Getenv("JWT_KEY_OVERLAP"); v != "" {
+		jwtKeyOverlap, err = time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("JWT_KEY_OVERLAP: %s", err)
+		}
+		if jwtKeyOverlap < handlers.MinJWTKeyOverlap {
+			log.Fatalf("JWT_KEY_OVERLAP must be at least %s", handlers.MinJWTKeyOverlap)
+		}
+	}
+	var jwtKEK []byte
+	if v := os.Getenv("JWT_KEY_ENCRYPTION_KEY"); v != "" {
+		jwtKEK, err = base64.StdEncoding.DecodeString(v)
+		if err != nil || len(jwtKEK) != 32 {
+			log.Fatal("JWT_KEY_ENCRYPTION_KEY must be 32 bytes, base64 encoded")
+		}
+	}
+	mailer, err := newMailer()
+	if err != nil {
+		log.Fatal(err)
+	}
 	cfg := handlers.ApiConfig{
 		DB: dbQueries,
 		Platform: os.Getenv("PLATFORM"),
-		JWTSecret: os.Getenv("JWT_SECRET"),
-		PolkaKey: os.Getenv("POLKA_KEY"), 
+		JWTKey: jwtKey,
+		JWTKeyOverlap: jwtKeyOverlap,
+		JWTKeyEncryptionKey: jwtKEK,
+		Payments: &payments.Polka{
+			APIKey:        os.Getenv("POLKA_KEY"),
+			WebhookSecret: os.Getenv("POLKA_WEBHOOK_SECRET"),
+		},
+		AdminKey: os.Getenv("ADMIN_API_KEY"),
+		Blobs: blobs,
+		Entitlements: ents,
+		RateLimiter: newRateLimiter(dbQueries),
+		RateLimits: rateLimits,
+		TrustForwardedFor: os.Getenv("TRUST_FORWARDED_FOR") == "true",
+		Mailer: mailer,
+		PublicURL: os.Getenv("PUBLIC_URL"),
+		RequireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true",
+	}
+	if err := cfg.LoadKeyRing(context.Background()); err != nil {
+		log.Fatal(err)
 	}
 	mux := http.NewServeMux()
-	//readiness endpoint
-	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, "OK")
-	})
-
-	mux.HandleFunc("GET /admin/metrics", cfg.AdminMetricsHandler)
-	mux.HandleFunc("POST /admin/reset", cfg.AdminResetHandler)
-	mux.HandleFunc("POST /api/users", cfg.HandleCreateUser)
-	mux.HandleFunc("PUT /api/users", cfg.HandleUpdateUser)
-	mux.HandleFunc("POST /api/chirps", cfg.HandleCreateChirp)
-	mux.HandleFunc("GET /api/chirps", cfg.HandleGetChirps)
-	mux.HandleFunc("GET /api/chirps/", cfg.HandleGetChirpByID)
-	mux.HandleFunc("DELETE /api/chirps/", cfg.HandleDeleteChirp)
-	mux.HandleFunc("POST /api/login", cfg.HandleLogin)
-	mux.HandleFunc("POST /api/refresh", cfg.HandleRefresh)
-	mux.HandleFunc("POST /api/revoke", cfg.HandleRevoke)
-	mux.HandleFunc("POST /api/polka/webhooks", cfg.HandlePolkaWebhook)
-
-	
+	cfg.RegisterRoutes(mux)
+
+	// permanently remove chirps deleted longer ago than the retention window
+	go cfg.RunChirpPurge(context.Background(), time.Hour)
+	// delete uploads that were never attached to a chirp
+	go cfg.RunMediaSweep(context.Background(), time.Hour)
+	// close Chirpy Red memberships whose paid period has run out
+	go cfg.RunMembershipExpiry(context.Background(), time.Hour)
+	// forget rate limit buckets that have refilled
+	go cfg.RunRateLimitSweep(context.Background(), 10*time.Minute)
+	// forget login failures outside the lockout window
+	go cfg.RunLoginThrottleSweep(context.Background(), time.Hour)
+	// delete login attempts past their retention
+	go cfg.RunLoginAttemptSweep(context.Background(), time.Hour)
+	// forget expired password reset tokens
+	go cfg.RunPasswordResetSweep(context.Background(), time.Hour)
+	// pick up JWT signing keys promoted or retired through other instances
+	go cfg.RunKeyRingRefresh(context.Background(), handlers.KeyRingRefreshInterval)
+
 	// File server wrapped with metrics middleware
 	fileServer := http.FileServer(http.Dir("."))
 	mux.Handle("/app/", http.StripPrefix("/app", cfg.MiddlewareMetricsInc(fileServer)))
@@ -71,4 +137,90 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-}
\ No newline at end of file
+}
+
+// newBlobStore picks where uploaded media is kept: an S3-compatible bucket
+// when MEDIA_STORE=s3, otherwise the MEDIA_DIR directory (default ./media).
+func newBlobStore() (media.BlobStore, error) {
+	if os.Getenv("MEDIA_STORE") == "s3" {
+		return &media.S3Store{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			Region:          os.Getenv("S3_REGION"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		}, nil
+	}
+	dir := os.Getenv("MEDIA_DIR")
+	if dir == "" {
+		dir = "media"
+	}
+	return media.NewDiskStore(dir)
+}
+
+// newRateLimiter picks where rate limit buckets are kept: in Postgres when
+// RATE_LIMIT_STORE=postgres, so that every instance shares limits, in
+// memory by default, or nowhere when RATE_LIMIT_STORE=off.
+func newRateLimiter(db database.Store) ratelimit.Backend {
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "postgres":
+		return &ratelimit.Postgres{DB: db}
+	case "off":
+		return nil
+	default:
+		return ratelimit.NewMemory()
+	}
+}
+
+// newJWTKey picks how access tokens are signed until a key managed through
+// /admin/jwt-keys is promoted: with the RSA or Ed25519 private key in the
+// JWT_SIGNING_KEY_FILE PEM file, published at /.well-known/jwks.json under
+// JWT_KEY_ID (default: its thumbprint), or with the shared JWT_SECRET.
+func newJWTKey() (*auth.Key, error) {
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		return auth.LoadKeyFile(os.Getenv("JWT_KEY_ID"), path)
+	}
+	return auth.NewHMACKey(os.Getenv("JWT_KEY_ID"), os.Getenv("JWT_SECRET")), nil
+}
+
+// newMailer picks how emails are sent: through SMTP_ADDR when
+// MAIL_TRANSPORT=smtp, written to MAIL_DIR (default ./mail) when
+// MAIL_TRANSPORT=file, or printed to the log when MAIL_TRANSPORT=log. The
+// last two put reset tokens and verification links where anyone reading
+// the files or logs can use them, so without MAIL_TRANSPORT only
+// PLATFORM=dev logs mail; elsewhere no mail is sent.
+func newMailer() (mail.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Chirpy <no-reply@localhost>"
+	}
+	switch transport := os.Getenv("MAIL_TRANSPORT"); transport {
+	case "smtp":
+		// links in real mail must not point at localhost
+		if os.Getenv("PUBLIC_URL") == "" {
+			return nil, fmt.Errorf("PUBLIC_URL is required with MAIL_TRANSPORT=smtp")
+		}
+		return &mail.SMTPMailer{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}, nil
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return &mail.FileMailer{Dir: dir, From: from}, nil
+	case "log":
+		return mail.LogMailer{}, nil
+	case "":
+		if os.Getenv("PLATFORM") == "dev" {
+			return mail.LogMailer{}, nil
+		}
+		log.Println("MAIL_TRANSPORT is not set; verification and password reset emails are disabled")
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("MAIL_TRANSPORT: unknown transport %q", transport)
+	}
+}
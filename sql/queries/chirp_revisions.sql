@@ -0,0 +1,7 @@
+-- name: ListChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY replaced_at ASC, id ASC;
+
+-- name: DeleteChirpRevisions :exec
+DELETE FROM chirp_revisions WHERE chirp_id = $1;
@@ -1,20 +1,149 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, rechirp_of, quote_of)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
 -- name: GetChirps :many
 SELECT * FROM chirps
+WHERE deleted_at IS NULL
 ORDER BY created_at ASC;
 
 -- name: GetChirpsByID :one
 SELECT * FROM chirps
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: DeleteChirpByID :exec
 DELETE FROM chirps WHERE id = $1;
 
+-- name: EditChirp :one
+-- the replaced body is saved in the same statement so no edit can lose it
+WITH revision AS (
+    INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+    SELECT gen_random_uuid(), id, body, COALESCE(edited_at, created_at), NOW()
+    FROM chirps WHERE id = sqlc.arg('id')
+)
+UPDATE chirps
+SET body = sqlc.arg('body'), edited_at = NOW(), updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
 -- name: GetChirpsByAuthorID :many
 SELECT * FROM chirps
-WHERE user_id = $1
-ORDER BY created_at ASC;
\ No newline at end of file
+WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: ListChirpsAfter :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('in_reply_to')::uuid IS NULL OR in_reply_to = sqlc.narg('in_reply_to')::uuid)
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListChirpsBefore :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('in_reply_to')::uuid IS NULL OR in_reply_to = sqlc.narg('in_reply_to')::uuid)
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: SearchChirpsAfter :many
+SELECT * FROM chirps
+WHERE search_vector @@ to_tsquery('english', sqlc.arg('query'))
+  AND deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: SearchChirpsBefore :many
+SELECT * FROM chirps
+WHERE search_vector @@ to_tsquery('english', sqlc.arg('query'))
+  AND deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: SearchChirpsByRankAfter :many
+SELECT sqlc.embed(chirps), ts_rank(search_vector, to_tsquery('english', sqlc.arg('query')))::real AS rank
+FROM chirps
+WHERE search_vector @@ to_tsquery('english', sqlc.arg('query'))
+  AND deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('cursor_rank')::real IS NULL
+       OR (ts_rank(search_vector, to_tsquery('english', sqlc.arg('query')))::real, created_at, id)
+          > (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY rank ASC, created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: SearchChirpsByRankBefore :many
+SELECT sqlc.embed(chirps), ts_rank(search_vector, to_tsquery('english', sqlc.arg('query')))::real AS rank
+FROM chirps
+WHERE search_vector @@ to_tsquery('english', sqlc.arg('query'))
+  AND deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('cursor_rank')::real IS NULL
+       OR (ts_rank(search_vector, to_tsquery('english', sqlc.arg('query')))::real, created_at, id)
+          < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: ChirpHasReplies :one
+-- Deleted replies count too: one may yet be restored into the thread.
+SELECT EXISTS (SELECT 1 FROM chirps WHERE in_reply_to = $1);
+
+-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '', tombstoned_at = $2, updated_at = $2
+WHERE id = $1;
+
+-- name: GetConversation :many
+SELECT * FROM chirps
+WHERE conversation_id = $1
+ORDER BY created_at ASC, id ASC;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[]) AND deleted_at IS NULL;
+
+-- name: GetRechirp :one
+SELECT * FROM chirps
+WHERE user_id = $1 AND rechirp_of = $2;
+
+-- name: GetRechirpStats :many
+SELECT c.id AS chirp_id,
+       (SELECT COUNT(*) FROM chirps r WHERE r.rechirp_of = c.id AND r.deleted_at IS NULL) AS rechirp_count,
+       (SELECT COUNT(*) FROM chirps q WHERE q.quote_of = c.id AND q.deleted_at IS NULL) AS quote_count,
+       EXISTS (SELECT 1 FROM chirps r WHERE r.rechirp_of = c.id AND r.deleted_at IS NULL AND r.user_id = sqlc.narg('viewer_id')::uuid) AS rechirped_by_me
+FROM chirps c
+WHERE c.id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: SoftDeleteChirp :exec
+-- Rechirps of the chirp are hidden along with it.
+UPDATE chirps
+SET deleted_at = $2
+WHERE (id = $1 OR rechirp_of = $1) AND deleted_at IS NULL;
+
+-- name: GetDeletedChirp :one
+SELECT * FROM chirps
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: RestoreChirp :exec
+-- Only rechirps hidden by the same soft delete come back.
+UPDATE chirps
+SET deleted_at = NULL
+WHERE (id = $1 OR rechirp_of = $1) AND deleted_at = $2;
+
+-- name: ListPurgeableChirps :many
+SELECT * FROM chirps
+WHERE deleted_at < sqlc.arg('before') AND tombstoned_at IS NULL
+ORDER BY deleted_at ASC, id ASC
+LIMIT sqlc.arg('max_chirps');
@@ -0,0 +1,27 @@
+-- name: ActivateJWTKey :execrows
+-- Makes the key the one new tokens are signed with, unless it has expired.
+-- A replaced key promoted again before its overlap ends stops expiring.
+UPDATE jwt_keys
+SET activated_at = $2, expires_at = NULL
+WHERE id = $1 AND (expires_at IS NULL OR expires_at > $2);
+
+-- name: CreateJWTKey :one
+INSERT INTO jwt_keys (id, private_key, created_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ExpireJWTKey :execrows
+-- Stops accepting the key after expires_at. A key due to expire sooner is
+-- left alone.
+UPDATE jwt_keys
+SET expires_at = $2
+WHERE id = $1 AND (expires_at IS NULL OR expires_at > $2);
+
+-- name: ListJWTKeys :many
+SELECT * FROM jwt_keys
+ORDER BY created_at, id;
+
+-- name: UpdateJWTKeyPrivateKey :exec
+UPDATE jwt_keys
+SET private_key = $2
+WHERE id = $1;
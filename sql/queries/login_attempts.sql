@@ -0,0 +1,43 @@
+-- name: CreateLoginAttempt :exec
+INSERT INTO login_attempts (id, email, ip, user_id, succeeded, reason, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, NOW());
+
+-- name: DeleteLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE key = $1;
+
+-- name: DeleteOldLoginAttempts :execrows
+DELETE FROM login_attempts
+WHERE created_at < $1;
+
+-- name: DeleteStaleLoginThrottles :execrows
+-- Forgets keys with no failure or lock since the cutoff.
+DELETE FROM login_throttles
+WHERE last_failure_at < $1
+  AND (locked_until IS NULL OR locked_until < $1);
+
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE key = $1;
+
+-- name: ListLoginAttempts :many
+SELECT * FROM login_attempts
+WHERE email = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: RecordLoginFailure :one
+-- Counts a failure against key. Failures before reset_before are
+-- forgotten, so the count starts again at one.
+INSERT INTO login_throttles (key, failures, last_failure_at)
+VALUES (sqlc.arg('key'), 1, sqlc.arg('now'))
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE WHEN login_throttles.last_failure_at < sqlc.arg('reset_before') THEN 1
+                    ELSE login_throttles.failures + 1 END,
+    last_failure_at = sqlc.arg('now')
+RETURNING *;
+
+-- name: SetLoginThrottleLock :exec
+UPDATE login_throttles
+SET locked_until = $2
+WHERE key = $1;
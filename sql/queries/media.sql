@@ -0,0 +1,46 @@
+-- name: CreateMediaAttachment :one
+INSERT INTO media_attachments (
+    id,
+    user_id,
+    content_type,
+    size_bytes,
+    width,
+    height,
+    blob_key,
+    thumbnail_key,
+    thumbnail_content_type,
+    created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING *;
+
+-- name: GetMediaAttachment :one
+SELECT * FROM media_attachments WHERE id = $1;
+
+-- name: GetMediaAttachmentsByIDs :many
+SELECT * FROM media_attachments WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: AttachMedia :execrows
+UPDATE media_attachments
+SET chirp_id = sqlc.arg('chirp_id'), position = sqlc.arg('position')
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND chirp_id IS NULL;
+
+-- name: ListChirpMedia :many
+SELECT * FROM media_attachments
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: DeleteChirpMedia :many
+DELETE FROM media_attachments WHERE chirp_id = $1
+RETURNING *;
+
+-- name: DeleteUnattachedMedia :many
+-- Uploads never attached to a chirp, created before the given time.
+DELETE FROM media_attachments
+WHERE chirp_id IS NULL AND created_at < sqlc.arg('before')::timestamp
+RETURNING *;
+
+-- name: DeleteAllMedia :many
+DELETE FROM media_attachments
+RETURNING *;
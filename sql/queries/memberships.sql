@@ -0,0 +1,35 @@
+-- name: CreateMembership :one
+INSERT INTO memberships (id, user_id, tier, status, started_at, ends_at, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, $2, 'active', NOW(), $3, NOW(), NOW())
+RETURNING *;
+
+-- name: GetCurrentMembership :one
+-- The open period, which may have lapsed without being expired yet.
+SELECT * FROM memberships
+WHERE user_id = $1 AND ended_at IS NULL;
+
+-- name: GetActiveMembership :one
+SELECT * FROM memberships
+WHERE user_id = sqlc.arg('user_id') AND ended_at IS NULL
+  AND (ends_at IS NULL OR ends_at > sqlc.arg('now')::timestamp);
+
+-- name: UpdateMembershipStatus :one
+UPDATE memberships
+SET status = $2, ends_at = $3, ended_at = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ExpireLapsedMemberships :many
+UPDATE memberships
+SET status = 'expired', ended_at = ends_at, updated_at = NOW()
+WHERE ended_at IS NULL AND ends_at <= sqlc.arg('now')::timestamp
+RETURNING *;
+
+-- name: CreateMembershipHistory :exec
+INSERT INTO membership_history (id, membership_id, user_id, status, reason, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, NOW());
+
+-- name: ListMembershipHistory :many
+SELECT * FROM membership_history
+WHERE user_id = $1
+ORDER BY created_at ASC, id ASC;
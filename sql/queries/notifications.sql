@@ -0,0 +1,36 @@
+-- name: CreateNotification :exec
+INSERT INTO notifications (id, user_id, actor_id, kind, chirp_id, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: ListNotificationsAfter :many
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg('user_id')
+  AND (NOT sqlc.arg('unread_only')::boolean OR read_at IS NULL)
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListNotificationsBefore :many
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg('user_id')
+  AND (NOT sqlc.arg('unread_only')::boolean OR read_at IS NULL)
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications SET read_at = NOW()
+WHERE user_id = sqlc.arg('user_id')
+  AND id = ANY(sqlc.arg('ids')::uuid[])
+  AND read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
@@ -0,0 +1,40 @@
+-- name: CreatePasswordReset :exec
+INSERT INTO password_resets (token_hash, user_id, created_at, expires_at)
+VALUES ($1, $2, NOW(), $3);
+
+-- name: DeleteStalePasswordResets :execrows
+DELETE FROM password_resets
+WHERE expires_at < $1;
+
+-- name: GetPasswordResetUser :one
+-- Returns the user a live reset token belongs to.
+SELECT user_id FROM password_resets
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2;
+
+-- name: ResetPassword :one
+-- Uses up a live reset token and, in the same statement, sets the user's
+-- new password, uses up their other reset tokens, revokes their refresh
+-- tokens and bumps their token version, so a reset either happens in full
+-- or not at all. Returns no rows for an unknown, expired or already used
+-- token, including one used by a concurrent request.
+WITH reset AS (
+    UPDATE password_resets
+    SET used_at = sqlc.arg('used_at')
+    WHERE token_hash = sqlc.arg('token_hash') AND used_at IS NULL AND expires_at > sqlc.arg('used_at')
+    RETURNING user_id
+), other_resets AS (
+    UPDATE password_resets
+    SET used_at = sqlc.arg('used_at')
+    WHERE user_id = (SELECT user_id FROM reset)
+      AND token_hash <> sqlc.arg('token_hash') AND used_at IS NULL
+), sessions AS (
+    UPDATE refresh_tokens
+    SET revoked_at = sqlc.arg('used_at'), updated_at = sqlc.arg('used_at')
+    WHERE user_id = (SELECT user_id FROM reset) AND revoked_at IS NULL
+)
+UPDATE users
+SET hashed_password = sqlc.arg('hashed_password'),
+    token_version = token_version + 1,
+    updated_at = NOW()
+WHERE id = (SELECT user_id FROM reset)
+RETURNING id, email;
@@ -0,0 +1,21 @@
+-- name: DeleteStaleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1;
+
+-- name: GetRateLimitBucket :one
+SELECT * FROM rate_limit_buckets
+WHERE key = $1;
+
+-- name: TakeRateLimitToken :one
+-- Refills the bucket at rate tokens a second since it was last updated, up
+-- to burst, and takes one token. Returns no rows, leaving the bucket as it
+-- was, when less than a whole token is available.
+INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+VALUES (sqlc.arg('key'), sqlc.arg('burst')::double precision - 1, sqlc.arg('now')::timestamp)
+ON CONFLICT (key) DO UPDATE
+SET tokens = LEAST(sqlc.arg('burst')::double precision,
+                   rate_limit_buckets.tokens + GREATEST(0, EXTRACT(EPOCH FROM sqlc.arg('now')::timestamp - rate_limit_buckets.updated_at))::double precision * sqlc.arg('rate')::double precision) - 1,
+    updated_at = GREATEST(rate_limit_buckets.updated_at, sqlc.arg('now')::timestamp)
+WHERE LEAST(sqlc.arg('burst')::double precision,
+            rate_limit_buckets.tokens + GREATEST(0, EXTRACT(EPOCH FROM sqlc.arg('now')::timestamp - rate_limit_buckets.updated_at))::double precision * sqlc.arg('rate')::double precision) >= 1
+RETURNING tokens;
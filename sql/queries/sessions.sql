@@ -0,0 +1,23 @@
+-- name: ListUserSessions :many
+-- A user's sessions, one row per family with a live token: the device it
+-- was last used from and when the family was started.
+SELECT
+  t.family_id,
+  t.user_agent,
+  t.ip,
+  (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id)::timestamp AS started_at,
+  t.last_used_at,
+  t.expires_at
+FROM
+  refresh_tokens t
+WHERE
+  t.user_id = sqlc.arg('user_id')
+  AND t.rotated_at IS NULL
+  AND t.revoked_at IS NULL
+  AND t.expires_at > sqlc.arg('now')::timestamp
+ORDER BY t.last_used_at DESC, t.family_id;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = $2, updated_at = $2
+WHERE user_id = $1 AND revoked_at IS NULL;
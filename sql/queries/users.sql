@@ -1,6 +1,6 @@
 -- name: CreateUser :one
-INSERT INTO users (id, email, hashed_password, created_at, updated_at)
-VALUES (gen_random_uuid(), $1, $2, NOW(), NOW())
+INSERT INTO users (id, email, hashed_password, username, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, $2, $3, NOW(), NOW())
 RETURNING *;
 
 -- name: DeleteAllUsers :exec
@@ -13,36 +13,91 @@ SELECT * FROM users WHERE email = $1;
 INSERT INTO refresh_tokens (
     token,
     user_id,
+    family_id,
     expires_at,
+    user_agent,
+    ip,
     created_at,
-    updated_at
+    updated_at,
+    last_used_at
 ) VALUES (
-    $1, $2, $3, NOW(), NOW()
+    $1, $2, $3, $4, $5, $6, NOW(), NOW(), NOW()
 );
 
 -- name: GetUserFromRefreshToken :one
 SELECT
   token,
   user_id,
+  family_id,
   expires_at,
-  revoked_at
+  revoked_at,
+  rotated_at
 FROM
   refresh_tokens
 WHERE
   token = $1;
 
--- name: RevokeRefreshToken :exec
+-- name: RotateRefreshToken :execrows
+-- Replaces a live token with new_token in the same family. Both happen in
+-- one statement, so a token is never used up without its replacement
+-- existing. Affects no rows if the token was already rotated or revoked,
+-- including by a concurrent refresh.
+WITH rotated AS (
+    UPDATE refresh_tokens
+    SET rotated_at = sqlc.arg('rotated_at'), replaced_by = sqlc.arg('new_token'), updated_at = sqlc.arg('rotated_at')
+    WHERE token = sqlc.arg('token') AND rotated_at IS NULL AND revoked_at IS NULL
+    RETURNING user_id, family_id
+)
+INSERT INTO refresh_tokens (
+    token,
+    user_id,
+    family_id,
+    expires_at,
+    user_agent,
+    ip,
+    created_at,
+    updated_at,
+    last_used_at
+)
+SELECT
+    sqlc.arg('new_token'), user_id, family_id, sqlc.arg('expires_at'), sqlc.arg('user_agent'), sqlc.arg('ip'),
+    sqlc.arg('rotated_at'), sqlc.arg('rotated_at'), sqlc.arg('rotated_at')
+FROM rotated;
+
+-- name: RevokeRefreshTokenFamily :execrows
 UPDATE refresh_tokens
-SET revoked_at = $1, updated_at = $2
-WHERE token = $3;
+SET revoked_at = $3, updated_at = $3
+WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL;
 
 -- name: UpdateUser :one
 UPDATE users
 SET email = $2,
     hashed_password = $3,
+    username = COALESCE(sqlc.narg('username'), username),
+    -- a new address has to be verified again
+    verified_at = CASE WHEN email = $2 THEN verified_at END,
     updated_at =  NOW()
 WHERE id = $1
 RETURNING *;
 
--- name: UpgradeUserToChirpyRed :exec
-UPDATE users SET is_chirpy_red = TRUE, updated_at = NOW() WHERE id = $1;
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: GetUsersByUsernames :many
+SELECT * FROM users WHERE username = ANY(sqlc.arg('usernames')::text[]);
+
+-- name: GetUserTokenVersion :one
+SELECT token_version FROM users WHERE id = $1;
+
+-- name: IncrementUserTokenVersion :one
+UPDATE users
+SET token_version = token_version + 1, updated_at = NOW()
+WHERE id = $1
+RETURNING token_version;
+
+-- name: VerifyUserEmail :execrows
+-- Marks the address verified if it is still the user's and was not
+-- verified already, so each verification link works once.
+UPDATE users
+SET verified_at = $3, updated_at = $3
+WHERE id = $1 AND email = $2 AND verified_at IS NULL;
@@ -0,0 +1,63 @@
+-- name: ClaimWebhookEvent :one
+-- Claims a stored event that has not been applied for the caller to apply.
+-- Returns no rows when it has been applied, or another delivery claimed it
+-- after stale_before and may still be applying it.
+UPDATE webhook_events
+SET processing_started_at = sqlc.arg('processing_started_at')
+WHERE event_id = sqlc.arg('event_id') AND processed_at IS NULL
+  AND (processing_started_at IS NULL OR processing_started_at < sqlc.arg('stale_before'))
+RETURNING *;
+
+-- name: ClaimWebhookEventForReplay :one
+-- Claims a stored event to apply again, whether or not it has been applied.
+-- Returns no rows when another delivery or replay claimed it after
+-- stale_before and may still be applying it.
+UPDATE webhook_events
+SET processing_started_at = sqlc.arg('processing_started_at')
+WHERE id = sqlc.arg('id')
+  AND (processing_started_at IS NULL OR processing_started_at < sqlc.arg('stale_before'))
+RETURNING *;
+
+-- name: CreateWebhookEvent :one
+-- Stores a new event, claimed by the caller. Returns no rows when the event
+-- ID has been seen before.
+INSERT INTO webhook_events (id, event_id, event, payload, received_at, processing_started_at)
+VALUES (gen_random_uuid(), $1, $2, $3, NOW(), NOW())
+ON CONFLICT (event_id) DO NOTHING
+RETURNING *;
+
+-- name: GetWebhookEvent :one
+SELECT * FROM webhook_events
+WHERE id = $1;
+
+-- name: GetWebhookEventByEventID :one
+SELECT * FROM webhook_events
+WHERE event_id = $1;
+
+-- name: ListWebhookEventsAfter :many
+SELECT * FROM webhook_events
+WHERE (NOT sqlc.arg('pending_only')::boolean OR processed_at IS NULL)
+  AND (sqlc.narg('cursor_received_at')::timestamp IS NULL
+       OR (received_at, id) > (sqlc.narg('cursor_received_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY received_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListWebhookEventsBefore :many
+SELECT * FROM webhook_events
+WHERE (NOT sqlc.arg('pending_only')::boolean OR processed_at IS NULL)
+  AND (sqlc.narg('cursor_received_at')::timestamp IS NULL
+       OR (received_at, id) < (sqlc.narg('cursor_received_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY received_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: MarkWebhookEventProcessed :exec
+-- Records success and releases the claim.
+UPDATE webhook_events
+SET processed_at = $2, last_error = NULL, processing_started_at = NULL
+WHERE id = $1;
+
+-- name: MarkWebhookEventFailed :exec
+-- Records the failure and releases the claim, so a redelivery can retry.
+UPDATE webhook_events
+SET last_error = $2, processing_started_at = NULL
+WHERE id = $1;
@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE INDEX chirps_user_id_created_at_idx ON chirps (user_id, created_at, id);
+CREATE INDEX chirps_created_at_idx ON chirps (created_at, id);
+
+-- +goose Down
+DROP INDEX chirps_created_at_idx;
+DROP INDEX chirps_user_id_created_at_idx;
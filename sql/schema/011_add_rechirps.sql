@@ -0,0 +1,20 @@
+-- +goose Up
+-- a plain rechirp has rechirp_of set and an empty body; a quote chirp has
+-- quote_of set and the quoting user's own body
+ALTER TABLE chirps
+ADD COLUMN rechirp_of UUID REFERENCES chirps(id) ON DELETE CASCADE,
+ADD COLUMN quote_of UUID REFERENCES chirps(id) ON DELETE SET NULL,
+ADD CONSTRAINT chirps_rechirp_or_quote_check CHECK (rechirp_of IS NULL OR quote_of IS NULL);
+
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_key ON chirps (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL;
+CREATE INDEX chirps_rechirp_of_idx ON chirps (rechirp_of) WHERE rechirp_of IS NOT NULL;
+CREATE INDEX chirps_quote_of_idx ON chirps (quote_of) WHERE quote_of IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_quote_of_idx;
+DROP INDEX chirps_rechirp_of_idx;
+DROP INDEX chirps_user_id_rechirp_of_key;
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_rechirp_or_quote_check,
+DROP COLUMN quote_of,
+DROP COLUMN rechirp_of;
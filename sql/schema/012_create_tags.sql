@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE tags (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- created_at is copied from the chirp so feeds and trending windows can be
+-- served from this table alone
+CREATE TABLE chirp_tags (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, tag_id)
+);
+
+CREATE INDEX chirp_tags_tag_id_created_at_idx ON chirp_tags (tag_id, created_at, chirp_id);
+CREATE INDEX chirp_tags_created_at_idx ON chirp_tags (created_at);
+
+-- +goose Down
+DROP TABLE chirp_tags;
+DROP TABLE tags;
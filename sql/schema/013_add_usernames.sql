@@ -0,0 +1,7 @@
+-- +goose Up
+-- usernames are optional so existing accounts keep working; they are
+-- stored lowercased so @mentions resolve case-insensitively
+ALTER TABLE users ADD COLUMN username TEXT UNIQUE;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN username;
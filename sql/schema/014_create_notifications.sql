@@ -0,0 +1,22 @@
+-- +goose Up
+-- user_id is the recipient and actor_id the user whose action caused the
+-- notification. chirp_id is the mentioning chirp, the reply or the liked
+-- chirp, and NULL for follows.
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('mention', 'reply', 'like', 'follow')),
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    read_at TIMESTAMP
+);
+
+-- liking or following again after undoing it must not notify twice
+CREATE UNIQUE INDEX notifications_dedupe_key ON notifications
+    (user_id, actor_id, kind, COALESCE(chirp_id, '00000000-0000-0000-0000-000000000000'));
+CREATE INDEX notifications_user_id_created_at_idx ON notifications (user_id, created_at, id);
+CREATE INDEX notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+
+-- +goose Down
+DROP TABLE notifications;
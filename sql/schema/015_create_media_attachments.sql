@@ -0,0 +1,23 @@
+-- +goose Up
+-- Uploads start unattached (chirp_id NULL) and are attached when a chirp
+-- referencing them is created. The bytes live in the blob store under
+-- blob_key and thumbnail_key.
+CREATE TABLE media_attachments (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    blob_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    thumbnail_content_type TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX media_attachments_chirp_id_position_idx ON media_attachments (chirp_id, position);
+
+-- +goose Down
+DROP TABLE media_attachments;
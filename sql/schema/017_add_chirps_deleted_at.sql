@@ -0,0 +1,11 @@
+-- +goose Up
+-- deleted_at marks a soft delete. The chirp stays restorable until the
+-- purge either removes it or, if it has replies, tombstones it. Existing
+-- tombstones count as deleted.
+ALTER TABLE chirps ADD COLUMN deleted_at TIMESTAMP;
+UPDATE chirps SET deleted_at = tombstoned_at WHERE tombstoned_at IS NOT NULL;
+
+CREATE INDEX chirps_deleted_at_idx ON chirps (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN deleted_at;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Every webhook delivery we accept, keyed by the sender's event ID so that
+-- retried deliveries are only applied once. processed_at stays NULL until
+-- the event has been applied; last_error holds the most recent failure.
+CREATE TABLE webhook_events (
+    id UUID PRIMARY KEY,
+    event_id TEXT NOT NULL UNIQUE,
+    event TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMP,
+    last_error TEXT
+);
+
+CREATE INDEX webhook_events_received_at_idx ON webhook_events (received_at, id);
+
+-- +goose Down
+DROP TABLE webhook_events;
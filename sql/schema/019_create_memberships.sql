@@ -0,0 +1,48 @@
+-- +goose Up
+-- A membership is one period of a paid tier. ends_at is when access runs
+-- out (NULL while the subscription renews) and ended_at is when the period
+-- was closed; a user has at most one open period. membership_history
+-- records every status change along with what caused it.
+CREATE TABLE memberships (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tier TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('active', 'cancelled', 'downgraded', 'expired')),
+    started_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP,
+    ended_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX memberships_user_id_open_key ON memberships (user_id) WHERE ended_at IS NULL;
+CREATE INDEX memberships_ends_at_idx ON memberships (ends_at) WHERE ended_at IS NULL;
+
+CREATE TABLE membership_history (
+    id UUID PRIMARY KEY,
+    membership_id UUID NOT NULL REFERENCES memberships(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX membership_history_user_id_idx ON membership_history (user_id, created_at);
+
+INSERT INTO memberships (id, user_id, tier, status, started_at)
+SELECT gen_random_uuid(), id, 'chirpy_red', 'active', updated_at
+FROM users WHERE is_chirpy_red;
+
+INSERT INTO membership_history (id, membership_id, user_id, status, reason)
+SELECT gen_random_uuid(), id, user_id, status, 'migrated'
+FROM memberships;
+
+ALTER TABLE users DROP COLUMN is_chirpy_red;
+
+-- +goose Down
+ALTER TABLE users ADD COLUMN is_chirpy_red BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE users SET is_chirpy_red = TRUE
+WHERE id IN (SELECT user_id FROM memberships WHERE ended_at IS NULL);
+
+DROP TABLE membership_history;
+DROP TABLE memberships;
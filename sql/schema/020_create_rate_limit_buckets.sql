@@ -0,0 +1,14 @@
+-- +goose Up
+-- Token buckets for rate limiting, shared by every API instance. tokens is
+-- the level at updated_at; the refill since then is worked out on each
+-- request, so idle buckets need no writes.
+CREATE TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX rate_limit_buckets_updated_at_idx ON rate_limit_buckets (updated_at);
+
+-- +goose Down
+DROP TABLE rate_limit_buckets;
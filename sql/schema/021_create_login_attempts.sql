@@ -0,0 +1,29 @@
+-- +goose Up
+-- Every login attempt, for auditing. email is as entered, user_id is set
+-- when it named an account, and reason says why a failed attempt failed.
+CREATE TABLE login_attempts (
+    id UUID PRIMARY KEY,
+    email TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    succeeded BOOLEAN NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX login_attempts_email_idx ON login_attempts (email, created_at);
+CREATE INDEX login_attempts_ip_idx ON login_attempts (ip, created_at);
+
+-- Recent consecutive login failures per email ("email:...") and per client
+-- IP ("ip:..."). While locked_until is in the future, logins for the key
+-- are refused without checking the password.
+CREATE TABLE login_throttles (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE login_throttles;
+DROP TABLE login_attempts;
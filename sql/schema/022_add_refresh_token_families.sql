@@ -0,0 +1,22 @@
+-- +goose Up
+-- Refresh tokens rotate: each refresh replaces the token with a new one in
+-- the same family, recording the successor in replaced_by. Presenting a
+-- token that has already been rotated means it was copied, so the whole
+-- family is revoked. Existing tokens each start a family of their own.
+ALTER TABLE refresh_tokens
+    ADD COLUMN family_id UUID,
+    ADD COLUMN rotated_at TIMESTAMP,
+    ADD COLUMN replaced_by TEXT;
+
+UPDATE refresh_tokens SET family_id = gen_random_uuid();
+
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_family_id_idx;
+ALTER TABLE refresh_tokens
+    DROP COLUMN replaced_by,
+    DROP COLUMN rotated_at,
+    DROP COLUMN family_id;
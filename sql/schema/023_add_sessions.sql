@@ -0,0 +1,21 @@
+-- +goose Up
+-- A session is a refresh token family. Each token records the device it
+-- was issued to and when, so the live token of a family describes the
+-- session. users.token_version is stamped into access tokens; bumping it
+-- invalidates every access token issued before.
+ALTER TABLE refresh_tokens
+    ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ip TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_used_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+ALTER TABLE users ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN token_version;
+DROP INDEX refresh_tokens_user_id_idx;
+ALTER TABLE refresh_tokens
+    DROP COLUMN last_used_at,
+    DROP COLUMN ip,
+    DROP COLUMN user_agent;
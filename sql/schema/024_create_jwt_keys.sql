@@ -0,0 +1,15 @@
+-- +goose Up
+-- Keys access tokens are signed with, shared by every API instance. A key
+-- is published for verification from when it is created, signs tokens
+-- once activated, and is no longer accepted after expires_at. private_key
+-- is a PEM encoded PKCS #8 key, encrypted with JWT_KEY_ENCRYPTION_KEY.
+CREATE TABLE jwt_keys (
+    id TEXT PRIMARY KEY,
+    private_key TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    activated_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE jwt_keys;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- When the user proved they own their email address. Accounts created
+-- before verification existed are taken as verified, so that requiring it
+-- does not lock them out.
+ALTER TABLE users ADD COLUMN verified_at TIMESTAMP;
+UPDATE users SET verified_at = created_at;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN verified_at;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Password reset tokens. Only a SHA-256 hash of each token is kept, so a
+-- leaked table cannot be used to reset passwords. A token works once,
+-- before expires_at.
+CREATE TABLE password_resets (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX password_resets_user_id_idx ON password_resets (user_id);
+
+-- +goose Down
+DROP TABLE password_resets;
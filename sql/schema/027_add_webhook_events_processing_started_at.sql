@@ -0,0 +1,8 @@
+-- +goose Up
+-- When a delivery of the event started applying it. A delivery only
+-- applies an event it managed to claim, so concurrent deliveries of one
+-- event cannot both apply it; a failed attempt releases its claim.
+ALTER TABLE webhook_events ADD COLUMN processing_started_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE webhook_events DROP COLUMN processing_started_at;
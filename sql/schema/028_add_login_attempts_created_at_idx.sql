@@ -0,0 +1,6 @@
+-- +goose Up
+-- For the sweep that deletes login attempts past their retention.
+CREATE INDEX login_attempts_created_at_idx ON login_attempts (created_at);
+
+-- +goose Down
+DROP INDEX login_attempts_created_at_idx;